@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/spf13/cobra"
+	"github.com/vcraescu/dbsync/internal/logger"
+	"github.com/vcraescu/dbsync/pkg/dbsync"
+)
+
+var applyCmd = &cobra.Command{
+	Use:   "apply [PLAN_FILE] [SLAVE_NAME]",
+	Short: "Apply sync plan [PLAN_FILE] generated by diff to slave server with name [SLAVE_NAME] from config.",
+	Args:  cobra.ExactArgs(2),
+	PreRunE: func(_ *cobra.Command, args []string) error {
+		if err := loadSlave(args[1]); err != nil {
+			return err
+		}
+
+		if !config.ValidateSlave() {
+			return errors.New("invalid config")
+		}
+
+		return nil
+	},
+	Run: runApplyCmd,
+}
+
+var applyForce bool
+
+func init() {
+	applyCmd.Flags().BoolVar(&applyForce, "force", false, "Apply the plan even if slave changed since it was generated")
+}
+
+func runApplyCmd(_ *cobra.Command, args []string) {
+	ctx, stop := signalContext()
+	defer stop()
+
+	p, err := dbsync.ReadPlan(args[0])
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	logger.Info(
+		"Plan loaded",
+		"generated_at", p.CreatedAt.Format("2006-01-02 15:04:05 MST"),
+		"master", fmt.Sprintf("%s/%s", p.Master.Host, p.Master.Schema),
+	)
+
+	if p.Slave.Host != config.Slave.Host || p.Slave.Schema != config.Slave.Schema {
+		logger.Warn("Plan was generated for another slave", "slave", fmt.Sprintf("%s/%s", p.Slave.Host, p.Slave.Schema))
+	}
+
+	slave, err := createSlaveServer(ctx)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	if applyForce {
+		logger.Info("Applying plan...")
+	} else {
+		logger.Info("Verifying slave checksums and applying plan...")
+	}
+
+	opts := dbsync.ApplyOptions{
+		Force:    applyForce,
+		Timeouts: config.timeouts(),
+	}
+	if err := dbsync.Apply(ctx, p, slave, opts); err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	logger.Info("Done!")
+}
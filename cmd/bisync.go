@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+	"github.com/vcraescu/dbsync/internal/logger"
+	"github.com/vcraescu/dbsync/pkg/dbsync"
+)
+
+var bisyncCmd = &cobra.Command{
+	Use:     "bisync [LEFT_NAME] [RIGHT_NAME]",
+	Short:   "Sync servers with names [LEFT_NAME] and [RIGHT_NAME] from config both ways, copying the tables changed on one side since the last two-way sync to the other.",
+	Args:    cobra.ExactArgs(2),
+	PreRunE: loadServers,
+	Run:     runBisyncCmd,
+}
+
+var (
+	bisyncWorkers  int
+	bisyncConflict string
+	bisyncBase     string
+)
+
+func init() {
+	bisyncCmd.Flags().IntVar(&bisyncWorkers, "workers", 4, "Number of tables dumped and imported in parallel")
+	bisyncCmd.Flags().StringVar(
+		&bisyncConflict,
+		"conflict",
+		string(dbsync.ConflictFail),
+		"Tables changed on both sides: fail (sync nothing), left (left wins) or right (right wins)",
+	)
+	bisyncCmd.Flags().StringVar(
+		&bisyncBase,
+		"base",
+		"",
+		"File holding the checksums of the last two-way sync, instead of the state of the pair",
+	)
+}
+
+func runBisyncCmd(_ *cobra.Command, args []string) {
+	ctx, stop := signalContext()
+	defer stop()
+
+	policy, err := dbsync.ParseConflictPolicy(bisyncConflict)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	store, err := stateStore()
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	pair, err := store.Load(args[0], args[1])
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	base := pair.Base
+	if bisyncBase != "" {
+		if base, err = dbsync.ReadBase(bisyncBase); err != nil {
+			logger.Fatal(err.Error())
+		}
+	}
+
+	if base == nil {
+		logger.Warn("No previous two-way sync found, tables differing on both sides conflict")
+	} else {
+		logger.Info("Comparing with the previous two-way sync", "synced_at", base.SyncedAt.Format("2006-01-02 15:04:05 MST"))
+	}
+
+	left, err := createMasterServer(ctx)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	right, err := createSlaveServer(ctx)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	opts := config.Options("")
+	opts.Workers = bisyncWorkers
+
+	logger.Info("Syncing both ways...")
+	res, err := dbsync.Bisync(ctx, left, right, base, policy, opts)
+	if res != nil {
+		logBisync(res)
+	}
+
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	if bisyncBase != "" {
+		if err := res.Base.WriteFile(bisyncBase); err != nil {
+			logger.Fatal(err.Error())
+		}
+	} else {
+		pair.Base = res.Base
+		if err := store.Save(pair); err != nil {
+			logger.Fatal(err.Error())
+		}
+	}
+
+	logger.Info("Done!")
+}
+
+func logBisync(res *dbsync.BisyncResult) {
+	byChange := make(map[dbsync.BisyncChange][]string)
+	for _, t := range res.Tables {
+		byChange[t.Change] = append(byChange[t.Change], t.Name)
+	}
+
+	for _, change := range []dbsync.BisyncChange{dbsync.BisyncChangedLeft, dbsync.BisyncChangedRight, dbsync.BisyncConflict} {
+		if len(byChange[change]) > 0 {
+			logger.Info("Tables "+string(change), "tables", byChange[change])
+		}
+	}
+
+	if len(res.ToRight) > 0 {
+		logger.Info("Synced to right", "tables", res.ToRight)
+	}
+
+	if len(res.ToLeft) > 0 {
+		logger.Info("Synced to left", "tables", res.ToLeft)
+	}
+}
@@ -0,0 +1,134 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+	"github.com/vcraescu/dbsync/internal/compress"
+	"github.com/vcraescu/dbsync/internal/database"
+	"github.com/vcraescu/dbsync/internal/logger"
+	"github.com/vcraescu/dbsync/pkg/dbsync"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff [MASTER_NAME] [SLAVE_NAME]",
+	Short: "Show differences between master server [MASTER_NAME] and slave server [SLAVE_NAME], optionally writing them to a sync plan. Exits with 1 when they differ.",
+	Args:  cobra.ExactArgs(2),
+	PreRunE: func(cmd *cobra.Command, args []string) error {
+		if err := loadServers(cmd, args); err != nil {
+			return failure(diffExitError, cmd.OutOrStdout(), diffOutput, nil, err)
+		}
+
+		return nil
+	},
+	RunE: runDiffCmd,
+}
+
+var (
+	diffChecksum string
+	diffOut      string
+	diffCompress string
+	diffOutput   string
+	diffCount    bool
+)
+
+func init() {
+	diffCmd.Flags().StringVar(
+		&diffChecksum,
+		"checksum",
+		string(database.ChecksumContent),
+		"Change detection strategy: content, native (CHECKSUM TABLE) or metadata (row count, data length and update time)",
+	)
+	diffCmd.Flags().StringVar(&diffOut, "out", "", "Write the sync plan to file, to be applied later with apply")
+	diffCmd.Flags().StringVar(&diffCompress, "compress", "", "Compress the sync plan with gzip (default) or zstd")
+	diffCmd.Flags().Lookup("compress").NoOptDefVal = string(compress.Gzip)
+	diffCmd.Flags().StringVar(&diffOutput, "output", outputText, "Output format: text or json, written to stdout")
+	diffCmd.Flags().BoolVar(
+		&diffCount,
+		"count-rows",
+		false,
+		"Report exact row counts, reading every table, instead of the estimates of the server statistics",
+	)
+}
+
+// exit codes of diff, as the ones of diff(1)
+const (
+	diffExitSame      = 0
+	diffExitDifferent = 1
+	diffExitError     = 2
+)
+
+// errDifferent - error of diff ending with diffExitDifferent
+var errDifferent = errors.New("master and slave differ")
+
+func runDiffCmd(cmd *cobra.Command, _ []string) error {
+	ctx, stop := signalContext()
+	defer stop()
+
+	out := cmd.OutOrStdout()
+
+	if err := validateOutput(diffOutput); err != nil {
+		return failure(diffExitError, out, diffOutput, nil, err)
+	}
+
+	master, err := createMasterServer(ctx)
+	if err != nil {
+		return failure(diffExitError, out, diffOutput, nil, err)
+	}
+
+	slave, err := createSlaveServer(ctx)
+	if err != nil {
+		return failure(diffExitError, out, diffOutput, nil, err)
+	}
+
+	logger.Info("Computing differences between master and slave...")
+	opts := config.Options(diffChecksum)
+	opts.CountRows = diffCount
+	diff, err := dbsync.GenerateDiff(ctx, master, slave, opts)
+	if err != nil {
+		return failure(diffExitError, out, diffOutput, nil, err)
+	}
+	defer diff.Close()
+
+	report, err := diff.Report(ctx)
+	if err != nil {
+		return failure(diffExitError, out, diffOutput, nil, err)
+	}
+
+	setReportServers(report, "")
+
+	if !diff.Empty() && diffOut != "" {
+		logger.Info("Generating plan...")
+		p, err := diff.Plan(ctx)
+		if err != nil {
+			return failure(diffExitError, out, diffOutput, report, err)
+		}
+
+		// plans name the configured hosts rather than the resolved or tunneled ones
+		p.Master = dbsync.ServerInfo{Host: config.Master.Host, Schema: config.Master.Schema}
+		p.Slave = dbsync.ServerInfo{Host: config.Slave.Host, Schema: config.Slave.Schema}
+
+		if err := dbsync.WritePlan(p, diffOut, diffCompress); err != nil {
+			return failure(diffExitError, out, diffOutput, report, err)
+		}
+
+		logger.Info("Plan written", "file", diffOut)
+		report.Plan = diffOut
+	}
+
+	write := writeTable
+	if diffOutput == outputJSON {
+		write = writeReport
+	}
+
+	if err := write(out, report); err != nil {
+		return failure(diffExitError, out, outputText, nil, err)
+	}
+
+	if diff.Empty() {
+		logger.Info("No differences")
+		return nil
+	}
+
+	return &exitError{code: diffExitDifferent, err: errDifferent}
+}
@@ -0,0 +1,113 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vcraescu/dbsync/internal/logger"
+	"github.com/vcraescu/dbsync/internal/progress"
+	"github.com/vcraescu/dbsync/pkg/dbsync"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history [MASTER_NAME|SNAPSHOT_DIR SLAVE_NAME]",
+	Short: "List the previous syncs of every pair, or of master [MASTER_NAME], or a snapshot, to slave [SLAVE_NAME].",
+	Args: func(_ *cobra.Command, args []string) error {
+		if len(args) != 0 && len(args) != 2 {
+			return errors.New("accepts no arguments or a master and a slave")
+		}
+
+		return nil
+	},
+	Run: runHistoryCmd,
+}
+
+var historyLimit int
+
+func init() {
+	historyCmd.Flags().IntVar(&historyLimit, "limit", 20, "Number of most recent syncs listed, all of them when 0")
+}
+
+// historyRun - run of a pair
+type historyRun struct {
+	pair *dbsync.State
+	run  dbsync.Run
+}
+
+func runHistoryCmd(_ *cobra.Command, args []string) {
+	store, err := stateStore()
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	var pairs []*dbsync.State
+	if len(args) == 2 {
+		pair, err := store.Load(pairName(args[0]), args[1])
+		if err != nil {
+			logger.Fatal(err.Error())
+		}
+
+		pairs = append(pairs, pair)
+	} else if pairs, err = store.States(); err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	var runs []historyRun
+	for _, pair := range pairs {
+		for _, run := range pair.Runs {
+			runs = append(runs, historyRun{pair: pair, run: run})
+		}
+	}
+
+	if len(runs) == 0 {
+		logger.Info("No sync found")
+		return
+	}
+
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].run.StartedAt.Before(runs[j].run.StartedAt)
+	})
+	if historyLimit > 0 && len(runs) > historyLimit {
+		runs = runs[len(runs)-historyLimit:]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STARTED\tMASTER\tSLAVE\tUSER\tDURATION\tBYTES\tRESULT\tTABLES")
+	for _, r := range runs {
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			formatTime(r.run.StartedAt),
+			r.pair.Master,
+			r.pair.Slave,
+			orDash(r.run.User),
+			time.Duration(r.run.DurationMs)*time.Millisecond,
+			progress.FormatBytes(r.run.Bytes),
+			formatResult(r.run),
+			orDash(strings.Join(r.run.Tables, ", ")),
+		)
+	}
+
+	if err := w.Flush(); err != nil {
+		logger.Fatal(err.Error())
+	}
+}
+
+func formatResult(run dbsync.Run) string {
+	if run.Succeeded() {
+		return "ok"
+	}
+
+	return "failed: " + run.Error
+}
+
+// formatTime - formats a time in the local timezone
+func formatTime(t time.Time) string {
+	return t.Local().Format("2006-01-02 15:04:05")
+}
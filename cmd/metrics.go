@@ -0,0 +1,123 @@
+package cmd
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/vcraescu/dbsync/internal/metrics"
+	"github.com/vcraescu/dbsync/pkg/dbsync"
+)
+
+// error phases of watch besides the sync phases
+const (
+	phaseWatch    = "watch"
+	phaseChecksum = "checksum"
+	phaseSync     = "sync"
+)
+
+// watchMetrics - prometheus metrics of watch
+type watchMetrics struct {
+	registry         *metrics.Registry
+	syncs            *metrics.Counter
+	tables           *metrics.Counter
+	rows             *metrics.Counter
+	bytes            *metrics.Counter
+	errors           *metrics.Counter
+	checksumDuration *metrics.Summary
+	lastSuccess      *metrics.Gauge
+
+	mu sync.Mutex
+	// pendingSince - when the oldest master change not synced yet was detected
+	pendingSince time.Time
+}
+
+func newWatchMetrics() *watchMetrics {
+	r := metrics.NewRegistry()
+	m := &watchMetrics{
+		registry: r,
+		syncs:    r.NewCounter("dbsync_syncs_total", "Syncs performed, by result.", "result"),
+		tables:   r.NewCounter("dbsync_tables_synced_total", "Tables created, refreshed or deleted on slave.", ""),
+		rows:     r.NewCounter("dbsync_rows_synced_total", "Rows dumped from master and imported into slave.", ""),
+		bytes:    r.NewCounter("dbsync_bytes_synced_total", "Bytes of sql streamed from master to slave.", ""),
+		errors:   r.NewCounter("dbsync_errors_total", "Errors, by phase.", "phase"),
+		checksumDuration: r.NewSummary(
+			"dbsync_checksum_duration_seconds",
+			"Durations of the comparisons of master and slave.",
+		),
+		lastSuccess: r.NewGauge(
+			"dbsync_last_success_timestamp_seconds",
+			"Unix time of the last successful sync.",
+		),
+	}
+
+	r.NewGaugeFunc(
+		"dbsync_replication_lag_seconds",
+		"Time since the oldest master change not synced yet was detected, 0 when in sync.",
+		m.lag,
+	)
+
+	return m
+}
+
+// changeDetected - starts the replication lag unless a previous change is pending already
+func (m *watchMetrics) changeDetected(now time.Time) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.pendingSince.IsZero() {
+		m.pendingSince = now
+	}
+}
+
+func (m *watchMetrics) lag() float64 {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.pendingSince.IsZero() {
+		return 0
+	}
+
+	return time.Since(m.pendingSince).Seconds()
+}
+
+// synced - counts a sync and its tables, rows and bytes. A successful sync clears the changes
+// detected before it started.
+func (m *watchMetrics) synced(start time.Time, res *dbsync.Result, rows int64, err error) {
+	if res != nil {
+		for _, t := range res.Tables {
+			if t.Err == nil {
+				m.tables.Inc("")
+			}
+
+			m.bytes.Add("", float64(t.Bytes))
+		}
+	}
+
+	m.rows.Add("", float64(rows))
+	if err != nil {
+		m.syncs.Inc("failure")
+		m.errors.Inc(errorPhase(err))
+		return
+	}
+
+	m.syncs.Inc("success")
+	m.lastSuccess.Set(float64(time.Now().Unix()))
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if !m.pendingSince.After(start) {
+		m.pendingSince = time.Time{}
+	}
+}
+
+// errorPhase - returns the sync phase which failed
+func errorPhase(err error) string {
+	var phaseErr *dbsync.PhaseError
+	if errors.As(err, &phaseErr) {
+		return phaseErr.Phase
+	}
+
+	return phaseSync
+}
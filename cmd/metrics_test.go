@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/vcraescu/dbsync/pkg/dbsync"
+)
+
+func TestErrorPhase(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&dbsync.PhaseError{Phase: dbsync.PhaseDump, Table: "users", Err: errors.New("killed")}, "dump"},
+		{fmt.Errorf("sync: %w", &dbsync.PhaseError{Phase: dbsync.PhaseImport, Err: errors.New("gone")}), "import"},
+		{errors.New("state: permission denied"), phaseSync},
+	}
+
+	for _, tt := range tests {
+		if got := errorPhase(tt.err); got != tt.want {
+			t.Errorf("errorPhase(%q) = %q, want %q", tt.err, got, tt.want)
+		}
+	}
+}
+
+func TestWatchMetricsSynced(t *testing.T) {
+	m := newWatchMetrics()
+	start := time.Now()
+	m.changeDetected(start.Add(-time.Minute))
+
+	res := &dbsync.Result{Tables: []dbsync.TableResult{
+		{Table: "users", Bytes: 100},
+		{Table: "orders", Bytes: 50, Err: errors.New("killed")},
+	}}
+	m.synced(start, res, 10, &dbsync.PhaseError{Phase: dbsync.PhaseImport, Table: "orders", Err: errors.New("killed")})
+
+	if m.lag() < 60 {
+		t.Errorf("lag = %v after a failed sync, want the time since the change", m.lag())
+	}
+
+	m.synced(start, &dbsync.Result{}, 0, nil)
+	if m.lag() != 0 {
+		t.Errorf("lag = %v after a successful sync, want 0", m.lag())
+	}
+
+	var b strings.Builder
+	m.registry.WriteTo(&b)
+	for _, want := range []string{
+		`dbsync_syncs_total{result="failure"} 1`,
+		`dbsync_syncs_total{result="success"} 1`,
+		"dbsync_tables_synced_total 1",
+		"dbsync_bytes_synced_total 150",
+		"dbsync_rows_synced_total 10",
+		`dbsync_errors_total{phase="import"} 1`,
+		"dbsync_replication_lag_seconds 0",
+	} {
+		if !strings.Contains(b.String(), want+"\n") {
+			t.Errorf("metrics do not hold %q:\n%s", want, b.String())
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"context"
+	"sort"
+	"time"
+
+	"github.com/vcraescu/dbsync/internal/logger"
+	"github.com/vcraescu/dbsync/internal/notify"
+	"github.com/vcraescu/dbsync/internal/state"
+	"github.com/vcraescu/dbsync/internal/watcher"
+	"github.com/vcraescu/dbsync/pkg/dbsync"
+)
+
+// createNotifiers - returns the notifiers of the notifications config, sorted by name
+func createNotifiers() ([]*notify.Notifier, error) {
+	names := make([]string, 0, len(config.Notifications))
+	for name := range config.Notifications {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	notifiers := make([]*notify.Notifier, 0, len(names))
+	for _, name := range names {
+		cfg := config.Notifications[name]
+		n, err := notify.New(name, cfg.Type, cfg.URL, cfg.Headers, cfg.Events)
+		if err != nil {
+			return nil, err
+		}
+
+		notifiers = append(notifiers, n)
+	}
+
+	return notifiers, nil
+}
+
+// syncSummary - returns the summary of the sync of the pair started at start, failed when err is not nil.
+// The tables come from the diff, or the diff of the result when nil, and the rows from the progress.
+func syncSummary(
+	args []string,
+	start time.Time,
+	diff *dbsync.Diff,
+	res *dbsync.Result,
+	p *dbsync.Progress,
+	err error,
+) notify.Summary {
+	s := notify.Summary{
+		Event:      notify.EventSuccess,
+		Master:     pairName(args[0]),
+		Slave:      args[1],
+		User:       state.CurrentUser(),
+		StartedAt:  start.UTC(),
+		DurationMs: int64(time.Since(start) / time.Millisecond),
+	}
+
+	if err != nil {
+		s.Event = notify.EventFailure
+		s.Error = logger.Default().Redact(err.Error())
+	}
+
+	if diff == nil && res != nil {
+		diff = res.Diff
+	}
+
+	if diff != nil {
+		s.Create = diff.Create
+		s.Refresh = diff.Refresh
+		s.Delete = diff.Delete
+		s.Skipped = len(diff.Skipped)
+	}
+
+	if res != nil {
+		for _, t := range res.Tables {
+			s.Bytes += t.Bytes
+		}
+	}
+
+	if p != nil {
+		_, s.Rows, _ = p.Snapshot().Totals()
+	}
+
+	return s
+}
+
+// diffSummary - returns the summary of the master changes detected by watch
+func diffSummary(args []string, diff watcher.Diff) notify.Summary {
+	now := time.Now()
+
+	return notify.Summary{
+		Event:     notify.EventDiff,
+		Master:    args[0],
+		Slave:     args[1],
+		User:      state.CurrentUser(),
+		StartedAt: now.UTC(),
+		Create:    diff.Created,
+		Refresh:   diff.Updated,
+		Delete:    diff.Deleted,
+	}
+}
+
+// sendNotification - sends the summary to the notifiers subscribed to its event. The command context
+// is not used, as the failure notified may be its cancellation.
+func sendNotification(notifiers []*notify.Notifier, s notify.Summary) {
+	notify.Notify(context.Background(), notifiers, s)
+}
@@ -0,0 +1,135 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/vcraescu/dbsync/internal/logger"
+	"github.com/vcraescu/dbsync/pkg/dbsync"
+)
+
+const (
+	outputText = "text"
+	outputJSON = "json"
+)
+
+// validateOutput - returns an error if the output format is unknown
+func validateOutput(output string) error {
+	if output != outputText && output != outputJSON {
+		return fmt.Errorf("unknown output %s, expected %s or %s", output, outputText, outputJSON)
+	}
+
+	return nil
+}
+
+// setReportServers - names the configured hosts in the report rather than the resolved or tunneled ones
+func setReportServers(r *dbsync.Report, snapshot string) {
+	r.Master = dbsync.ServerInfo{Host: config.Master.Host, Schema: config.Master.Schema}
+	if snapshot != "" {
+		r.Master = dbsync.ServerInfo{Schema: snapshot}
+	}
+
+	r.Slave = dbsync.ServerInfo{Host: config.Slave.Host, Schema: config.Slave.Schema}
+}
+
+// writeReport - writes the report to w as json
+func writeReport(w io.Writer, r *dbsync.Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(r)
+}
+
+// writeTable - writes the tables of the report to w as a text table, followed by the objects
+func writeTable(w io.Writer, r *dbsync.Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TABLE\tSTATUS\tMASTER CHECKSUM\tSLAVE CHECKSUM\tMASTER ROWS\tSLAVE ROWS")
+	for _, t := range r.Tables {
+		fmt.Fprintf(
+			tw,
+			"%s\t%s\t%s\t%s\t%s\t%s\n",
+			t.Name,
+			t.Status,
+			orDash(t.MasterChecksum),
+			orDash(t.SlaveChecksum),
+			formatRows(t.MasterRows, r.RowsEstimated),
+			formatRows(t.SlaveRows, r.RowsEstimated),
+		)
+	}
+
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	for _, o := range r.DropObjects {
+		if _, err := fmt.Fprintf(w, "Drop %s\n", o); err != nil {
+			return err
+		}
+	}
+
+	for _, o := range r.CreateObjects {
+		if _, err := fmt.Fprintf(w, "Create %s\n", o); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+
+	return s
+}
+
+// formatRows - formats a row count, estimates prefixed with ~
+func formatRows(n *int64, estimated bool) string {
+	if n == nil {
+		return "-"
+	}
+
+	if estimated {
+		return "~" + strconv.FormatInt(*n, 10)
+	}
+
+	return strconv.FormatInt(*n, 10)
+}
+
+// exitError - error ending a command with an exit code, returned to cobra rather than exiting
+// right away so that the deferred calls of the command run first
+type exitError struct {
+	code int
+	err  error
+}
+
+func (e *exitError) Error() string {
+	return e.err.Error()
+}
+
+func (e *exitError) Unwrap() error {
+	return e.err
+}
+
+// failure - logs err and returns it to exit with the given code. With json output the report,
+// created when nil, is written to w first holding the error.
+func failure(code int, w io.Writer, output string, r *dbsync.Report, err error) error {
+	logger.Error(err.Error())
+	if output == outputJSON {
+		if r == nil {
+			r = &dbsync.Report{Tables: []dbsync.TableReport{}}
+		}
+
+		r.Synced = false
+		r.Error = err.Error()
+		if werr := writeReport(w, r); werr != nil {
+			logger.Error(werr.Error())
+		}
+	}
+
+	return &exitError{code: code, err: err}
+}
@@ -0,0 +1,168 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vcraescu/dbsync/pkg/dbsync"
+)
+
+func TestWriteTable(t *testing.T) {
+	rows := func(n int64) *int64 {
+		return &n
+	}
+
+	r := &dbsync.Report{
+		Tables: []dbsync.TableReport{
+			{Name: "new", Status: dbsync.TableCreated, MasterRows: rows(10)},
+			{Name: "orders", Status: dbsync.TableUpdated, MasterChecksum: "m1", SlaveChecksum: "s1", MasterRows: rows(5), SlaveRows: rows(3)},
+		},
+		CreateObjects: []string{"view active_users"},
+		DropObjects:   []string{"trigger audit"},
+		RowsEstimated: true,
+	}
+
+	var out bytes.Buffer
+	if err := writeTable(&out, r); err != nil {
+		t.Fatalf("writeTable() error = %s", err)
+	}
+
+	want := "TABLE   STATUS   MASTER CHECKSUM  SLAVE CHECKSUM  MASTER ROWS  SLAVE ROWS\n" +
+		"new     created  -                -               ~10          -\n" +
+		"orders  updated  m1               s1              ~5           ~3\n" +
+		"Drop trigger audit\n" +
+		"Create view active_users\n"
+	if out.String() != want {
+		t.Errorf("writeTable() =\n%s\nwant\n%s", out.String(), want)
+	}
+
+	if got := formatRows(rows(7), false); got != "7" {
+		t.Errorf("formatRows() of a counted row count = %q, want 7", got)
+	}
+}
+
+func TestFailure(t *testing.T) {
+	var out bytes.Buffer
+	err := failure(diffExitError, &out, outputJSON, nil, errors.New("master unreachable"))
+
+	if code := exitCode(err); code != diffExitError {
+		t.Errorf("exitCode() = %d, want %d", code, diffExitError)
+	}
+
+	var r dbsync.Report
+	if err := json.Unmarshal(out.Bytes(), &r); err != nil {
+		t.Fatalf("report %q: %s", out.String(), err)
+	}
+
+	if r.Synced || r.Error != "master unreachable" || r.Tables == nil {
+		t.Errorf("report = %+v, want the error", r)
+	}
+
+	out.Reset()
+	failure(1, &out, outputText, nil, errors.New("master unreachable"))
+	if out.Len() > 0 {
+		t.Errorf("text output written %q, want nothing", out.String())
+	}
+}
+
+// sqliteFile - creates a sqlite database file by running sql
+func sqliteFile(t *testing.T, dir, name, sql string) string {
+	t.Helper()
+
+	file := filepath.Join(dir, name+".db")
+	cmd := exec.Command("sqlite3", "-batch", "-bail", file)
+	cmd.Stdin = strings.NewReader(sql)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("sqlite3 %s: %s: %s", name, err, out)
+	}
+
+	return file
+}
+
+// sqliteConfig - writes a config of sqlite servers, the database files by server name, returning its file
+func sqliteConfig(t *testing.T, dir string, servers map[string]string) string {
+	t.Helper()
+
+	var cfg strings.Builder
+	cfg.WriteString("servers:\n")
+	for name, file := range servers {
+		fmt.Fprintf(&cfg, "  %s:\n    driver: sqlite\n    schema: %s\n", name, file)
+	}
+
+	file := filepath.Join(dir, "dbsync.yml")
+	if err := ioutil.WriteFile(file, []byte(cfg.String()), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	return file
+}
+
+// execute - runs the command line, returning its output and exit code
+func execute(args ...string) (string, int) {
+	var out bytes.Buffer
+	rootCmd.SetOutput(&out)
+	defer rootCmd.SetOutput(nil)
+	rootCmd.SetArgs(args)
+
+	if err := rootCmd.Execute(); err != nil {
+		return out.String(), exitCode(err)
+	}
+
+	return out.String(), 0
+}
+
+func TestDiffExitCodes(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+
+	dir := t.TempDir()
+	const schema = "create table users (id integer primary key, name text);\n"
+	servers := map[string]string{
+		"master": sqliteFile(t, dir, "master", schema+"insert into users values (1, 'a');\n"),
+		"same":   sqliteFile(t, dir, "same", schema+"insert into users values (1, 'a');\n"),
+		"other":  sqliteFile(t, dir, "other", schema+"insert into users values (1, 'b');\n"),
+	}
+
+	cfgFile := sqliteConfig(t, dir, servers)
+
+	tests := []struct {
+		slave  string
+		code   int
+		status dbsync.TableStatus
+		err    string
+	}{
+		{"same", diffExitSame, dbsync.TableUnchanged, ""},
+		{"other", diffExitDifferent, dbsync.TableUpdated, ""},
+		{"missing", diffExitError, "", "slave server name not found in config file"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.slave, func(t *testing.T) {
+			out, code := execute("diff", "master", tt.slave, "--config", cfgFile, "--output", outputJSON)
+			if code != tt.code {
+				t.Errorf("exit code = %d, want %d", code, tt.code)
+			}
+
+			var r dbsync.Report
+			if err := json.Unmarshal([]byte(out), &r); err != nil {
+				t.Fatalf("report %q: %s", out, err)
+			}
+
+			if r.Error != tt.err {
+				t.Errorf("report error = %q, want %q", r.Error, tt.err)
+			}
+
+			if tt.status != "" && (len(r.Tables) != 1 || r.Tables[0].Status != tt.status) {
+				t.Errorf("report tables = %+v, want users %s", r.Tables, tt.status)
+			}
+		})
+	}
+}
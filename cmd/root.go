@@ -1,17 +1,26 @@
 package cmd
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"log"
 	"os"
+	"os/signal"
+	"syscall"
+	"time"
 
 	"github.com/mitchellh/go-homedir"
 	"github.com/spf13/cobra"
 	"github.com/spf13/viper"
-	"github.com/vcraescu/dbsync/internal/database/mysql"
+	"github.com/vcraescu/dbsync/internal/compress"
+	"github.com/vcraescu/dbsync/internal/database"
+	"github.com/vcraescu/dbsync/internal/database/sqlite"
+	"github.com/vcraescu/dbsync/internal/logger"
 	"github.com/vcraescu/dbsync/internal/net"
+	"github.com/vcraescu/dbsync/internal/snapshot"
 	"github.com/vcraescu/dbsync/internal/tunnel"
+	"github.com/vcraescu/dbsync/pkg/dbsync"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -19,32 +28,68 @@ import (
 const Version = "0.0.2"
 
 var rootCmd = &cobra.Command{
-	Use:          "dbsync master_server slave_server",
-	Short:        "Sync 2 MySQL databases",
-	Long:         `Sync 2 MySQL databases`,
+	Use:          "dbsync",
+	Short:        "Sync 2 MySQL or PostgreSQL databases",
+	Long:         `Sync 2 MySQL or PostgreSQL databases`,
 	Version:      Version,
 	SilenceUsage: true,
-	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
-		masterName := args[0]
-		slaveName := args[1]
-		cfg, ok := config.Servers[masterName]
-		if !ok {
-			return errors.New("master server name not found in config file")
-		}
-		config.Master = cfg
+	// errors are printed by Execute, unless already logged by the command exiting with them
+	SilenceErrors: true,
+}
 
-		cfg, ok = config.Servers[slaveName]
-		if !ok {
-			return errors.New("slave server name not found in config file")
-		}
-		config.Slave = cfg
+// loadServers - loads master and slave server config from the first two arguments
+func loadServers(_ *cobra.Command, args []string) error {
+	if err := loadMaster(args[0]); err != nil {
+		return err
+	}
 
-		if !config.Validate() {
-			return errors.New("invalid config")
-		}
+	if err := loadSlave(args[1]); err != nil {
+		return err
+	}
 
-		return nil
-	},
+	if !config.Validate() {
+		return errors.New("invalid config")
+	}
+
+	return nil
+}
+
+// loadSyncServers - loads master and slave server config, master being optional when the
+// first argument is a snapshot directory
+func loadSyncServers(cmd *cobra.Command, args []string) error {
+	if !snapshot.IsSnapshot(args[0]) {
+		return loadServers(cmd, args)
+	}
+
+	if err := loadSlave(args[1]); err != nil {
+		return err
+	}
+
+	if !config.ValidateSlave() {
+		return errors.New("invalid config")
+	}
+
+	return nil
+}
+
+func loadMaster(name string) error {
+	cfg, ok := config.Servers[name]
+	if !ok {
+		return errors.New("master server name not found in config file")
+	}
+	config.Master = cfg
+
+	return nil
+}
+
+func loadSlave(name string) error {
+	cfg, ok := config.Servers[name]
+	if !ok {
+		return errors.New("slave server name not found in config file")
+	}
+	config.Slave = cfg
+
+	return nil
 }
 
 // SSHConfig - ssh config from yaml file
@@ -57,56 +102,168 @@ type SSHConfig struct {
 
 // ServerConfig - server config from yaml file
 type ServerConfig struct {
-	SSHConfig SSHConfig `mapstructure:"ssh"`
-	Username  string    `mapstructure:"username"`
-	Password  string    `mapstructure:"password"`
-	Host      string    `mapstructure:"host"`
-	Schema    string    `mapstructure:"schema"`
-	Port      int       `mapstructure:"port"`
-	Timezone  string    `mapstructure:"timezone"`
+	SSHConfig      SSHConfig `mapstructure:"ssh"`
+	Driver         string    `mapstructure:"driver"`
+	Username       string    `mapstructure:"username"`
+	Password       string    `mapstructure:"password"`
+	Host           string    `mapstructure:"host"`
+	Schema         string    `mapstructure:"schema"`
+	Port           int       `mapstructure:"port"`
+	Timezone       string    `mapstructure:"timezone"`
+	MaxConnections int       `mapstructure:"max_connections"`
+	Compression    string    `mapstructure:"compression"`
+}
+
+// MaskConfig - column masking rule from yaml file
+type MaskConfig struct {
+	Type      string `mapstructure:"type"`
+	Value     string `mapstructure:"value"`
+	Generator string `mapstructure:"generator"`
+}
+
+// TimeoutsConfig - maximum durations of the sync phases from yaml file, e.g. "10m", unlimited when not set
+type TimeoutsConfig struct {
+	Checksum time.Duration `mapstructure:"checksum"`
+	Dump     time.Duration `mapstructure:"dump"`
+	Import   time.Duration `mapstructure:"import"`
+}
+
+// TableConfig - table config from yaml file
+type TableConfig struct {
+	Where string                `mapstructure:"where"`
+	Mask  map[string]MaskConfig `mapstructure:"mask"`
+	// Types - slave column types by column, overriding the mapped types when the drivers differ
+	Types map[string]string `mapstructure:"types"`
+}
+
+// MaskingConfig - masking config from yaml file
+type MaskingConfig struct {
+	Seed string `mapstructure:"seed"`
+}
+
+// NotificationConfig - webhook notified of the syncs from yaml file
+type NotificationConfig struct {
+	// Type - webhook, posting the summary as json, or slack, posting it as a chat message
+	Type    string            `mapstructure:"type"`
+	URL     string            `mapstructure:"url"`
+	Headers map[string]string `mapstructure:"headers"`
+	// Events - events notified: success, failure and diff, all of them when empty
+	Events []string `mapstructure:"events"`
 }
 
 // Config - the entire yaml config
 type Config struct {
-	Servers    map[string]ServerConfig `mapstructure:"servers"`
-	Master     ServerConfig
-	Slave      ServerConfig
-	configFile string
+	Servers       map[string]ServerConfig       `mapstructure:"servers"`
+	Tables        map[string]TableConfig        `mapstructure:"tables"`
+	Masking       MaskingConfig                 `mapstructure:"masking"`
+	Subset        map[string]string             `mapstructure:"subset"`
+	Timeouts      TimeoutsConfig                `mapstructure:"timeouts"`
+	Notifications map[string]NotificationConfig `mapstructure:"notifications"`
+	Master        ServerConfig
+	Slave         ServerConfig
+	configFile    string
+	stateDir      string
+	logLevel      string
+	logFormat     string
 }
 
 var config = &Config{}
 
-// CreateMasterConnectionConfig - create master server mysql connection config
-func (cfg *Config) CreateMasterConnectionConfig() *mysql.ConnectionConfig {
+// sshTunnels - started ssh tunnels by server, master or slave
+var sshTunnels = make(map[string]*tunnel.SSHTunnel)
+
+// CreateMasterConnectionConfig - create master server connection config
+func (cfg *Config) CreateMasterConnectionConfig() *database.ConnectionConfig {
 	ip, err := cfg.GetMasterHostIP()
 	if err != nil {
 		panic(err)
 	}
 
-	return &mysql.ConnectionConfig{
-		Username: cfg.Master.Username,
-		Password: cfg.Master.Password,
-		Host:     ip,
-		Port:     cfg.Master.Port,
-		Schema:   cfg.Master.Schema,
-		Timezone: cfg.Master.Timezone,
+	return &database.ConnectionConfig{
+		Driver:         cfg.Master.Driver,
+		Username:       cfg.Master.Username,
+		Password:       cfg.Master.Password,
+		Host:           ip,
+		Port:           cfg.Master.Port,
+		Schema:         cfg.Master.Schema,
+		Timezone:       cfg.Master.Timezone,
+		MaxConnections: cfg.Master.MaxConnections,
+		Filters:        cfg.Filters(),
+		Compression:    compress.Algorithm(cfg.Master.Compression),
 	}
 }
 
-// CreateSlaveConnectionConfig - creates slave connection mysql config
-func (cfg *Config) CreateSlaveConnectionConfig() *mysql.ConnectionConfig {
+// CreateSlaveConnectionConfig - creates slave connection config
+func (cfg *Config) CreateSlaveConnectionConfig() *database.ConnectionConfig {
 	ip, err := cfg.GetSlaveHostIP()
 	if err != nil {
 		panic(err)
 	}
 
-	return &mysql.ConnectionConfig{
-		Username: cfg.Slave.Username,
-		Password: cfg.Slave.Password,
-		Host:     ip,
-		Port:     cfg.Slave.Port,
-		Schema:   cfg.Slave.Schema,
-		Timezone: cfg.Slave.Timezone,
+	return &database.ConnectionConfig{
+		Driver:         cfg.Slave.Driver,
+		Username:       cfg.Slave.Username,
+		Password:       cfg.Slave.Password,
+		Host:           ip,
+		Port:           cfg.Slave.Port,
+		Schema:         cfg.Slave.Schema,
+		Timezone:       cfg.Slave.Timezone,
+		MaxConnections: cfg.Slave.MaxConnections,
+		Filters:        cfg.Filters(),
+		Compression:    compress.Algorithm(cfg.Slave.Compression),
+	}
+}
+
+// Filters - returns the where conditions by table
+func (cfg *Config) Filters() map[string]string {
+	filters := make(map[string]string)
+	for table, tableCfg := range cfg.Tables {
+		if tableCfg.Where != "" {
+			filters[table] = tableCfg.Where
+		}
+	}
+
+	return filters
+}
+
+// Options - creates the sync options of the table config using the given checksum strategy
+func (cfg *Config) Options(checksum string) dbsync.Options {
+	opts := dbsync.Options{
+		Checksum: checksum,
+		Filters:  cfg.Filters(),
+		Masks:    make(map[string]map[string]dbsync.Mask),
+		MaskSeed: cfg.Masking.Seed,
+		Types:    make(map[string]map[string]string),
+		Timeouts: cfg.timeouts(),
+	}
+
+	for table, tableCfg := range cfg.Tables {
+		for column, mask := range tableCfg.Mask {
+			if opts.Masks[table] == nil {
+				opts.Masks[table] = make(map[string]dbsync.Mask)
+			}
+
+			opts.Masks[table][column] = dbsync.Mask{
+				Type:      mask.Type,
+				Value:     mask.Value,
+				Generator: mask.Generator,
+			}
+		}
+
+		if len(tableCfg.Types) > 0 {
+			opts.Types[table] = tableCfg.Types
+		}
+	}
+
+	return opts
+}
+
+// timeouts - returns the configured sync phase timeouts
+func (cfg *Config) timeouts() dbsync.Timeouts {
+	return dbsync.Timeouts{
+		Checksum: cfg.Timeouts.Checksum,
+		Dump:     cfg.Timeouts.Dump,
+		Import:   cfg.Timeouts.Import,
 	}
 }
 
@@ -132,74 +289,82 @@ func (cfg *Config) GetSlaveHostIP() (string, error) {
 
 // Validate - validate configuration
 func (cfg *Config) Validate() bool {
-	valid := true
-	if cfg.Master.Username == "" {
-		fmt.Fprintln(os.Stderr, "Error: Master username is required")
-		valid = false
-	}
+	masterValid := cfg.ValidateMaster()
+	slaveValid := cfg.ValidateSlave()
 
-	if cfg.Master.Password == "" {
-		fmt.Fprintln(os.Stderr, "Error: Master password is required")
-		valid = false
-	}
-
-	if cfg.Master.Host == "" {
-		fmt.Fprintln(os.Stderr, "Error: Master host is required")
-		valid = false
-	}
+	return masterValid && slaveValid
+}
 
-	if cfg.Master.Port <= 0 {
-		fmt.Fprintln(os.Stderr, "Error: Master port is invalid")
-		valid = false
-	}
+// ValidateMaster - validate master configuration
+func (cfg *Config) ValidateMaster() bool {
+	return cfg.Master.validate("Master")
+}
 
-	if cfg.Master.Schema == "" {
-		fmt.Fprintln(os.Stderr, "Error: Master schema is required")
-		valid = false
-	}
+// ValidateSlave - validate slave configuration
+func (cfg *Config) ValidateSlave() bool {
+	return cfg.Slave.validate("Slave")
+}
 
-	if cfg.Slave.Username == "" {
-		fmt.Fprintln(os.Stderr, "Error: Master username is required")
-		valid = false
-	}
+func (cfg *ServerConfig) validate(name string) bool {
+	valid := true
+	// sqlite databases are local files
+	if cfg.Driver != sqlite.DriverName {
+		if cfg.Username == "" {
+			fmt.Fprintf(os.Stderr, "Error: %s username is required\n", name)
+			valid = false
+		}
 
-	if cfg.Slave.Password == "" {
-		fmt.Fprintln(os.Stderr, "Error: Master password is required")
-		valid = false
-	}
+		if cfg.Password == "" {
+			fmt.Fprintf(os.Stderr, "Error: %s password is required\n", name)
+			valid = false
+		}
 
-	if cfg.Slave.Host == "" {
-		fmt.Fprintln(os.Stderr, "Error: Master host is required")
-		valid = false
-	}
+		if cfg.Host == "" {
+			fmt.Fprintf(os.Stderr, "Error: %s host is required\n", name)
+			valid = false
+		}
 
-	if cfg.Slave.Port <= 0 {
-		fmt.Fprintln(os.Stderr, "Error: Slave port is invalid")
-		valid = false
+		if cfg.Port <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: %s port is invalid\n", name)
+			valid = false
+		}
 	}
 
-	if cfg.Slave.Schema == "" {
-		fmt.Fprintln(os.Stderr, "Error: Slave schema is required")
+	if cfg.Schema == "" {
+		fmt.Fprintf(os.Stderr, "Error: %s schema is required\n", name)
 		valid = false
 	}
 
-	if cfg.Slave.SSHConfig.User != "" || cfg.Slave.SSHConfig.Host != "" || cfg.Slave.SSHConfig.Port > 0 {
-		if cfg.Slave.SSHConfig.User == "" {
-			fmt.Fprintln(os.Stderr, "Error: Slave SSH user is required")
+	if cfg.SSHConfig.User != "" || cfg.SSHConfig.Host != "" || cfg.SSHConfig.Port > 0 {
+		if cfg.SSHConfig.User == "" {
+			fmt.Fprintf(os.Stderr, "Error: %s SSH user is required\n", name)
 			valid = false
 		}
 
-		if cfg.Slave.SSHConfig.Host == "" {
-			fmt.Fprintln(os.Stderr, "Error: Slave SSH host is required")
+		if cfg.SSHConfig.Host == "" {
+			fmt.Fprintf(os.Stderr, "Error: %s SSH host is required\n", name)
 			valid = false
 		}
 
-		if cfg.Slave.SSHConfig.Port <= 0 {
-			fmt.Fprintln(os.Stderr, "Error: Slave SSH port is invalid")
+		if cfg.SSHConfig.Port <= 0 {
+			fmt.Fprintf(os.Stderr, "Error: %s SSH port is invalid\n", name)
 			valid = false
 		}
 	}
 
+	if _, err := compress.ParseAlgorithm(cfg.Compression); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s %s\n", name, err)
+		valid = false
+	}
+
+	if _, err := database.Lookup(cfg.Driver); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s %s\n", name, err)
+		valid = false
+	} else if cfg.Compression != "" && cfg.Driver != "" && cfg.Driver != database.DefaultDriver {
+		fmt.Fprintf(os.Stderr, "Error: %s compression is only supported by the mysql driver\n", name)
+		valid = false
+	}
+
 	return valid
 }
 
@@ -210,7 +375,7 @@ func (cfg *SSHConfig) CreateAuthMethod() (*ssh.AuthMethod, error) {
 		if err != nil {
 			return nil, fmt.Errorf("ssh agent auth method: %s", err)
 		}
-		log.Println("Authenticating to slave server using SSH Agent")
+		logger.Info("Authenticating to the ssh server using the ssh agent")
 
 		return &authMethod, nil
 	}
@@ -225,23 +390,23 @@ func (cfg *SSHConfig) CreateAuthMethod() (*ssh.AuthMethod, error) {
 		return nil, fmt.Errorf("public key auth method: %s", err)
 	}
 
-	log.Printf("Authenticating to slave server using private key: %s\n", file)
+	logger.Info("Authenticating to the ssh server using a private key", "key", file)
 
 	return &authMethod, nil
 }
 
-func startSSHTunnel(mysqlCfg *mysql.ConnectionConfig, sshCfg SSHConfig) (*tunnel.SSHTunnel, error) {
+func startSSHTunnel(ctx context.Context, dbCfg *database.ConnectionConfig, sshCfg SSHConfig) (*tunnel.SSHTunnel, error) {
 	localEndpoint := tunnel.Endpoint{
 		Host: "localhost",
 	}
 	serverEndpoint := tunnel.Endpoint{
 		Host: sshCfg.Host,
-		Port: config.Slave.SSHConfig.Port,
-		User: config.Slave.SSHConfig.User,
+		Port: sshCfg.Port,
+		User: sshCfg.User,
 	}
 	remoteEndpoint := tunnel.Endpoint{
-		Host: mysqlCfg.Host,
-		Port: mysqlCfg.Port,
+		Host: dbCfg.Host,
+		Port: dbCfg.Port,
 	}
 
 	authMethod, err := sshCfg.CreateAuthMethod()
@@ -249,16 +414,85 @@ func startSSHTunnel(mysqlCfg *mysql.ConnectionConfig, sshCfg SSHConfig) (*tunnel
 		return nil, err
 	}
 
-	t, err := tunnel.StartSSHTunnel(localEndpoint, serverEndpoint, remoteEndpoint, authMethod)
+	t, err := tunnel.StartSSHTunnel(ctx, localEndpoint, serverEndpoint, remoteEndpoint, authMethod)
 	if err != nil {
 		return nil, err
 	}
 
-	mysqlCfg.Port = t.LocalPort()
+	dbCfg.Port = t.LocalPort()
 
 	return t, nil
 }
 
+// createMasterServer - starts the master ssh tunnel when required and returns the master server
+func createMasterServer(ctx context.Context) (dbsync.Server, error) {
+	cfg := config.CreateMasterConnectionConfig()
+	if err := startMasterSSHTunnel(ctx, cfg); err != nil {
+		return dbsync.Server{}, err
+	}
+
+	return createServer(cfg), nil
+}
+
+// createSlaveServer - starts the slave ssh tunnel when required and returns the slave server
+func createSlaveServer(ctx context.Context) (dbsync.Server, error) {
+	cfg := config.CreateSlaveConnectionConfig()
+	if err := startSlaveSSHTunnel(ctx, cfg); err != nil {
+		return dbsync.Server{}, err
+	}
+
+	return createServer(cfg), nil
+}
+
+// createServer - returns the server of a connection config, which points to the local end of the ssh tunnel
+func createServer(cfg *database.ConnectionConfig) dbsync.Server {
+	return dbsync.Server{
+		Driver:         cfg.Driver,
+		Username:       cfg.Username,
+		Password:       cfg.Password,
+		Host:           cfg.Host,
+		Port:           cfg.Port,
+		Schema:         cfg.Schema,
+		Timezone:       cfg.Timezone,
+		MaxConnections: cfg.MaxConnections,
+		Compression:    string(cfg.Compression),
+	}
+}
+
+// startMasterSSHTunnel - starts the master ssh tunnel when required and points the config to it
+func startMasterSSHTunnel(ctx context.Context, dbCfg *database.ConnectionConfig) error {
+	if !config.MasterSSHTunnelIsRequired() {
+		return nil
+	}
+
+	t, err := startSSHTunnel(ctx, dbCfg, config.Master.SSHConfig)
+	if err != nil {
+		return err
+	}
+	sshTunnels["master"] = t
+
+	logger.Info("SSH tunnel started", "server", "master", "local", fmt.Sprintf("%s:%d", t.LocalHost(), t.LocalPort()))
+
+	return nil
+}
+
+// startSlaveSSHTunnel - starts the slave ssh tunnel when required and points the config to it
+func startSlaveSSHTunnel(ctx context.Context, dbCfg *database.ConnectionConfig) error {
+	if !config.SlaveSSHTunnelIsRequired() {
+		return nil
+	}
+
+	t, err := startSSHTunnel(ctx, dbCfg, config.Slave.SSHConfig)
+	if err != nil {
+		return err
+	}
+	sshTunnels["slave"] = t
+
+	logger.Info("SSH tunnel started", "server", "slave", "local", fmt.Sprintf("%s:%d", t.LocalHost(), t.LocalPort()))
+
+	return nil
+}
+
 func init() {
 	cobra.OnInitialize(initConfig)
 	rootCmd.PersistentFlags().StringVar(
@@ -267,18 +501,36 @@ func init() {
 		"",
 		"Config file (default $HOME/.dbsync.yaml or ./.dbsync.yml)",
 	)
+	rootCmd.PersistentFlags().StringVar(
+		&config.stateDir,
+		"state-dir",
+		"",
+		"Directory of the state of the synced pairs (default $HOME/.dbsync/state)",
+	)
+	rootCmd.PersistentFlags().StringVar(&config.logLevel, "log-level", "info", "Log level: debug, info, warn or error")
+	rootCmd.PersistentFlags().StringVar(&config.logFormat, "log-format", logger.TextFormat, "Log format: text or json")
 
 	rootCmd.AddCommand(syncCmd)
+	rootCmd.AddCommand(diffCmd)
+	rootCmd.AddCommand(applyCmd)
+	rootCmd.AddCommand(snapshotCmd)
+	rootCmd.AddCommand(bisyncCmd)
+	rootCmd.AddCommand(historyCmd)
+	rootCmd.AddCommand(statusCmd)
+	rootCmd.AddCommand(watchCmd)
 }
 
 func initConfig() {
+	initLogger()
+
 	if config.configFile != "" {
 		viper.SetConfigFile(config.configFile)
 		if err := viper.ReadInConfig(); err != nil {
-			log.Fatal("Can't read config:", err)
+			logger.Fatal("Can't read config", "error", err)
 		}
 
 		viper.Unmarshal(&config)
+		addSecrets()
 		return
 	}
 
@@ -291,11 +543,53 @@ func initConfig() {
 	}
 
 	viper.Unmarshal(&config)
+	addSecrets()
+}
+
+// initLogger - configures the logger from the flags; the standard logger, used by the libraries,
+// goes through it as well so that its messages are redacted
+func initLogger() {
+	if err := logger.Configure(config.logLevel, config.logFormat); err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	log.SetFlags(0)
+	log.SetOutput(logger.Default().Writer(logger.InfoLevel))
+}
+
+// addSecrets - redacts the passwords of the configured servers and the notification urls, which
+// usually hold a token, from the logs
+func addSecrets() {
+	for _, cfg := range config.Servers {
+		logger.AddSecret(cfg.Password)
+	}
+
+	for _, cfg := range config.Notifications {
+		logger.AddSecret(cfg.URL)
+	}
+}
+
+// signalContext - returns a context cancelled on interrupt or termination, which kills the running
+// database clients and closes the ssh tunnels
+func signalContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 }
 
 // Execute - execute command
 func Execute() {
 	if err := rootCmd.Execute(); err != nil {
-		os.Exit(1)
+		os.Exit(exitCode(err))
+	}
+}
+
+// exitCode - returns the exit code of a command error, printing it unless the command logged it
+func exitCode(err error) int {
+	var e *exitError
+	if errors.As(err, &e) {
+		return e.code
 	}
+
+	fmt.Fprintln(os.Stderr, "Error:", err)
+
+	return 1
 }
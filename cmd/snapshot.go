@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"errors"
+
+	"github.com/spf13/cobra"
+	"github.com/vcraescu/dbsync/internal/compress"
+	"github.com/vcraescu/dbsync/internal/logger"
+	"github.com/vcraescu/dbsync/pkg/dbsync"
+)
+
+var snapshotCmd = &cobra.Command{
+	Use:   "snapshot [MASTER_NAME]",
+	Short: "Write schema, data and checksums of master server with name [MASTER_NAME] from config to a directory usable as sync master.",
+	Args:  cobra.ExactArgs(1),
+	PreRunE: func(_ *cobra.Command, args []string) error {
+		if err := loadMaster(args[0]); err != nil {
+			return err
+		}
+
+		if !config.ValidateMaster() {
+			return errors.New("invalid config")
+		}
+
+		if snapshotOut == "" {
+			return errors.New("--out is required")
+		}
+
+		return nil
+	},
+	Run: runSnapshotCmd,
+}
+
+var (
+	snapshotOut      string
+	snapshotCompress string
+)
+
+func init() {
+	snapshotCmd.Flags().StringVar(&snapshotOut, "out", "", "Snapshot directory")
+	snapshotCmd.Flags().StringVar(&snapshotCompress, "compress", "", "Compress the table files with gzip (default) or zstd")
+	snapshotCmd.Flags().Lookup("compress").NoOptDefVal = string(compress.Gzip)
+}
+
+func runSnapshotCmd(_ *cobra.Command, _ []string) {
+	ctx, stop := signalContext()
+	defer stop()
+
+	master, err := createMasterServer(ctx)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	logger.Info("Writing snapshot...", "file", snapshotOut)
+
+	n, err := dbsync.Snapshot(ctx, master, snapshotOut, snapshotCompress, config.Options(""))
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	logger.Info("Done!", "tables", n)
+}
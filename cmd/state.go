@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"path/filepath"
+	"time"
+
+	"github.com/vcraescu/dbsync/internal/logger"
+	"github.com/vcraescu/dbsync/internal/snapshot"
+	"github.com/vcraescu/dbsync/internal/state"
+	"github.com/vcraescu/dbsync/pkg/dbsync"
+)
+
+// stateStore - returns the store of the pair states, in the --state-dir directory
+func stateStore() (*dbsync.StateStore, error) {
+	dir := config.stateDir
+	if dir == "" {
+		var err error
+		if dir, err = dbsync.DefaultStateDir(); err != nil {
+			return nil, err
+		}
+	}
+
+	return dbsync.NewStateStore(dir), nil
+}
+
+// pairName - returns the name of a server in the state store: its config name, or the absolute
+// directory of a snapshot
+func pairName(arg string) string {
+	if !snapshot.IsSnapshot(arg) {
+		return arg
+	}
+
+	if dir, err := filepath.Abs(arg); err == nil {
+		return dir
+	}
+
+	return arg
+}
+
+// saveState - saves the state of a pair; a failure is only logged, the sync being done already
+func saveState(store *dbsync.StateStore, pair *dbsync.State) {
+	if err := store.Save(pair); err != nil {
+		logger.Warn("Can't save the sync state", "error", err)
+	}
+}
+
+// addFailedRun - records a sync which failed before syncing anything, e.g. while comparing
+func addFailedRun(pair *dbsync.State, start time.Time, err error) {
+	pair.AddRun(dbsync.Run{
+		StartedAt:  start.UTC(),
+		DurationMs: int64(time.Since(start) / time.Millisecond),
+		Error:      err.Error(),
+		User:       state.CurrentUser(),
+	})
+}
@@ -0,0 +1,190 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+	"github.com/vcraescu/dbsync/internal/database"
+	"github.com/vcraescu/dbsync/internal/logger"
+	"github.com/vcraescu/dbsync/pkg/dbsync"
+)
+
+var statusCmd = &cobra.Command{
+	Use:     "status [MASTER_NAME|SNAPSHOT_DIR] [SLAVE_NAME]",
+	Short:   "Show how stale slave server [SLAVE_NAME] is relative to master server [MASTER_NAME], or a snapshot: the last syncs and the tables changed since.",
+	Args:    cobra.ExactArgs(2),
+	PreRunE: loadSyncServers,
+	Run:     runStatusCmd,
+}
+
+var statusChecksum string
+
+func init() {
+	statusCmd.Flags().StringVar(
+		&statusChecksum,
+		"checksum",
+		string(database.ChecksumContent),
+		"Change detection strategy: content, native (CHECKSUM TABLE) or metadata (row count, data length and update time)",
+	)
+}
+
+func runStatusCmd(_ *cobra.Command, args []string) {
+	ctx, stop := signalContext()
+	defer stop()
+
+	store, err := stateStore()
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	pair, err := store.Load(pairName(args[0]), args[1])
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	master, slave, err := createSyncServers(ctx, args[0])
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	opts := config.Options(statusChecksum)
+	opts.State = pair
+
+	diff, err := dbsync.GenerateDiff(ctx, master, slave, opts)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+	defer diff.Close()
+
+	now := time.Now()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "Last sync:\t%s\n", formatRun(pair.LastRun(), now))
+	fmt.Fprintf(w, "Last successful sync:\t%s\n", formatRun(pair.LastSuccess(), now))
+	if diff.Empty() {
+		fmt.Fprintln(w, "Status:\tin sync")
+	} else {
+		fmt.Fprintf(
+			w,
+			"Status:\t%d tables and %d objects out of date\n",
+			len(diff.Create)+len(diff.Refresh)+len(diff.Delete),
+			len(diff.CreateObjects)+len(diff.DropObjects),
+		)
+		fmt.Fprintf(w, "Behind since:\t%s\n", behindSince(pair, diff, now))
+	}
+
+	if err := w.Flush(); err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	if diff.Empty() {
+		return
+	}
+
+	fmt.Println()
+	writeStaleTables(pair, diff, now)
+}
+
+// staleTable - table differing between master and slave
+type staleTable struct {
+	name   string
+	status dbsync.TableStatus
+}
+
+// staleTables - returns the tables differing between master and slave, by name
+func staleTables(diff *dbsync.Diff) []staleTable {
+	var tables []staleTable
+	for _, table := range append(append([]string{}, diff.Create...), diff.Refresh...) {
+		tables = append(tables, staleTable{name: table, status: diff.Status(table)})
+	}
+
+	for _, table := range diff.Delete {
+		tables = append(tables, staleTable{name: table, status: dbsync.TableDeleted})
+	}
+
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].name < tables[j].name
+	})
+
+	return tables
+}
+
+// writeStaleTables - writes the differing tables, with the last time each was known in sync
+// and the last time it was synced, followed by the differing objects
+func writeStaleTables(pair *dbsync.State, diff *dbsync.Diff, now time.Time) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "TABLE\tSTATUS\tIN SYNC AT\tSYNCED AT")
+	for _, t := range staleTables(diff) {
+		known := pair.Tables[t.name]
+		fmt.Fprintf(
+			w,
+			"%s\t%s\t%s\t%s\n",
+			t.name,
+			t.status,
+			formatAgo(known.CheckedAt, now),
+			formatAgo(known.SyncedAt, now),
+		)
+	}
+
+	if err := w.Flush(); err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	for _, o := range diff.DropObjects {
+		fmt.Printf("Drop %s\n", o)
+	}
+
+	for _, o := range diff.CreateObjects {
+		fmt.Printf("Create %s\n", o)
+	}
+}
+
+// behindSince - returns the earliest time a differing table was known in sync, or the last
+// successful sync when none of them is known
+func behindSince(pair *dbsync.State, diff *dbsync.Diff, now time.Time) string {
+	var since time.Time
+	for _, t := range staleTables(diff) {
+		checked := pair.Tables[t.name].CheckedAt
+		if !checked.IsZero() && (since.IsZero() || checked.Before(since)) {
+			since = checked
+		}
+	}
+
+	if since.IsZero() {
+		if run := pair.LastSuccess(); run != nil {
+			since = run.StartedAt
+		}
+	}
+
+	if since.IsZero() {
+		return "unknown, never synced"
+	}
+
+	return formatAgo(since, now)
+}
+
+// formatRun - formats the start, user and result of a run
+func formatRun(run *dbsync.Run, now time.Time) string {
+	if run == nil {
+		return "never"
+	}
+
+	s := formatAgo(run.StartedAt, now)
+	if run.User != "" {
+		s += " by " + run.User
+	}
+
+	return s + ", " + formatResult(*run)
+}
+
+// formatAgo - formats a time followed by how long ago it was, "-" for the zero time
+func formatAgo(t, now time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+
+	return fmt.Sprintf("%s (%s ago)", formatTime(t), now.Sub(t).Round(time.Second))
+}
@@ -1,82 +1,248 @@
 package cmd
 
 import (
-	"log"
-	"fmt"
-	"strings"
+	"context"
+	"errors"
+	"os"
+	"time"
 
 	"github.com/spf13/cobra"
-	"github.com/vcraescu/dbsync/internal/database/mysql"
+	"github.com/vcraescu/dbsync/internal/database"
+	"github.com/vcraescu/dbsync/internal/logger"
+	"github.com/vcraescu/dbsync/internal/progress"
+	"github.com/vcraescu/dbsync/internal/snapshot"
+	"github.com/vcraescu/dbsync/pkg/dbsync"
 )
 
 var syncCmd = &cobra.Command{
-	Use:   "sync [MASTER_NAME] [SLAVE_NAME]",
-	Short: "Sync master server with name [MASTER_NAME] from config to slave server with name [SLAVE_NAME] from config.",
-	Args:  cobra.ExactArgs(2),
-	Run:   runSyncCmd,
+	Use:     "sync [MASTER_NAME|SNAPSHOT_DIR] [SLAVE_NAME]",
+	Short:   "Sync master server with name [MASTER_NAME] from config, or a snapshot, to slave server with name [SLAVE_NAME] from config.",
+	Args:    cobra.ExactArgs(2),
+	PreRunE: loadSyncServers,
+	RunE:    runSyncCmd,
 }
 
-func runSyncCmd(_ *cobra.Command, _ []string) {
-	masterCfg := config.CreateMasterConnectionConfig()
-	slaveCfg := config.CreateSlaveConnectionConfig()
+var (
+	syncWorkers  int
+	syncChecksum string
+	syncSubset   bool
+	syncOutput   string
+	syncFull     bool
+	syncProgress bool
+	syncCount    bool
+)
 
-	if config.MasterSSHTunnelIsRequired() {
-		masterTunn, err := startSSHTunnel(masterCfg, config.Master.SSHConfig)
-		if err != nil {
-			log.Fatal(err)
-		}
+func init() {
+	syncCmd.Flags().IntVar(&syncWorkers, "workers", 4, "Number of tables dumped and imported in parallel")
+	syncCmd.Flags().StringVar(
+		&syncChecksum,
+		"checksum",
+		string(database.ChecksumContent),
+		"Change detection strategy: content, native (CHECKSUM TABLE) or metadata (row count, data length and update time)",
+	)
+	syncCmd.Flags().BoolVar(
+		&syncSubset,
+		"subset",
+		false,
+		"Replace slave with the referentially intact subset of master selected by the subset config",
+	)
+	syncCmd.Flags().StringVar(&syncOutput, "output", outputText, "Output format: text or json, written to stdout")
+	syncCmd.Flags().BoolVar(
+		&syncCount,
+		"count-rows",
+		false,
+		"Report exact row counts with --output json, reading every table, instead of the estimates of the server statistics",
+	)
+	syncCmd.Flags().BoolVar(&syncFull, "full", false, "Compare every table, ignoring the tables known to be in sync")
+	syncCmd.Flags().BoolVar(
+		&syncProgress,
+		"progress",
+		true,
+		"Show the progress on stderr, as bars on a terminal or as a line every 10 seconds otherwise",
+	)
+}
+
+func runSyncCmd(cmd *cobra.Command, args []string) error {
+	ctx, stop := signalContext()
+	defer stop()
 
-		log.Printf("SSH Tunnel for master started at %s:%d\n", masterTunn.LocalHost(), masterTunn.LocalPort())
+	out := cmd.OutOrStdout()
+
+	if err := validateOutput(syncOutput); err != nil {
+		return failure(1, out, syncOutput, nil, err)
 	}
 
-	if config.SlaveSSHTunnelIsRequired() {
-		slaveTunn, err := startSSHTunnel(slaveCfg, config.Slave.SSHConfig)
-		if err != nil {
-			log.Fatal(err)
-		}
+	notifiers, err := createNotifiers()
+	if err != nil {
+		return failure(1, out, syncOutput, nil, err)
+	}
 
-		log.Printf("SSH Tunnel for slave started at %s:%d\n", slaveTunn.LocalHost(), slaveTunn.LocalPort())
+	start := time.Now()
+	// failed - notifies the failure and returns it to exit with code 1 once the deferred calls ran
+	failed := func(r *dbsync.Report, res *dbsync.Result, err error) error {
+		sendNotification(notifiers, syncSummary(args, start, nil, res, nil, err))
+
+		return failure(1, out, syncOutput, r, err)
 	}
 
-	masterConn := mysql.New(*masterCfg)
-	slaveConn := mysql.New(*slaveCfg)
+	master, slave, err := createSyncServers(ctx, args[0])
+	if err != nil {
+		return failed(nil, nil, err)
+	}
 
-	log.Println("Computing differences between master and slave...")
-	diff, err := mysql.GenerateDiff(masterConn, slaveConn)
+	store, err := stateStore()
 	if err != nil {
-		log.Fatal(err)
+		return failed(nil, nil, err)
 	}
 
-	if diff.Empty() {
-		log.Println("Nothing to sync. Exit")
-		return
+	pair, err := store.Load(pairName(args[0]), args[1])
+	if err != nil {
+		return failed(nil, nil, err)
 	}
 
-	if len(diff.Create) > 0 {
-		log.Println(fmt.Sprintf("Create tables: %s", strings.Join(diff.Create, ", ")))
+	if syncFull {
+		pair.Tables = make(map[string]dbsync.StateTable)
 	}
 
-	if len(diff.Delete) > 0 {
-		log.Println(fmt.Sprintf("Delete tables: %s", strings.Join(diff.Create, ", ")))
+	opts := config.Options(syncChecksum)
+	opts.Workers = syncWorkers
+	opts.CountRows = syncCount
+	opts.State = pair
+	if syncSubset {
+		if len(config.Subset) == 0 {
+			return failed(nil, nil, errors.New("subset config is empty"))
+		}
+
+		opts.Subset = config.Subset
+		logger.Info("Computing subset of master...")
+	}
+
+	if master.Snapshot != "" {
+		logger.Info("Computing differences between snapshot and slave...", "snapshot", master.Snapshot)
+	} else {
+		logger.Info("Computing differences between master and slave...")
+	}
+
+	var p *dbsync.Progress
+	var display *progress.Display
+	if syncProgress {
+		p = dbsync.NewProgress()
+		ctx = dbsync.WithProgress(ctx, p)
+		display = newProgressDisplay(p)
 	}
 
-	dumper := mysql.NewDumper(*masterCfg)
+	diffStart := time.Now()
+	display.Start()
+	diff, err := dbsync.GenerateDiff(ctx, master, slave, opts)
+	display.Stop()
 	if err != nil {
-		log.Fatal(err)
+		addFailedRun(pair, diffStart, err)
+		saveState(store, pair)
+		return failed(nil, nil, err)
+	}
+	defer diff.Close()
+
+	if len(diff.Skipped) > 0 {
+		logger.Info("Skipped tables unchanged since the last sync", "tables", len(diff.Skipped))
+	}
+
+	var report *dbsync.Report
+	if syncOutput == outputJSON {
+		if report, err = diff.Report(ctx); err != nil {
+			return failed(nil, nil, err)
+		}
+
+		setReportServers(report, master.Snapshot)
+	}
+
+	if diff.Empty() {
+		if err := diff.Record(ctx); err != nil {
+			return failed(nil, nil, err)
+		}
+		saveState(store, pair)
+
+		logger.Info("Nothing to sync. Exit")
+		sendNotification(notifiers, syncSummary(args, start, diff, nil, p, nil))
+		if report != nil {
+			return writeReport(out, report)
+		}
+
+		return nil
+	}
+
+	logDiff(diff)
+
+	logger.Info("Syncing...")
+
+	display.Start()
+	res, err := diff.Sync(ctx)
+	display.Stop()
+	saveState(store, pair)
+	if report != nil {
+		report.AddResult(res, err)
 	}
 
-	dump, err := diff.GenerateSQL(dumper)
 	if err != nil {
-		log.Fatal(err)
+		return failed(report, res, err)
+	}
+
+	logger.Info("Done!")
+	sendNotification(notifiers, syncSummary(args, start, diff, res, p, nil))
+	if report != nil {
+		return writeReport(out, report)
+	}
+
+	return nil
+}
+
+// createSyncServers - returns the master server, or snapshot, and the slave server
+func createSyncServers(ctx context.Context, masterArg string) (dbsync.Server, dbsync.Server, error) {
+	var master dbsync.Server
+	if snapshot.IsSnapshot(masterArg) {
+		if syncSubset {
+			return master, dbsync.Server{}, errors.New("subset cannot be used with snapshots")
+		}
+
+		master.Snapshot = masterArg
+	} else {
+		var err error
+		if master, err = createMasterServer(ctx); err != nil {
+			return master, dbsync.Server{}, err
+		}
 	}
 
-	log.Println("Syncing...")
+	slave, err := createSlaveServer(ctx)
+
+	return master, slave, err
+}
+
+func logDiff(diff *dbsync.Diff) {
+	if len(diff.Create) > 0 {
+		logger.Info("Create tables", "tables", diff.Create)
+	}
 
-	imp := mysql.NewImporter(*slaveCfg)
-	if err = imp.Import(dump); err != nil {
-		log.Fatal(err)
+	if len(diff.Refresh) > 0 {
+		logger.Info("Refresh tables", "tables", diff.Refresh)
 	}
 
-	log.Println("Done!")
+	if len(diff.Delete) > 0 {
+		logger.Info("Delete tables", "tables", diff.Delete)
+	}
+
+	for _, o := range diff.DropObjects {
+		logger.Info("Drop object", "object", o)
+	}
+
+	for _, o := range diff.CreateObjects {
+		logger.Info("Create object", "object", o)
+	}
 }
 
+// newProgressDisplay - draws the progress on stderr, or logs it when the logs are json lines
+func newProgressDisplay(p *dbsync.Progress) *progress.Display {
+	if logger.Default().Format() == logger.JSONFormat {
+		return progress.NewPlain(logger.Default().Writer(logger.InfoLevel), p)
+	}
+
+	return progress.New(os.Stderr, p)
+}
@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"encoding/json"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vcraescu/dbsync/pkg/dbsync"
+)
+
+func TestSyncFailure(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+
+	dir := t.TempDir()
+	cfgFile := sqliteConfig(t, dir, map[string]string{
+		"master": filepath.Join(dir, "missing", "master.db"),
+		"slave":  sqliteFile(t, dir, "slave", "create table users (id integer primary key);\n"),
+	})
+
+	// the failure is returned rather than exiting, so the deferred cleanup runs
+	out, code := execute(
+		"sync", "master", "slave",
+		"--config", cfgFile,
+		"--state-dir", filepath.Join(dir, "state"),
+		"--output", outputJSON,
+		"--progress=false",
+	)
+	if code != 1 {
+		t.Errorf("exit code = %d, want 1", code)
+	}
+
+	var r dbsync.Report
+	if err := json.Unmarshal([]byte(out), &r); err != nil {
+		t.Fatalf("report %q: %s", out, err)
+	}
+
+	if r.Synced || !strings.Contains(r.Error, "master.db") {
+		t.Errorf("report = %+v, want the master error", r)
+	}
+}
@@ -1,16 +1,211 @@
 package cmd
 
 import (
+	"context"
+	"time"
+
 	"github.com/spf13/cobra"
-	"fmt"
+	"github.com/vcraescu/dbsync/internal/database"
+	"github.com/vcraescu/dbsync/internal/logger"
+	"github.com/vcraescu/dbsync/internal/metrics"
+	"github.com/vcraescu/dbsync/internal/notify"
+	"github.com/vcraescu/dbsync/internal/watcher"
+	"github.com/vcraescu/dbsync/pkg/dbsync"
 )
 
-
 var watchCmd = &cobra.Command{
-	Use:     "watch",
-	Short:   "Watch master for changes and sync to slave. NOT IMPLEMENTED YET!",
-	Run: func(cmd *cobra.Command, args []string) {
-		fmt.Println("Watch")
-	},
+	Use:     "watch [MASTER_NAME] [SLAVE_NAME]",
+	Short:   "Watch master server [MASTER_NAME] for changes and sync them to slave server [SLAVE_NAME].",
+	Args:    cobra.ExactArgs(2),
+	PreRunE: loadServers,
+	Run:     runWatchCmd,
 }
 
+var (
+	watchInterval    time.Duration
+	watchWorkers     int
+	watchChecksum    string
+	watchMetricsAddr string
+)
+
+func init() {
+	watchCmd.Flags().DurationVar(&watchInterval, "interval", time.Minute, "Time between the checks of master")
+	watchCmd.Flags().IntVar(&watchWorkers, "workers", 4, "Number of tables dumped and imported in parallel")
+	watchCmd.Flags().StringVar(
+		&watchChecksum,
+		"checksum",
+		string(database.ChecksumContent),
+		"Change detection strategy of the syncs: content, native (CHECKSUM TABLE) or metadata (row count, data length and update time)",
+	)
+	watchCmd.Flags().StringVar(
+		&watchMetricsAddr,
+		"metrics-addr",
+		"",
+		"Address serving the prometheus metrics on /metrics and the health checks on /healthz, e.g. :9090",
+	)
+}
+
+func runWatchCmd(_ *cobra.Command, args []string) {
+	ctx, stop := signalContext()
+	defer stop()
+
+	store, err := stateStore()
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	notifiers, err := createNotifiers()
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	masterCfg := config.CreateMasterConnectionConfig()
+	if err := startMasterSSHTunnel(ctx, masterCfg); err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	engine, err := database.Lookup(masterCfg.Driver)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	master := createServer(masterCfg)
+	slave, err := createSlaveServer(ctx)
+	if err != nil {
+		logger.Fatal(err.Error())
+	}
+
+	m := newWatchMetrics()
+	if watchMetricsAddr != "" {
+		checks := map[string]metrics.Check{
+			"master": master.Ping,
+			"slave":  slave.Ping,
+		}
+		for name, t := range sshTunnels {
+			checks[name+"_tunnel"] = t.Check
+		}
+
+		if err := metrics.NewServer(watchMetricsAddr, m.registry, checks).Start(ctx); err != nil {
+			logger.Fatal(err.Error())
+		}
+
+		logger.Info("Serving metrics", "addr", watchMetricsAddr)
+	}
+
+	w := watcher.New(engine.Connection(*masterCfg), watchInterval)
+	watchErr := make(chan error, 1)
+	go func() {
+		watchErr <- w.Start(ctx)
+	}()
+
+	logger.Info("Watching master", "interval", watchInterval)
+
+	// syncs what differs already, the watcher only reporting the changes made from now on
+	watchSync(ctx, store, args, master, slave, m, notifiers)
+
+	for {
+		select {
+		case err := <-watchErr:
+			if ctx.Err() != nil {
+				logger.Info("Stopped")
+				return
+			}
+
+			logger.Fatal(err.Error())
+		case err := <-w.ErrCh:
+			if ctx.Err() == nil {
+				logger.Error("Checking master failed", "error", err)
+				m.errors.Inc(phaseWatch)
+			}
+		case diff := <-w.DiffCh:
+			m.changeDetected(time.Now())
+			logWatchDiff(diff)
+			sendNotification(notifiers, diffSummary(args, diff))
+			drainDiffs(w)
+			watchSync(ctx, store, args, master, slave, m, notifiers)
+		}
+	}
+}
+
+// drainDiffs - drops the changes detected while syncing, the next sync comparing every table anyway
+func drainDiffs(w *watcher.Watcher) {
+	for {
+		select {
+		case <-w.DiffCh:
+		default:
+			return
+		}
+	}
+}
+
+// watchSync - syncs the differences between master and slave, recording the run in the state
+func watchSync(
+	ctx context.Context,
+	store *dbsync.StateStore,
+	args []string,
+	master, slave dbsync.Server,
+	m *watchMetrics,
+	notifiers []*notify.Notifier,
+) {
+	start := time.Now()
+	pair, err := store.Load(args[0], args[1])
+	if err != nil {
+		logger.Error(err.Error())
+		m.errors.Inc(phaseSync)
+		sendNotification(notifiers, syncSummary(args, start, nil, nil, nil, err))
+		return
+	}
+
+	opts := config.Options(watchChecksum)
+	opts.Workers = watchWorkers
+	opts.State = pair
+
+	p := dbsync.NewProgress()
+	ctx = dbsync.WithProgress(ctx, p)
+
+	diff, err := dbsync.GenerateDiff(ctx, master, slave, opts)
+	m.checksumDuration.Observe(time.Since(start).Seconds())
+	if err != nil {
+		addFailedRun(pair, start, err)
+		saveState(store, pair)
+		logger.Error(err.Error())
+		m.syncs.Inc("failure")
+		m.errors.Inc(phaseChecksum)
+		sendNotification(notifiers, syncSummary(args, start, nil, nil, p, err))
+		return
+	}
+	defer diff.Close()
+
+	if !diff.Empty() {
+		logDiff(diff)
+		logger.Info("Syncing...")
+	}
+
+	res, err := diff.Sync(ctx)
+	saveState(store, pair)
+
+	_, rows, _ := p.Snapshot().Totals()
+	m.synced(start, res, rows, err)
+	sendNotification(notifiers, syncSummary(args, start, diff, res, p, err))
+	if err != nil {
+		logger.Error(err.Error())
+		return
+	}
+
+	logger.Info("Done!")
+}
+
+func logWatchDiff(diff watcher.Diff) {
+	for _, change := range []struct {
+		name   string
+		tables []string
+	}{
+		{"created", diff.Created},
+		{"updated", diff.Updated},
+		{"deleted", diff.Deleted},
+	} {
+		if len(change.tables) > 0 {
+			logger.Info("Master tables "+change.name, "tables", change.tables)
+		}
+	}
+}
@@ -5,6 +5,8 @@ require (
 	github.com/go-sql-driver/mysql v1.4.0
 	github.com/hashicorp/hcl v0.0.0-20180404174102-ef8a98b0bbce
 	github.com/inconshreveable/mousetrap v1.0.0
+	github.com/klauspost/compress v1.11.13
+	github.com/lib/pq v1.10.0
 	github.com/magiconair/properties v1.8.0
 	github.com/mitchellh/go-homedir v0.0.0-20180523094522-3864e76763d9
 	github.com/mitchellh/mapstructure v0.0.0-20180511142126-bb74f1db0675
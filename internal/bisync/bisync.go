@@ -0,0 +1,190 @@
+package bisync
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Change - how a table changed since the last two-way sync
+type Change string
+
+const (
+	// Unchanged - same content on both sides, or changed the same way on both
+	Unchanged Change = "unchanged"
+	// ChangedLeft - created, updated or deleted on the left side only
+	ChangedLeft Change = "changed-on-left"
+	// ChangedRight - created, updated or deleted on the right side only
+	ChangedRight Change = "changed-on-right"
+	// Conflict - changed differently on both sides
+	Conflict Change = "conflict"
+)
+
+// Policy - how conflicting tables are resolved
+type Policy string
+
+const (
+	// Fail - nothing is synced when a table conflicts
+	Fail Policy = "fail"
+	// LeftWins - conflicting tables are copied from left to right
+	LeftWins Policy = "left"
+	// RightWins - conflicting tables are copied from right to left
+	RightWins Policy = "right"
+)
+
+// ParsePolicy - returns the conflict policy of the given name, fail when empty
+func ParsePolicy(name string) (Policy, error) {
+	switch Policy(name) {
+	case "":
+		return Fail, nil
+	case Fail, LeftWins, RightWins:
+		return Policy(name), nil
+	}
+
+	return "", fmt.Errorf("unknown conflict policy %s, expected %s, %s or %s", name, Fail, LeftWins, RightWins)
+}
+
+// Base - content checksums of the tables of both sides after the last two-way sync.
+// A table missing from a side did not exist there.
+type Base struct {
+	SyncedAt time.Time         `json:"synced_at"`
+	Left     map[string]string `json:"left"`
+	Right    map[string]string `json:"right"`
+}
+
+// Table - classification of a table of either side
+type Table struct {
+	Name   string `json:"name"`
+	Change Change `json:"change"`
+	// InLeft, InRight - true when the table currently exists on the side
+	InLeft  bool `json:"in_left"`
+	InRight bool `json:"in_right"`
+}
+
+// Classify - compares the current checksums of both sides with the base ones. Without a base,
+// tables existing on a single side are changed on that side and tables with different content
+// on both sides conflict.
+func Classify(base *Base, left, right map[string]string) []Table {
+	if base == nil {
+		base = &Base{}
+	}
+
+	names := make(map[string]bool)
+	for _, chks := range []map[string]string{left, right, base.Left, base.Right} {
+		for name := range chks {
+			names[name] = true
+		}
+	}
+
+	tables := make([]Table, 0, len(names))
+	for name := range names {
+		l, inLeft := left[name]
+		r, inRight := right[name]
+		t := Table{Name: name, InLeft: inLeft, InRight: inRight}
+
+		leftChanged := changed(base.Left, name, l, inLeft)
+		rightChanged := changed(base.Right, name, r, inRight)
+		switch {
+		case leftChanged && rightChanged:
+			t.Change = Conflict
+			if inLeft == inRight && l == r {
+				t.Change = Unchanged
+			}
+		case leftChanged:
+			t.Change = ChangedLeft
+		case rightChanged:
+			t.Change = ChangedRight
+		default:
+			t.Change = Unchanged
+		}
+
+		tables = append(tables, t)
+	}
+
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+
+	return tables
+}
+
+// changed - returns true when the table was created, updated or deleted since the base
+func changed(base map[string]string, name, checksum string, exists bool) bool {
+	baseChecksum, inBase := base[name]
+
+	return exists != inBase || checksum != baseChecksum
+}
+
+// Resolve - returns the tables to copy from left to right and from right to left, conflicting
+// tables going the way of the policy. Returns an error listing the conflicts with the fail policy.
+func Resolve(tables []Table, policy Policy) ([]Table, []Table, error) {
+	var toRight, toLeft, conflicts []Table
+	for _, t := range tables {
+		switch t.Change {
+		case ChangedLeft:
+			toRight = append(toRight, t)
+		case ChangedRight:
+			toLeft = append(toLeft, t)
+		case Conflict:
+			conflicts = append(conflicts, t)
+		}
+	}
+
+	if len(conflicts) == 0 {
+		return toRight, toLeft, nil
+	}
+
+	switch policy {
+	case LeftWins:
+		toRight = append(toRight, conflicts...)
+	case RightWins:
+		toLeft = append(toLeft, conflicts...)
+	default:
+		names := make([]string, 0, len(conflicts))
+		for _, t := range conflicts {
+			names = append(names, t.Name)
+		}
+
+		return nil, nil, fmt.Errorf("tables changed on both sides: %s", strings.Join(names, ", "))
+	}
+
+	return toRight, toLeft, nil
+}
+
+// ReadBase - reads the base from file, nil when the file does not exist
+func ReadBase(file string) (*Base, error) {
+	data, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	var base Base
+	if err := json.Unmarshal(data, &base); err != nil {
+		return nil, fmt.Errorf("base %s: %s", file, err)
+	}
+
+	return &base, nil
+}
+
+// WriteFile - writes the base to file, creating its directory
+func (b *Base) WriteFile(file string) error {
+	if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(file, append(data, '\n'), 0644)
+}
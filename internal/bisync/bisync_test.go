@@ -0,0 +1,147 @@
+package bisync
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClassify(t *testing.T) {
+	base := &Base{
+		Left:  map[string]string{"same": "a", "left": "a", "right": "a", "both": "a", "converged": "a", "deleted": "a", "gone": "a"},
+		Right: map[string]string{"same": "a", "left": "a", "right": "a", "both": "a", "converged": "a", "deleted": "a", "gone": "a"},
+	}
+	left := map[string]string{"same": "a", "left": "b", "right": "a", "both": "b", "converged": "b", "new": "a"}
+	right := map[string]string{"same": "a", "left": "a", "right": "b", "both": "c", "converged": "b", "deleted": "a"}
+
+	want := []Table{
+		{Name: "both", Change: Conflict, InLeft: true, InRight: true},
+		{Name: "converged", Change: Unchanged, InLeft: true, InRight: true},
+		{Name: "deleted", Change: ChangedLeft, InRight: true},
+		{Name: "gone", Change: Unchanged},
+		{Name: "left", Change: ChangedLeft, InLeft: true, InRight: true},
+		{Name: "new", Change: ChangedLeft, InLeft: true},
+		{Name: "right", Change: ChangedRight, InLeft: true, InRight: true},
+		{Name: "same", Change: Unchanged, InLeft: true, InRight: true},
+	}
+
+	if got := Classify(base, left, right); !reflect.DeepEqual(got, want) {
+		t.Errorf("Classify() = %+v, want %+v", got, want)
+	}
+}
+
+func TestClassifyWithoutBase(t *testing.T) {
+	left := map[string]string{"same": "a", "left": "a", "differ": "a"}
+	right := map[string]string{"same": "a", "right": "a", "differ": "b"}
+
+	want := []Table{
+		{Name: "differ", Change: Conflict, InLeft: true, InRight: true},
+		{Name: "left", Change: ChangedLeft, InLeft: true},
+		{Name: "right", Change: ChangedRight, InRight: true},
+		{Name: "same", Change: Unchanged, InLeft: true, InRight: true},
+	}
+
+	if got := Classify(nil, left, right); !reflect.DeepEqual(got, want) {
+		t.Errorf("Classify() = %+v, want %+v", got, want)
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tables := []Table{
+		{Name: "a", Change: ChangedLeft},
+		{Name: "b", Change: ChangedRight},
+		{Name: "c", Change: Conflict},
+		{Name: "d", Change: Unchanged},
+		{Name: "e", Change: Conflict},
+	}
+
+	tests := []struct {
+		policy  Policy
+		toRight []string
+		toLeft  []string
+		wantErr string
+	}{
+		{policy: LeftWins, toRight: []string{"a", "c", "e"}, toLeft: []string{"b"}},
+		{policy: RightWins, toRight: []string{"a"}, toLeft: []string{"b", "c", "e"}},
+		{policy: Fail, wantErr: "tables changed on both sides: c, e"},
+	}
+
+	for _, tt := range tests {
+		t.Run(string(tt.policy), func(t *testing.T) {
+			toRight, toLeft, err := Resolve(tables, tt.policy)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("Resolve() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("Resolve() error = %s", err)
+			}
+
+			if got := names(toRight); !reflect.DeepEqual(got, tt.toRight) {
+				t.Errorf("Resolve() to right = %q, want %q", got, tt.toRight)
+			}
+
+			if got := names(toLeft); !reflect.DeepEqual(got, tt.toLeft) {
+				t.Errorf("Resolve() to left = %q, want %q", got, tt.toLeft)
+			}
+		})
+	}
+}
+
+func TestResolveWithoutConflicts(t *testing.T) {
+	toRight, toLeft, err := Resolve([]Table{{Name: "a", Change: ChangedLeft}}, Fail)
+	if err != nil || len(toRight) != 1 || len(toLeft) != 0 {
+		t.Errorf("Resolve() = %+v, %+v, %v", toRight, toLeft, err)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	for name, want := range map[string]Policy{"": Fail, "fail": Fail, "left": LeftWins, "right": RightWins} {
+		if got, err := ParsePolicy(name); err != nil || got != want {
+			t.Errorf("ParsePolicy(%q) = %q, %v, want %q", name, got, err, want)
+		}
+	}
+
+	if _, err := ParsePolicy("newest"); err == nil {
+		t.Errorf("ParsePolicy() of an unknown policy did not fail")
+	}
+}
+
+func TestBaseWriteRead(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "bisync", "base.json")
+	if base, err := ReadBase(file); base != nil || err != nil {
+		t.Fatalf("ReadBase() of a missing file = %+v, %v", base, err)
+	}
+
+	base := &Base{
+		SyncedAt: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC),
+		Left:     map[string]string{"users": "a"},
+		Right:    map[string]string{"users": "a", "logs": "b"},
+	}
+	if err := base.WriteFile(file); err != nil {
+		t.Fatalf("WriteFile() error = %s", err)
+	}
+
+	got, err := ReadBase(file)
+	if err != nil {
+		t.Fatalf("ReadBase() error = %s", err)
+	}
+
+	if !reflect.DeepEqual(got, base) {
+		t.Errorf("ReadBase() = %+v, want %+v", got, base)
+	}
+}
+
+func names(tables []Table) []string {
+	var names []string
+	for _, t := range tables {
+		names = append(names, t.Name)
+	}
+
+	return names
+}
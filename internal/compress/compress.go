@@ -0,0 +1,119 @@
+package compress
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/ioutil"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// Algorithm - compression algorithm
+type Algorithm string
+
+// Compression algorithms
+const (
+	None Algorithm = ""
+	Gzip Algorithm = "gzip"
+	Zstd Algorithm = "zstd"
+)
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ParseAlgorithm - parses compression algorithm name
+func ParseAlgorithm(name string) (Algorithm, error) {
+	switch a := Algorithm(name); a {
+	case None, Gzip, Zstd:
+		return a, nil
+	case "none":
+		return None, nil
+	}
+
+	return None, fmt.Errorf("unknown compression %q", name)
+}
+
+// Extension - returns the file extension of the algorithm
+func (a Algorithm) Extension() string {
+	switch a {
+	case Gzip:
+		return ".gz"
+	case Zstd:
+		return ".zst"
+	}
+
+	return ""
+}
+
+// Detect - detects the compression algorithm by the magic bytes at the start of data
+func Detect(data []byte) Algorithm {
+	switch {
+	case bytes.HasPrefix(data, gzipMagic):
+		return Gzip
+	case bytes.HasPrefix(data, zstdMagic):
+		return Zstd
+	}
+
+	return None
+}
+
+// NewWriter - returns a writer compressing to w; closing it does not close w
+func NewWriter(w io.Writer, a Algorithm) (io.WriteCloser, error) {
+	switch a {
+	case None:
+		return nopWriteCloser{w}, nil
+	case Gzip:
+		return gzip.NewWriter(w), nil
+	case Zstd:
+		return zstd.NewWriter(w)
+	}
+
+	return nil, fmt.Errorf("unknown compression %q", a)
+}
+
+// NewReader - returns a reader decompressing r, detecting the compression by its magic bytes.
+// Uncompressed data is read as is.
+func NewReader(r io.Reader) (io.ReadCloser, error) {
+	br := bufio.NewReader(r)
+	magic, err := br.Peek(len(zstdMagic))
+	if err != nil && err != io.EOF {
+		return nil, err
+	}
+
+	switch Detect(magic) {
+	case Gzip:
+		return gzip.NewReader(br)
+	case Zstd:
+		zr, err := zstd.NewReader(br)
+		if err != nil {
+			return nil, err
+		}
+
+		return zstdReadCloser{zr}, nil
+	}
+
+	return ioutil.NopCloser(br), nil
+}
+
+type nopWriteCloser struct {
+	io.Writer
+}
+
+func (nopWriteCloser) Close() error {
+	return nil
+}
+
+type zstdReadCloser struct {
+	*zstd.Decoder
+}
+
+func (zr zstdReadCloser) Close() error {
+	zr.Decoder.Close()
+
+	return nil
+}
@@ -0,0 +1,120 @@
+package compress
+
+import (
+	"bytes"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func compressed(t *testing.T, a Algorithm, data string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	w, err := NewWriter(&buf, a)
+	if err != nil {
+		t.Fatalf("NewWriter(%q) error = %s", a, err)
+	}
+
+	if _, err := w.Write([]byte(data)); err != nil {
+		t.Fatalf("Write(%q) error = %s", a, err)
+	}
+
+	if err := w.Close(); err != nil {
+		t.Fatalf("Close(%q) error = %s", a, err)
+	}
+
+	return buf.Bytes()
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name string
+		data []byte
+		want Algorithm
+	}{
+		{"empty", nil, None},
+		{"sql", []byte("-- MySQL dump"), None},
+		{"json", []byte(`{"format": "dbsync-plan"}`), None},
+		{"gzip magic only", []byte{0x1f, 0x8b}, Gzip},
+		{"truncated zstd magic", []byte{0x28, 0xb5, 0x2f}, None},
+		{"gzip", compressed(t, Gzip, "data"), Gzip},
+		{"zstd", compressed(t, Zstd, "data"), Zstd},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Detect(tt.data); got != tt.want {
+				t.Errorf("Detect() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	data := strings.Repeat("INSERT INTO `t` VALUES (1);\n", 100)
+	inputs := map[string][]byte{
+		"empty":        nil,
+		"short":        []byte("ab"),
+		"uncompressed": []byte(data),
+		"gzip":         compressed(t, Gzip, data),
+		"zstd":         compressed(t, Zstd, data),
+		"gzip empty":   compressed(t, Gzip, ""),
+	}
+	wants := map[string]string{
+		"empty":        "",
+		"short":        "ab",
+		"uncompressed": data,
+		"gzip":         data,
+		"zstd":         data,
+		"gzip empty":   "",
+	}
+
+	for name, input := range inputs {
+		t.Run(name, func(t *testing.T) {
+			r, err := NewReader(bytes.NewReader(input))
+			if err != nil {
+				t.Fatalf("NewReader() error = %s", err)
+			}
+			defer r.Close()
+
+			got, err := ioutil.ReadAll(r)
+			if err != nil {
+				t.Fatalf("ReadAll() error = %s", err)
+			}
+
+			if string(got) != wants[name] {
+				t.Errorf("read %d bytes, want %d", len(got), len(wants[name]))
+			}
+		})
+	}
+}
+
+func TestParseAlgorithm(t *testing.T) {
+	tests := []struct {
+		name    string
+		want    Algorithm
+		wantErr bool
+	}{
+		{"", None, false},
+		{"none", None, false},
+		{"gzip", Gzip, false},
+		{"zstd", Zstd, false},
+		{"bzip2", None, true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseAlgorithm(tt.name)
+		if got != tt.want || (err != nil) != tt.wantErr {
+			t.Errorf("ParseAlgorithm(%q) = %q, %v", tt.name, got, err)
+		}
+	}
+}
+
+func TestExtension(t *testing.T) {
+	for a, want := range map[Algorithm]string{None: "", Gzip: ".gz", Zstd: ".zst"} {
+		if got := a.Extension(); got != want {
+			t.Errorf("%q.Extension() = %q, want %q", a, got, want)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// ChecksumStrategy - how tables are compared between master and slave
+type ChecksumStrategy string
+
+// Checksum strategies
+const (
+	// ChecksumContent - compare the md5 of the content of every table
+	ChecksumContent ChecksumStrategy = "content"
+	// ChecksumNative - compare CHECKSUM TABLE results first and fall back to content
+	// checksums for tables whose native checksums differ
+	ChecksumNative ChecksumStrategy = "native"
+	// ChecksumMetadata - compare row counts, data length and update times from information_schema
+	// first and fall back to content checksums when they differ. Tables of equal size are
+	// considered unchanged only when slave was last written after the last update of master,
+	// which requires the clocks of both servers to agree.
+	ChecksumMetadata ChecksumStrategy = "metadata"
+)
+
+// ParseChecksumStrategy - parses checksum strategy name
+func ParseChecksumStrategy(name string) (ChecksumStrategy, error) {
+	switch s := ChecksumStrategy(name); s {
+	case ChecksumContent, ChecksumNative, ChecksumMetadata:
+		return s, nil
+	case "":
+		return ChecksumContent, nil
+	}
+
+	return "", fmt.Errorf("unknown checksum strategy %q", name)
+}
+
+// MetadataChecksum - returns the metadata checksum of a table from its row count, data length
+// and last update time
+func MetadataChecksum(rows, length int64, updated time.Time) string {
+	return fmt.Sprintf("%d:%d:%d", rows, length, updated.Unix())
+}
+
+// quickUnchanged - tells whether the quick checksums of master and slave prove a table unchanged.
+// Metadata checksums never match, as slave is written after master: their sizes must be equal
+// and master must not have been updated since, in the same second included, slave was written.
+// Otherwise, like an in-place update of master, the content of the table has to be compared.
+func quickUnchanged(strategy ChecksumStrategy, master, slave string) bool {
+	if strategy != ChecksumMetadata {
+		return master == slave
+	}
+
+	var masterRows, masterLength, masterUpdated int64
+	if _, err := fmt.Sscanf(master, "%d:%d:%d", &masterRows, &masterLength, &masterUpdated); err != nil {
+		return false
+	}
+
+	var slaveRows, slaveLength, slaveUpdated int64
+	if _, err := fmt.Sscanf(slave, "%d:%d:%d", &slaveRows, &slaveLength, &slaveUpdated); err != nil {
+		return false
+	}
+
+	return masterRows == slaveRows && masterLength == slaveLength && masterUpdated < slaveUpdated
+}
+
+// ParallelChecksums - returns the checksums of the given tables, computed by checksum on at most
+// workers tables at a time. The first error cancels the checksums still running.
+func ParallelChecksums(
+	ctx context.Context,
+	workers int,
+	checksum func(ctx context.Context, table string) (string, error),
+	names ...string,
+) (map[string]string, error) {
+	type result struct {
+		table    string
+		checksum string
+		err      error
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	tables := make(chan string)
+	results := make(chan result)
+
+	if workers <= 0 {
+		workers = 1
+	}
+
+	if workers > len(names) {
+		workers = len(names)
+	}
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for table := range tables {
+				checksum, err := checksum(ctx, table)
+				select {
+				case results <- result{table: table, checksum: checksum, err: err}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	go func() {
+		defer close(tables)
+		for _, name := range names {
+			select {
+			case tables <- name:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	chks := make(map[string]string, len(names))
+	for range names {
+		r := <-results
+		if r.err != nil {
+			return nil, r.err
+		}
+
+		chks[r.table] = r.checksum
+		TableChecksummed(ctx, r.table)
+	}
+
+	return chks, nil
+}
@@ -0,0 +1,140 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestQuickUnchanged(t *testing.T) {
+	at := func(sec int64) time.Time {
+		return time.Unix(1600000000+sec, 0)
+	}
+
+	tests := []struct {
+		name          string
+		strategy      ChecksumStrategy
+		master, slave string
+		want          bool
+	}{
+		{"native equal", ChecksumNative, "123", "123", true},
+		{"native different", ChecksumNative, "123", "124", false},
+		{"slave written after master", ChecksumMetadata, MetadataChecksum(10, 16384, at(0)), MetadataChecksum(10, 16384, at(5)), true},
+		{"master updated in place", ChecksumMetadata, MetadataChecksum(10, 16384, at(5)), MetadataChecksum(10, 16384, at(0)), false},
+		{"updated in the same second", ChecksumMetadata, MetadataChecksum(10, 16384, at(5)), MetadataChecksum(10, 16384, at(5)), false},
+		{"different row counts", ChecksumMetadata, MetadataChecksum(10, 16384, at(0)), MetadataChecksum(11, 16384, at(5)), false},
+		{"different data lengths", ChecksumMetadata, MetadataChecksum(10, 16384, at(0)), MetadataChecksum(10, 32768, at(5)), false},
+		{"invalid master checksum", ChecksumMetadata, "10:16384", MetadataChecksum(10, 16384, at(5)), false},
+		{"invalid slave checksum", ChecksumMetadata, MetadataChecksum(10, 16384, at(0)), "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := quickUnchanged(tt.strategy, tt.master, tt.slave); got != tt.want {
+				t.Errorf("quickUnchanged(%q, %q, %q) = %v, want %v", tt.strategy, tt.master, tt.slave, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParallelChecksumsWorkers(t *testing.T) {
+	const workers = 2
+	names := []string{"a", "b", "c", "d", "e"}
+
+	var mu sync.Mutex
+	running, maxRunning := 0, 0
+	started := make(chan string, len(names))
+	release := make(chan struct{})
+	checksum := func(ctx context.Context, table string) (string, error) {
+		mu.Lock()
+		running++
+		if running > maxRunning {
+			maxRunning = running
+		}
+		mu.Unlock()
+
+		started <- table
+		<-release
+
+		mu.Lock()
+		running--
+		mu.Unlock()
+
+		return "sum-" + table, nil
+	}
+
+	type result struct {
+		chks map[string]string
+		err  error
+	}
+
+	done := make(chan result)
+	go func() {
+		chks, err := ParallelChecksums(context.Background(), workers, checksum, names...)
+		done <- result{chks, err}
+	}()
+
+	for i := 0; i < workers; i++ {
+		<-started
+	}
+
+	select {
+	case table := <-started:
+		t.Errorf("%s checksummed while %d tables were", table, workers)
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+
+	r := <-done
+	if r.err != nil {
+		t.Fatalf("ParallelChecksums() error = %s", r.err)
+	}
+
+	want := map[string]string{"a": "sum-a", "b": "sum-b", "c": "sum-c", "d": "sum-d", "e": "sum-e"}
+	if !reflect.DeepEqual(r.chks, want) {
+		t.Errorf("ParallelChecksums() = %v, want %v", r.chks, want)
+	}
+
+	if maxRunning != workers {
+		t.Errorf("%d tables checksummed at a time, want %d", maxRunning, workers)
+	}
+}
+
+func TestParallelChecksumsError(t *testing.T) {
+	errFailed := errors.New("failed")
+	cancelled := make(chan struct{})
+	checksum := func(ctx context.Context, table string) (string, error) {
+		if table == "bad" {
+			return "", errFailed
+		}
+
+		// the checksums still running are cancelled by the error
+		<-ctx.Done()
+		if table == "slow" {
+			close(cancelled)
+		}
+
+		return "", ctx.Err()
+	}
+
+	_, err := ParallelChecksums(context.Background(), 2, checksum, "slow", "bad", "never")
+	if err != errFailed {
+		t.Errorf("ParallelChecksums() error = %v, want %v", err, errFailed)
+	}
+
+	select {
+	case <-cancelled:
+	case <-time.After(time.Second):
+		t.Errorf("running checksum not cancelled")
+	}
+}
+
+func TestParallelChecksumsNoTables(t *testing.T) {
+	chks, err := ParallelChecksums(context.Background(), 4, nil)
+	if err != nil || len(chks) != 0 {
+		t.Errorf("ParallelChecksums() = %v, %v, want no checksums", chks, err)
+	}
+}
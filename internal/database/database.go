@@ -0,0 +1,214 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vcraescu/dbsync/internal/compress"
+)
+
+// DefaultDriver - driver used when the config does not name one
+const DefaultDriver = "mysql"
+
+// ConnectionConfig - database connection config
+type ConnectionConfig struct {
+	// Driver - name of the engine, mysql when empty
+	Driver   string
+	Username string
+	Password string
+	Port     int
+	Host     string
+	Schema   string
+	Timezone string
+	// MaxConnections - maximum number of simultaneous connections to the server,
+	// which is also the number of tables checksummed in parallel
+	MaxConnections int
+	// Checksum - strategy used to detect changed tables
+	Checksum ChecksumStrategy
+	// Filters - where conditions by table; only the matching rows are compared and synced
+	Filters map[string]string
+	// FilterBatches - disjoint where conditions by table whose disjunction is the table filter. Tables
+	// with batches are dumped batch by batch, as a long filter does not fit a command line argument.
+	FilterBatches map[string][]string
+	// Compression - algorithm compressing the traffic of the dump and import clients
+	Compression compress.Algorithm
+}
+
+// Filter - returns the where condition of a filtered table. Tables are matched case-insensitively
+// when there is no exact match, as the config file keys are lowercased.
+func (cfg ConnectionConfig) Filter(table string) (string, bool) {
+	if filter, ok := cfg.Filters[table]; ok {
+		return filter, true
+	}
+
+	for name, filter := range cfg.Filters {
+		if strings.EqualFold(name, table) {
+			return filter, true
+		}
+	}
+
+	return "", false
+}
+
+// Batches - returns the where conditions a filtered table is dumped with, one after the other;
+// the filter itself when the table has no batches
+func (cfg ConnectionConfig) Batches(table string) ([]string, bool) {
+	if batches, ok := cfg.FilterBatches[table]; ok {
+		return batches, true
+	}
+
+	for name, batches := range cfg.FilterBatches {
+		if strings.EqualFold(name, table) {
+			return batches, true
+		}
+	}
+
+	filter, ok := cfg.Filter(table)
+	if !ok {
+		return nil, false
+	}
+
+	return []string{filter}, true
+}
+
+// Timeouts - maximum durations of the sync phases, unlimited when zero
+type Timeouts struct {
+	// Checksum - listing and checksumming the master and slave tables
+	Checksum time.Duration
+	// Dump - dumping the synced tables from master
+	Dump time.Duration
+	// Import - importing the dumps into slave
+	Import time.Duration
+}
+
+// WithTimeout - returns ctx limited to timeout, unlimited when timeout is zero
+func WithTimeout(ctx context.Context, timeout time.Duration) (context.Context, context.CancelFunc) {
+	if timeout <= 0 {
+		return context.WithCancel(ctx)
+	}
+
+	return context.WithTimeout(ctx, timeout)
+}
+
+// Source - database or snapshot the slave is synced from
+type Source interface {
+	Open(ctx context.Context) error
+	// Driver - name of the engine the tables and objects come from
+	Driver() string
+	TableNames(ctx context.Context) ([]string, error)
+	ChecksumTables(ctx context.Context, tables ...string) (map[string]string, error)
+	TableDependencies(ctx context.Context) (map[string][]string, error)
+	Objects(ctx context.Context) ([]Object, error)
+	// Filter - returns the where condition of a filtered table
+	Filter(table string) (string, bool)
+}
+
+// Connection - live database which can be synced from and to
+type Connection interface {
+	Source
+	Dialect
+	Close() error
+	Host() string
+	Schema() string
+}
+
+// Dialect - engine specific statements run on the slave
+type Dialect interface {
+	DropTableStatement(table string) string
+	DropObjectStatement(o Object) string
+	CreateObjectStatement(o Object) string
+	// ForeignKeyChecksStatement - statement enabling or disabling the foreign key checks of the session
+	ForeignKeyChecksStatement(enabled bool) string
+}
+
+// QuickChecksummer - connection able to compute cheap fingerprints of its tables
+type QuickChecksummer interface {
+	ChecksumStrategy() ChecksumStrategy
+	QuickChecksums(ctx context.Context, strategy ChecksumStrategy, tables ...string) (map[string]string, error)
+}
+
+// RowCounter - source able to count the rows of its tables. Filtered tables only count the
+// rows matching their filter.
+type RowCounter interface {
+	CountRows(ctx context.Context, tables ...string) (map[string]int64, error)
+}
+
+// RowEstimator - source able to estimate the rows of its tables without scanning them, from the
+// statistics of the server. Tables without an estimate, like filtered tables, are left out.
+type RowEstimator interface {
+	EstimateRows(ctx context.Context, tables ...string) (map[string]int64, error)
+}
+
+// Fingerprinter - source able to return cheap values changing whenever the content of a table
+// changes. Tables whose fingerprint is unknown, e.g. modified too recently, are left out.
+type Fingerprinter interface {
+	Fingerprints(ctx context.Context, tables ...string) (map[string]string, error)
+}
+
+// Dumper - streams the sql of single tables. The dump client is killed once ctx is done.
+type Dumper interface {
+	DumpTableTo(ctx context.Context, w io.Writer, table string) error
+	RefreshTableTo(ctx context.Context, w io.Writer, table string) error
+}
+
+// Importer - runs sql on a database. The import client is killed once ctx is done.
+type Importer interface {
+	Import(ctx context.Context, dump string) error
+	ImportFrom(ctx context.Context, r io.Reader) error
+}
+
+// Engine - creates the connections, dumpers and importers of a driver
+type Engine interface {
+	Connection(cfg ConnectionConfig) Connection
+	Dumper(cfg ConnectionConfig) Dumper
+	Importer(cfg ConnectionConfig) Importer
+}
+
+var (
+	enginesMu sync.RWMutex
+	engines   = make(map[string]Engine)
+)
+
+// Register - makes an engine available under the driver name; called by the engine packages on init
+func Register(driver string, e Engine) {
+	enginesMu.Lock()
+	defer enginesMu.Unlock()
+
+	if _, ok := engines[driver]; ok {
+		panic("database: Register called twice for driver " + driver)
+	}
+
+	engines[driver] = e
+}
+
+// Lookup - returns the engine registered under the driver name
+func Lookup(driver string) (Engine, error) {
+	if driver == "" {
+		driver = DefaultDriver
+	}
+
+	enginesMu.RLock()
+	defer enginesMu.RUnlock()
+
+	e, ok := engines[driver]
+	if !ok {
+		return nil, fmt.Errorf("unknown driver %q, available: %s", driver, strings.Join(drivers(), ", "))
+	}
+
+	return e, nil
+}
+
+func drivers() []string {
+	names := make([]string, 0, len(engines))
+	for name := range engines {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
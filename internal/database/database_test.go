@@ -0,0 +1,52 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConnectionConfigBatches(t *testing.T) {
+	cfg := ConnectionConfig{
+		Filters: map[string]string{
+			"users":  "(id in (1,2)) or (id in (3))",
+			"orders": "total > 0",
+		},
+		FilterBatches: map[string][]string{
+			"users": {"id in (1,2)", "id in (3)"},
+		},
+	}
+
+	tests := []struct {
+		table  string
+		want   []string
+		wantOk bool
+	}{
+		{"users", []string{"id in (1,2)", "id in (3)"}, true},
+		{"USERS", []string{"id in (1,2)", "id in (3)"}, true},
+		{"orders", []string{"total > 0"}, true},
+		{"Orders", []string{"total > 0"}, true},
+		{"logs", nil, false},
+	}
+
+	for _, tt := range tests {
+		got, ok := cfg.Batches(tt.table)
+		if !reflect.DeepEqual(got, tt.want) || ok != tt.wantOk {
+			t.Errorf("Batches(%q) = %q, %v, want %q, %v", tt.table, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
+
+func TestConnectionConfigFilterExactMatchFirst(t *testing.T) {
+	cfg := ConnectionConfig{
+		Filters: map[string]string{
+			"users": "lower",
+			"Users": "mixed",
+		},
+	}
+
+	for table, want := range map[string]string{"users": "lower", "Users": "mixed"} {
+		if got, ok := cfg.Filter(table); !ok || got != want {
+			t.Errorf("Filter(%q) = %q, %v, want %q", table, got, ok, want)
+		}
+	}
+}
@@ -0,0 +1,549 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Diff - computed diff
+type Diff struct {
+	// Create - tables to create, ordered so that referenced tables come first
+	Create []string
+	// Refresh - filtered tables whose matching rows are deleted and inserted again,
+	// ordered so that referenced tables come first
+	Refresh []string
+	// Delete - tables to delete, ordered so that referencing tables come first
+	Delete []string
+	// Updated - created or refreshed tables which already exist on slave
+	Updated []string
+	// Unchanged - tables with the same content on master and slave
+	Unchanged []string
+	// Unknown - tables existing on both sides whose content can't be compared, master and slave
+	// being of different engines. They are recreated, like the updated tables.
+	Unknown []string
+	// MasterChecksums, SlaveChecksums - checksums of the tables existing on both sides, the quick
+	// ones when those were conclusive
+	MasterChecksums map[string]string
+	SlaveChecksums  map[string]string
+	// CreateObjects - views, triggers, routines and events to create, in creation order
+	CreateObjects []Object
+	// DropObjects - views, triggers, routines and events to drop, in drop order
+	DropObjects []Object
+	// Cyclic - true when the foreign keys between the synced tables form a cycle
+	Cyclic bool
+	// Dependencies - tables referenced through foreign keys by every master table
+	Dependencies map[string][]string
+
+	// dialect - statements of the slave engine
+	dialect Dialect
+}
+
+// Empty - returns true if diff is empty
+func (d *Diff) Empty() bool {
+	return len(d.Create) == 0 && len(d.Refresh) == 0 && len(d.Delete) == 0 &&
+		len(d.CreateObjects) == 0 && len(d.DropObjects) == 0
+}
+
+// GenerateSQL - generate dump sql
+func (d *Diff) GenerateSQL(ctx context.Context, dumper Dumper) (string, error) {
+	var dump strings.Builder
+	if d.Empty() {
+		return "", errors.New("diff empty")
+	}
+
+	if d.Cyclic {
+		dump.WriteString(d.foreignKeyChecksSQL(false))
+	}
+
+	dump.WriteString(d.dropSQL())
+
+	for _, table := range d.Create {
+		if err := dumper.DumpTableTo(ctx, &dump, table); err != nil {
+			return "", fmt.Errorf("Generate SQL (%s): %s", table, err)
+		}
+	}
+
+	for _, table := range d.Refresh {
+		if err := dumper.RefreshTableTo(ctx, &dump, table); err != nil {
+			return "", fmt.Errorf("Generate SQL (%s): %s", table, err)
+		}
+	}
+
+	dump.WriteString(d.createObjectsSQL())
+
+	if d.Cyclic {
+		dump.WriteString(d.foreignKeyChecksSQL(true))
+	}
+
+	return strings.Trim(dump.String(), " \n"), nil
+}
+
+func (d *Diff) foreignKeyChecksSQL(enabled bool) string {
+	return d.dialect.ForeignKeyChecksStatement(enabled) + ";\n"
+}
+
+// dropSQL - statements dropping the deleted tables and the deleted or changed objects
+func (d *Diff) dropSQL() string {
+	var dump string
+	for _, o := range d.DropObjects {
+		dump += d.dialect.DropObjectStatement(o) + ";\n"
+	}
+
+	for _, table := range d.Delete {
+		dump += d.dialect.DropTableStatement(table) + ";\n"
+	}
+
+	return dump
+}
+
+// createObjectsSQL - statements creating the new or changed objects
+func (d *Diff) createObjectsSQL() string {
+	var dump string
+	for _, o := range d.CreateObjects {
+		dump += d.dialect.CreateObjectStatement(o) + "\n"
+	}
+
+	return dump
+}
+
+// ChecksumPair - master and slave checksums of a table
+type ChecksumPair struct {
+	Master string
+	Slave  string
+}
+
+// GenerateDiff - generate diff between to databases
+func GenerateDiff(ctx context.Context, masterConn Source, slaveConn Connection) (*Diff, error) {
+	return GenerateIncrementalDiff(ctx, masterConn, slaveConn, nil)
+}
+
+// GenerateIncrementalDiff - generates the diff between two databases without comparing the tables
+// known to be unchanged on both sides since the last sync, which are reported with the given checksums
+func GenerateIncrementalDiff(
+	ctx context.Context,
+	masterConn Source,
+	slaveConn Connection,
+	unchanged map[string]ChecksumPair,
+) (*Diff, error) {
+	masterTables, slaveTables, err := openAndListTables(ctx, masterConn, slaveConn)
+	if err != nil {
+		return nil, err
+	}
+
+	inSlave := make(map[string]bool, len(slaveTables))
+	for _, table := range slaveTables {
+		inSlave[table] = true
+	}
+
+	var create, common, known []string
+	for _, table := range masterTables {
+		if _, ok := unchanged[table]; ok && inSlave[table] {
+			known = append(known, table)
+			continue
+		}
+
+		if inSlave[table] {
+			common = append(common, table)
+			continue
+		}
+
+		create = append(create, table)
+	}
+
+	// the content checksums of different engines never match
+	sameEngine := masterConn.Driver() == slaveConn.Driver()
+	changed, masterChks, slaveChks := common, map[string]string{}, map[string]string{}
+	if sameEngine {
+		changed, masterChks, slaveChks, err = changedTables(ctx, masterConn, slaveConn, common)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	isChanged := make(map[string]bool, len(changed))
+	var refresh []string
+	for _, table := range changed {
+		isChanged[table] = true
+		if _, ok := masterConn.Filter(table); ok {
+			refresh = append(refresh, table)
+			continue
+		}
+
+		create = append(create, table)
+	}
+
+	diff, err := newDiff(ctx, masterConn, slaveConn, create, refresh, slaveOnlyTables(masterTables, slaveTables))
+	if err != nil {
+		return nil, err
+	}
+
+	diff.Updated = changed
+	if !sameEngine {
+		diff.Unknown = common
+	}
+
+	for _, table := range common {
+		if !isChanged[table] {
+			diff.Unchanged = append(diff.Unchanged, table)
+		}
+	}
+
+	for _, table := range known {
+		diff.Unchanged = append(diff.Unchanged, table)
+		if chks := unchanged[table]; chks.Master != "" && chks.Slave != "" {
+			masterChks[table] = chks.Master
+			slaveChks[table] = chks.Slave
+		}
+	}
+	sort.Strings(diff.Unchanged)
+
+	diff.MasterChecksums = masterChks
+	diff.SlaveChecksums = slaveChks
+
+	return diff, nil
+}
+
+// TableChecksums - returns the content checksums of every table of a source
+func TableChecksums(ctx context.Context, src Source) (map[string]string, error) {
+	names, err := src.TableNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return src.ChecksumTables(ctx, names...)
+}
+
+// GenerateSubsetDiff - generates a diff which recreates every master table on slave
+func GenerateSubsetDiff(ctx context.Context, masterConn Source, slaveConn Connection) (*Diff, error) {
+	masterTables, slaveTables, err := openAndListTables(ctx, masterConn, slaveConn)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := newDiff(ctx, masterConn, slaveConn, masterTables, nil, slaveOnlyTables(masterTables, slaveTables))
+	if err != nil {
+		return nil, err
+	}
+
+	inSlave := make(map[string]bool, len(slaveTables))
+	for _, table := range slaveTables {
+		inSlave[table] = true
+	}
+
+	for _, table := range masterTables {
+		if inSlave[table] {
+			diff.Updated = append(diff.Updated, table)
+		}
+	}
+
+	return diff, nil
+}
+
+// GenerateTablesDiff - generates a diff which recreates the given master tables on slave, refreshing
+// the filtered ones, and deletes the given slave tables. Only the triggers and views depending on
+// the recreated tables are recreated, other objects are left alone.
+func GenerateTablesDiff(ctx context.Context, masterConn Source, slaveConn Connection, tables, del []string) (*Diff, error) {
+	if err := masterConn.Open(ctx); err != nil {
+		return nil, fmt.Errorf("master: %s", err)
+	}
+
+	if err := slaveConn.Open(ctx); err != nil {
+		return nil, fmt.Errorf("slave: %s", err)
+	}
+
+	var create, refresh []string
+	for _, table := range tables {
+		if _, ok := masterConn.Filter(table); ok {
+			refresh = append(refresh, table)
+			continue
+		}
+
+		create = append(create, table)
+	}
+
+	diff, err := newDiff(ctx, masterConn, slaveConn, create, refresh, del)
+	if err != nil {
+		return nil, err
+	}
+
+	recreated := make(map[string]bool, len(diff.Create))
+	for _, table := range diff.Create {
+		recreated[table] = true
+	}
+
+	keys := make(map[string]bool)
+	var createObjects []Object
+	for _, o := range diff.CreateObjects {
+		if dependsOn(o, diff.Create, recreated) {
+			createObjects = append(createObjects, o)
+			keys[o.key()] = true
+		}
+	}
+
+	var dropObjects []Object
+	for _, o := range diff.DropObjects {
+		if keys[o.key()] {
+			dropObjects = append(dropObjects, o)
+		}
+	}
+
+	diff.CreateObjects, diff.DropObjects = createObjects, dropObjects
+
+	return diff, nil
+}
+
+// openAndListTables - opens both connections and returns master and slave table names
+func openAndListTables(ctx context.Context, masterConn Source, slaveConn Connection) ([]string, []string, error) {
+	if err := masterConn.Open(ctx); err != nil {
+		return nil, nil, fmt.Errorf("master: %s", err)
+	}
+
+	if err := slaveConn.Open(ctx); err != nil {
+		return nil, nil, fmt.Errorf("slave: %s", err)
+	}
+
+	var masterTables, slaveTables []string
+	masterErr, slaveErr := both(
+		func() (err error) {
+			masterTables, err = masterConn.TableNames(ctx)
+			return
+		},
+		func() (err error) {
+			slaveTables, err = slaveConn.TableNames(ctx)
+			return
+		},
+	)
+	if masterErr != nil {
+		return nil, nil, fmt.Errorf("master table names: %s", masterErr)
+	}
+
+	if slaveErr != nil {
+		return nil, nil, fmt.Errorf("slave table names: %s", slaveErr)
+	}
+
+	return masterTables, slaveTables, nil
+}
+
+func slaveOnlyTables(masterTables, slaveTables []string) []string {
+	inMaster := make(map[string]bool, len(masterTables))
+	for _, table := range masterTables {
+		inMaster[table] = true
+	}
+
+	var tables []string
+	for _, table := range slaveTables {
+		if !inMaster[table] {
+			tables = append(tables, table)
+		}
+	}
+
+	return tables
+}
+
+// newDiff - orders the tables by their foreign keys and computes the objects diff.
+// Objects are only synced between databases of the same engine.
+func newDiff(ctx context.Context, masterConn Source, slaveConn Connection, create, refresh, del []string) (*Diff, error) {
+	masterDeps, err := masterConn.TableDependencies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("master table dependencies: %s", err)
+	}
+
+	slaveDeps, err := slaveConn.TableDependencies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("slave table dependencies: %s", err)
+	}
+
+	var masterObjects, slaveObjects []Object
+	if masterConn.Driver() == slaveConn.Driver() {
+		masterObjects, err = masterConn.Objects(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("master objects: %s", err)
+		}
+
+		slaveObjects, err = slaveConn.Objects(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("slave objects: %s", err)
+		}
+	}
+
+	diff := &Diff{
+		Dependencies: masterDeps,
+		dialect:      slaveConn,
+	}
+
+	var createCyclic, deleteCyclic bool
+	diff.Create, createCyclic = sortTables(create, masterDeps)
+	diff.Refresh, _ = sortTables(refresh, masterDeps)
+	del, deleteCyclic = sortTables(del, slaveDeps)
+	diff.Delete = reverseTables(del)
+	diff.Cyclic = createCyclic || deleteCyclic
+	diff.CreateObjects, diff.DropObjects = diffObjects(masterObjects, slaveObjects, diff.Create)
+
+	return diff, nil
+}
+
+// diffObjects - returns the objects to create and drop on slave. Triggers of recreated tables
+// are always recreated since dropping a table drops its triggers, and so are the views selecting
+// from them since some engines drop those too.
+func diffObjects(masterObjects, slaveObjects []Object, recreated []string) ([]Object, []Object) {
+	recreatedTables := make(map[string]bool, len(recreated))
+	for _, table := range recreated {
+		recreatedTables[table] = true
+	}
+
+	slaveByKey := make(map[string]Object, len(slaveObjects))
+	for _, o := range slaveObjects {
+		slaveByKey[o.key()] = o
+	}
+
+	masterByKey := make(map[string]Object, len(masterObjects))
+	var create, drop []Object
+	for _, mo := range masterObjects {
+		masterByKey[mo.key()] = mo
+
+		so, ok := slaveByKey[mo.key()]
+		if ok && so.Definition == mo.Definition && !dependsOn(so, recreated, recreatedTables) {
+			continue
+		}
+
+		create = append(create, mo)
+		if ok {
+			drop = append(drop, so)
+		}
+	}
+
+	for _, so := range slaveObjects {
+		if _, ok := masterByKey[so.key()]; !ok {
+			drop = append(drop, so)
+		}
+	}
+
+	return sortObjects(create), reverseObjects(sortObjects(drop))
+}
+
+// dependsOn - returns true when the object is a trigger on or a view selecting from a recreated table
+func dependsOn(o Object, recreated []string, recreatedTables map[string]bool) bool {
+	switch o.Type {
+	case ObjectTrigger:
+		return recreatedTables[o.Table]
+	case ObjectView:
+		for _, table := range recreated {
+			if o.references(table) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// changedTables - returns the tables whose content differs between master and slave, along
+// with the master and slave checksums of the tables. Both must be of the same engine.
+// When both are live connections supporting quick checksums, tables whose
+// quick checksums prove them unchanged are not compared by content checksums.
+func changedTables(
+	ctx context.Context,
+	master Source,
+	slaveConn Connection,
+	tables []string,
+) ([]string, map[string]string, map[string]string, error) {
+	masterSums := make(map[string]string, len(tables))
+	slaveSums := make(map[string]string, len(tables))
+	inconclusive := tables
+	masterQuick, masterOk := master.(QuickChecksummer)
+	slaveQuick, slaveOk := slaveConn.(QuickChecksummer)
+	if masterOk && slaveOk && slaveQuick.ChecksumStrategy() != ChecksumContent {
+		strategy := slaveQuick.ChecksumStrategy()
+
+		var masterChks, slaveChks map[string]string
+		masterErr, slaveErr := both(
+			func() (err error) {
+				masterChks, err = masterQuick.QuickChecksums(ctx, strategy, tables...)
+				return
+			},
+			func() (err error) {
+				slaveChks, err = slaveQuick.QuickChecksums(ctx, strategy, tables...)
+				return
+			},
+		)
+		if masterErr != nil {
+			return nil, nil, nil, fmt.Errorf("master quick checksums: %s", masterErr)
+		}
+
+		if slaveErr != nil {
+			return nil, nil, nil, fmt.Errorf("slave quick checksums: %s", slaveErr)
+		}
+
+		inconclusive = nil
+		for _, table := range tables {
+			mc, masterOk := masterChks[table]
+			sc, slaveOk := slaveChks[table]
+			if masterOk && slaveOk && quickUnchanged(strategy, mc, sc) {
+				masterSums[table] = mc
+				slaveSums[table] = sc
+				continue
+			}
+
+			inconclusive = append(inconclusive, table)
+		}
+	}
+
+	if len(inconclusive) == 0 {
+		return nil, masterSums, slaveSums, nil
+	}
+
+	ProgressFrom(ctx).startChecksums(len(inconclusive))
+
+	var masterChks, slaveChks map[string]string
+	masterErr, slaveErr := both(
+		func() (err error) {
+			masterChks, err = master.ChecksumTables(ctx, inconclusive...)
+			return
+		},
+		func() (err error) {
+			slaveChks, err = slaveConn.ChecksumTables(ctx, inconclusive...)
+			return
+		},
+	)
+	if masterErr != nil {
+		return nil, nil, nil, fmt.Errorf("master table checksums: %s", masterErr)
+	}
+
+	if slaveErr != nil {
+		return nil, nil, nil, fmt.Errorf("slave table checksums: %s", slaveErr)
+	}
+
+	var changed []string
+	for _, table := range inconclusive {
+		masterSums[table] = masterChks[table]
+		slaveSums[table] = slaveChks[table]
+		if masterChks[table] != slaveChks[table] {
+			changed = append(changed, table)
+		}
+	}
+
+	return changed, masterSums, slaveSums, nil
+}
+
+// both - runs the master and slave functions at the same time
+func both(master, slave func() error) (error, error) {
+	var masterErr, slaveErr error
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		masterErr = master()
+	}()
+	go func() {
+		defer wg.Done()
+		slaveErr = slave()
+	}()
+	wg.Wait()
+
+	return masterErr, slaveErr
+}
@@ -0,0 +1,178 @@
+package database
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChangedTablesMetadata(t *testing.T) {
+	synced := time.Unix(1600000000, 0)
+	master := &fakeConn{
+		strategy: ChecksumMetadata,
+		checksums: map[string]string{
+			"unchanged": "a", "updated": "b", "resized": "c", "restarted": "d", "same": "e",
+		},
+		quick: map[string]string{
+			"unchanged": MetadataChecksum(10, 16384, synced.Add(-time.Minute)),
+			// updated in place after the sync, with the same size
+			"updated":   MetadataChecksum(10, 16384, synced.Add(time.Minute)),
+			"resized":   MetadataChecksum(11, 32768, synced.Add(-time.Minute)),
+			"restarted": MetadataChecksum(10, 16384, synced.Add(-time.Minute)),
+			"same":      MetadataChecksum(10, 16384, synced.Add(-time.Minute)),
+		},
+	}
+	slave := &fakeConn{
+		strategy: ChecksumMetadata,
+		checksums: map[string]string{
+			"unchanged": "a", "updated": "x", "resized": "y", "restarted": "z", "same": "e",
+		},
+		quick: map[string]string{
+			"unchanged": MetadataChecksum(10, 16384, synced),
+			"updated":   MetadataChecksum(10, 16384, synced),
+			"resized":   MetadataChecksum(10, 16384, synced),
+			// update time unknown since the server restarted
+			"same": MetadataChecksum(10, 16384, synced.Add(-time.Minute)),
+		},
+	}
+
+	tables := []string{"resized", "restarted", "same", "unchanged", "updated"}
+	changed, masterSums, slaveSums, err := changedTables(context.Background(), master, slave, tables)
+	if err != nil {
+		t.Fatalf("changedTables() error = %s", err)
+	}
+
+	if want := []string{"resized", "restarted", "updated"}; !reflect.DeepEqual(changed, want) {
+		t.Errorf("changed = %q, want %q", changed, want)
+	}
+
+	// every table but the one proven unchanged by its metadata is compared by content
+	if want := []string{"resized", "restarted", "same", "updated"}; !reflect.DeepEqual(slave.checksummed, want) {
+		t.Errorf("content checksummed = %q, want %q", slave.checksummed, want)
+	}
+
+	if masterSums["unchanged"] != master.quick["unchanged"] || slaveSums["unchanged"] != slave.quick["unchanged"] {
+		t.Errorf("unchanged checksums = %q, %q, want the quick checksums of each side", masterSums["unchanged"], slaveSums["unchanged"])
+	}
+
+	if masterSums["updated"] != "b" || slaveSums["updated"] != "x" {
+		t.Errorf("updated checksums = %q, %q, want the content checksums", masterSums["updated"], slaveSums["updated"])
+	}
+}
+
+func TestGenerateIncrementalDiffOtherEngines(t *testing.T) {
+	master := &fakeConn{
+		driver:    "mysql",
+		checksums: map[string]string{"users": "a", "orders": "b", "logs": "c", "items": "d"},
+	}
+	slave := &fakeConn{
+		driver:    "sqlite",
+		checksums: map[string]string{"users": "a", "orders": "x", "logs": "c", "old": "e"},
+	}
+
+	// logs did not change on either side since the last sync
+	unchanged := map[string]ChecksumPair{"logs": {Master: "c", Slave: "c"}}
+	diff, err := GenerateIncrementalDiff(context.Background(), master, slave, unchanged)
+	if err != nil {
+		t.Fatalf("GenerateIncrementalDiff() error = %s", err)
+	}
+
+	if len(master.checksummed) != 0 || len(slave.checksummed) != 0 {
+		t.Errorf("content checksummed = %q, %q, want none", master.checksummed, slave.checksummed)
+	}
+
+	tests := []struct {
+		name      string
+		got, want []string
+	}{
+		{"create", diff.Create, []string{"items", "orders", "users"}},
+		{"delete", diff.Delete, []string{"old"}},
+		{"updated", diff.Updated, []string{"orders", "users"}},
+		{"unknown", diff.Unknown, []string{"orders", "users"}},
+		{"unchanged", diff.Unchanged, []string{"logs"}},
+	}
+
+	for _, tt := range tests {
+		if !reflect.DeepEqual(tt.got, tt.want) {
+			t.Errorf("%s = %q, want %q", tt.name, tt.got, tt.want)
+		}
+	}
+}
+
+func TestDiffObjects(t *testing.T) {
+	view := Object{Type: ObjectView, Name: "active_users", Definition: "CREATE VIEW `active_users` AS select * from `users`"}
+	trigger := Object{Type: ObjectTrigger, Name: "audit", Table: "orders", Definition: "CREATE TRIGGER `audit` AFTER INSERT ON `orders`"}
+	procedure := Object{Type: ObjectProcedure, Name: "archive", Definition: "CREATE PROCEDURE `archive`() BEGIN END"}
+	function := Object{Type: ObjectFunction, Name: "total", Definition: "CREATE FUNCTION `total`() RETURNS int RETURN 1"}
+	event := Object{Type: ObjectEvent, Name: "cleanup", Definition: "CREATE EVENT `cleanup` ON SCHEDULE EVERY 1 DAY DO DELETE FROM `logs`"}
+
+	changed := func(o Object) Object {
+		o.Definition += " -- changed"
+		return o
+	}
+
+	all := []Object{view, trigger, procedure, function, event}
+
+	tests := []struct {
+		name          string
+		master, slave []Object
+		recreated     []string
+		create, drop  []string
+	}{
+		{
+			name:   "same objects",
+			master: all,
+			slave:  all,
+		},
+		{
+			name:   "create",
+			master: all,
+			create: []string{"function total", "procedure archive", "view active_users", "trigger audit", "event cleanup"},
+		},
+		{
+			name:  "drop",
+			slave: all,
+			drop:  []string{"event cleanup", "trigger audit", "view active_users", "procedure archive", "function total"},
+		},
+		{
+			name:   "replace",
+			master: []Object{changed(view), trigger, changed(procedure), function, changed(event)},
+			slave:  all,
+			create: []string{"procedure archive", "view active_users", "event cleanup"},
+			drop:   []string{"event cleanup", "view active_users", "procedure archive"},
+		},
+		{
+			name:      "objects depending on recreated tables",
+			master:    all,
+			slave:     all,
+			recreated: []string{"orders", "users"},
+			create:    []string{"view active_users", "trigger audit"},
+			drop:      []string{"trigger audit", "view active_users"},
+		},
+		{
+			name:      "objects depending on other tables",
+			master:    all,
+			slave:     all,
+			recreated: []string{"logs", "user"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			create, drop := diffObjects(tt.master, tt.slave, tt.recreated)
+			if got := objectNames(create); !reflect.DeepEqual(got, tt.create) {
+				t.Errorf("create = %q, want %q", got, tt.create)
+			}
+
+			if got := objectNames(drop); !reflect.DeepEqual(got, tt.drop) {
+				t.Errorf("drop = %q, want %q", got, tt.drop)
+			}
+		})
+	}
+
+	create, _ := diffObjects([]Object{changed(view)}, []Object{view}, nil)
+	if len(create) != 1 || create[0].Definition != changed(view).Definition {
+		t.Errorf("replaced objects = %+v, want the master definition", create)
+	}
+}
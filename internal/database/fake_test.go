@@ -0,0 +1,125 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+// fakeConn - in memory connection whose tables have fixed checksums
+type fakeConn struct {
+	driver   string
+	strategy ChecksumStrategy
+	// checksums, quick - content and quick checksums by table
+	checksums map[string]string
+	quick     map[string]string
+	filters   map[string]string
+	objects   []Object
+
+	mu sync.Mutex
+	// checksummed - tables whose content checksums were computed
+	checksummed []string
+}
+
+func (c *fakeConn) Open(ctx context.Context) error {
+	return nil
+}
+
+func (c *fakeConn) Driver() string {
+	if c.driver == "" {
+		return DefaultDriver
+	}
+
+	return c.driver
+}
+
+func (c *fakeConn) TableNames(ctx context.Context) ([]string, error) {
+	var names []string
+	for name := range c.checksums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+func (c *fakeConn) ChecksumTables(ctx context.Context, tables ...string) (map[string]string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	chks := make(map[string]string, len(tables))
+	for _, table := range tables {
+		chk, ok := c.checksums[table]
+		if !ok {
+			return nil, fmt.Errorf("table %s doesn't exist", table)
+		}
+
+		chks[table] = chk
+		c.checksummed = append(c.checksummed, table)
+	}
+	sort.Strings(c.checksummed)
+
+	return chks, nil
+}
+
+func (c *fakeConn) TableDependencies(ctx context.Context) (map[string][]string, error) {
+	return map[string][]string{}, nil
+}
+
+func (c *fakeConn) Objects(ctx context.Context) ([]Object, error) {
+	return c.objects, nil
+}
+
+func (c *fakeConn) Filter(table string) (string, bool) {
+	filter, ok := c.filters[table]
+
+	return filter, ok
+}
+
+func (c *fakeConn) ChecksumStrategy() ChecksumStrategy {
+	if c.strategy == "" {
+		return ChecksumContent
+	}
+
+	return c.strategy
+}
+
+func (c *fakeConn) QuickChecksums(ctx context.Context, strategy ChecksumStrategy, tables ...string) (map[string]string, error) {
+	chks := make(map[string]string, len(tables))
+	for _, table := range tables {
+		if chk, ok := c.quick[table]; ok {
+			chks[table] = chk
+		}
+	}
+
+	return chks, nil
+}
+
+func (c *fakeConn) DropTableStatement(table string) string {
+	return "drop table " + table
+}
+
+func (c *fakeConn) DropObjectStatement(o Object) string {
+	return fmt.Sprintf("drop %s %s", o.Type, o.Name)
+}
+
+func (c *fakeConn) CreateObjectStatement(o Object) string {
+	return o.Definition + ";"
+}
+
+func (c *fakeConn) ForeignKeyChecksStatement(enabled bool) string {
+	return fmt.Sprintf("set foreign_key_checks = %v", enabled)
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Host() string {
+	return "fake"
+}
+
+func (c *fakeConn) Schema() string {
+	return "fake"
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"sort"
+)
+
+// sortTables - sorts tables so that every table comes after the tables it references.
+// Returns true as second value when the references between the given tables contain
+// a cycle; the tables caught in the cycle are appended at the end in name order.
+func sortTables(tables []string, deps map[string][]string) ([]string, bool) {
+	names := make([]string, len(tables))
+	copy(names, tables)
+	sort.Strings(names)
+
+	set := make(map[string]bool, len(names))
+	for _, name := range names {
+		set[name] = true
+	}
+
+	cyclic := false
+	pending := make(map[string]int, len(names))
+	dependents := make(map[string][]string, len(names))
+	for _, name := range names {
+		for _, dep := range deps[name] {
+			if dep == name {
+				cyclic = true
+				continue
+			}
+
+			if !set[dep] {
+				continue
+			}
+
+			pending[name]++
+			dependents[dep] = append(dependents[dep], name)
+		}
+	}
+
+	var queue []string
+	for _, name := range names {
+		if pending[name] == 0 {
+			queue = append(queue, name)
+		}
+	}
+
+	sorted := make([]string, 0, len(names))
+	for len(queue) > 0 {
+		name := queue[0]
+		queue = queue[1:]
+		sorted = append(sorted, name)
+
+		for _, dependent := range dependents[name] {
+			pending[dependent]--
+			if pending[dependent] == 0 {
+				queue = append(queue, dependent)
+			}
+		}
+	}
+
+	if len(sorted) == len(names) {
+		return sorted, cyclic
+	}
+
+	for _, name := range names {
+		if pending[name] > 0 {
+			sorted = append(sorted, name)
+		}
+	}
+
+	return sorted, true
+}
+
+// tableLevels - groups tables so that every table only references tables from previous groups.
+// Tables caught in a foreign key cycle are placed together in the last group.
+func tableLevels(tables []string, deps map[string][]string) [][]string {
+	set := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		set[table] = true
+	}
+
+	sorted, _ := sortTables(tables, deps)
+	level := make(map[string]int, len(sorted))
+	var levels [][]string
+	for _, table := range sorted {
+		l := 0
+		for _, dep := range deps[table] {
+			if dep == table || !set[dep] {
+				continue
+			}
+
+			depLevel, ok := level[dep]
+			if !ok {
+				// dependency not placed yet, so the table is part of a cycle
+				l = -1
+				break
+			}
+
+			if depLevel+1 > l {
+				l = depLevel + 1
+			}
+		}
+
+		if l < 0 {
+			continue
+		}
+
+		level[table] = l
+		if l == len(levels) {
+			levels = append(levels, nil)
+		}
+
+		levels[l] = append(levels[l], table)
+	}
+
+	var cyclic []string
+	for _, table := range sorted {
+		if _, ok := level[table]; !ok {
+			cyclic = append(cyclic, table)
+		}
+	}
+
+	if len(cyclic) > 0 {
+		levels = append(levels, cyclic)
+	}
+
+	return levels
+}
+
+// reverseTables - returns tables in reverse order
+func reverseTables(tables []string) []string {
+	reversed := make([]string, len(tables))
+	for i, table := range tables {
+		reversed[len(tables)-1-i] = table
+	}
+
+	return reversed
+}
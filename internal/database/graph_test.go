@@ -0,0 +1,142 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSortTables(t *testing.T) {
+	tests := []struct {
+		name       string
+		tables     []string
+		deps       map[string][]string
+		want       []string
+		wantCyclic bool
+	}{
+		{
+			name:   "no references, name order",
+			tables: []string{"users", "orders", "logs"},
+			want:   []string{"logs", "orders", "users"},
+		},
+		{
+			name:   "referenced tables first",
+			tables: []string{"order_items", "orders", "products", "users"},
+			deps: map[string][]string{
+				"orders":      {"users"},
+				"order_items": {"orders", "products"},
+			},
+			want: []string{"products", "users", "orders", "order_items"},
+		},
+		{
+			name:   "references to other tables ignored",
+			tables: []string{"orders"},
+			deps:   map[string][]string{"orders": {"users"}},
+			want:   []string{"orders"},
+		},
+		{
+			name:       "self reference",
+			tables:     []string{"employees", "teams"},
+			deps:       map[string][]string{"employees": {"employees", "teams"}},
+			want:       []string{"teams", "employees"},
+			wantCyclic: true,
+		},
+		{
+			name:   "cycle appended in name order",
+			tables: []string{"b", "a", "c", "d"},
+			deps: map[string][]string{
+				"a": {"b"},
+				"b": {"a"},
+				"c": {"d"},
+			},
+			want:       []string{"d", "c", "a", "b"},
+			wantCyclic: true,
+		},
+		{
+			name:   "tables referencing a cycle come with it",
+			tables: []string{"a", "b", "c"},
+			deps: map[string][]string{
+				"a": {"b"},
+				"b": {"a"},
+				"c": {"a"},
+			},
+			want:       []string{"a", "b", "c"},
+			wantCyclic: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, cyclic := sortTables(tt.tables, tt.deps)
+			if !reflect.DeepEqual(got, tt.want) || cyclic != tt.wantCyclic {
+				t.Errorf("sortTables() = %v, %v, want %v, %v", got, cyclic, tt.want, tt.wantCyclic)
+			}
+		})
+	}
+}
+
+func TestSortTablesKeepsInput(t *testing.T) {
+	tables := []string{"b", "a"}
+	sortTables(tables, nil)
+
+	if !reflect.DeepEqual(tables, []string{"b", "a"}) {
+		t.Errorf("sortTables() modified its input: %v", tables)
+	}
+}
+
+func TestTableLevels(t *testing.T) {
+	tests := []struct {
+		name   string
+		tables []string
+		deps   map[string][]string
+		want   [][]string
+	}{
+		{
+			name: "no tables",
+		},
+		{
+			name:   "no references, a single level",
+			tables: []string{"b", "a"},
+			want:   [][]string{{"a", "b"}},
+		},
+		{
+			name:   "level after the deepest reference",
+			tables: []string{"order_items", "orders", "products", "users"},
+			deps: map[string][]string{
+				"orders":      {"users"},
+				"order_items": {"orders", "products"},
+			},
+			want: [][]string{{"products", "users"}, {"orders"}, {"order_items"}},
+		},
+		{
+			name:   "self reference in its own level",
+			tables: []string{"employees", "teams"},
+			deps:   map[string][]string{"employees": {"employees", "teams"}},
+			want:   [][]string{{"teams"}, {"employees"}},
+		},
+		{
+			name:   "cycles in the last level",
+			tables: []string{"a", "b", "c", "d", "e"},
+			deps: map[string][]string{
+				"a": {"b"},
+				"b": {"a"},
+				"c": {"a"},
+				"e": {"d"},
+			},
+			want: [][]string{{"d"}, {"e"}, {"a", "b", "c"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tableLevels(tt.tables, tt.deps); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("tableLevels() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReverseTables(t *testing.T) {
+	if got := reverseTables([]string{"a", "b", "c"}); !reflect.DeepEqual(got, []string{"c", "b", "a"}) {
+		t.Errorf("reverseTables() = %v", got)
+	}
+}
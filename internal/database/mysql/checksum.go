@@ -0,0 +1,196 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+// errUnknownSystemVariable - ER_UNKNOWN_SYSTEM_VARIABLE
+const errUnknownSystemVariable = 1193
+
+// ChecksumStrategy - returns the configured checksum strategy
+func (conn *Connection) ChecksumStrategy() database.ChecksumStrategy {
+	if conn.cfg.Checksum == "" {
+		return database.ChecksumContent
+	}
+
+	return conn.cfg.Checksum
+}
+
+// QuickChecksums - returns cheap fingerprints of the given tables. Tables which cannot be
+// fingerprinted, like filtered tables, are left out. Returns nil for the content strategy.
+func (conn *Connection) QuickChecksums(ctx context.Context, strategy database.ChecksumStrategy, names ...string) (map[string]string, error) {
+	var tables []string
+	for _, name := range names {
+		if _, ok := conn.cfg.Filter(name); !ok {
+			tables = append(tables, name)
+		}
+	}
+
+	if len(tables) == 0 {
+		return map[string]string{}, nil
+	}
+
+	switch strategy {
+	case database.ChecksumNative:
+		return conn.nativeChecksums(ctx, tables)
+	case database.ChecksumMetadata:
+		return conn.metadataChecksums(ctx, tables)
+	}
+
+	return nil, nil
+}
+
+func (conn *Connection) nativeChecksums(ctx context.Context, tables []string) (map[string]string, error) {
+	rows, err := conn.db.QueryContext(ctx, fmt.Sprintf("checksum table `%s`", strings.Join(tables, "`, `")))
+	if err != nil {
+		return nil, fmt.Errorf("Native Checksums: %s", err)
+	}
+	defer rows.Close()
+
+	prefix := conn.cfg.Schema + "."
+	chks := make(map[string]string, len(tables))
+	for rows.Next() {
+		var table string
+		var checksum sql.NullString
+		if err := rows.Scan(&table, &checksum); err != nil {
+			return nil, fmt.Errorf("Native Checksums: %s", err)
+		}
+
+		if !checksum.Valid {
+			continue
+		}
+
+		chks[strings.TrimPrefix(table, prefix)] = checksum.String
+	}
+
+	return chks, rows.Err()
+}
+
+// metadataChecksums - returns the row counts, data lengths and update times of the given tables.
+// Tables never updated since the server started are left out: InnoDB does not persist the update
+// time, which is NULL after a restart until the table is updated again.
+func (conn *Connection) metadataChecksums(ctx context.Context, tables []string) (map[string]string, error) {
+	c, err := conn.statisticsConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Metadata Checksums: %s", err)
+	}
+
+	// stale statistics would hide the latest changes, so every table is compared by content
+	if c == nil {
+		return map[string]string{}, nil
+	}
+	defer c.Close()
+
+	rows, err := c.QueryContext(ctx,
+		"select `TABLE_NAME`, `TABLE_ROWS`, `DATA_LENGTH`, unix_timestamp(`UPDATE_TIME`) "+
+			"from `information_schema`.`TABLES` where `TABLE_SCHEMA` = ? and `TABLE_TYPE` = 'BASE TABLE'",
+		conn.cfg.Schema,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Metadata Checksums: %s", err)
+	}
+	defer rows.Close()
+
+	wanted := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		wanted[table] = true
+	}
+
+	chks := make(map[string]string, len(tables))
+	for rows.Next() {
+		var table string
+		var count, length, updated sql.NullInt64
+		if err := rows.Scan(&table, &count, &length, &updated); err != nil {
+			return nil, fmt.Errorf("Metadata Checksums: %s", err)
+		}
+
+		if !wanted[table] || !count.Valid || !length.Valid || !updated.Valid {
+			continue
+		}
+
+		chks[table] = database.MetadataChecksum(count.Int64, length.Int64, time.Unix(updated.Int64, 0))
+	}
+
+	return chks, rows.Err()
+}
+
+// statisticsConn - returns a connection reading fresh table statistics from information_schema.
+// MySQL 8.0 caches them for information_schema_stats_expiry seconds, a day by default, so the cache
+// is disabled for the session. Returns nil when it can't be.
+func (conn *Connection) statisticsConn(ctx context.Context) (*sql.Conn, error) {
+	c, err := conn.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := c.ExecContext(ctx, "set session information_schema_stats_expiry = 0"); err != nil {
+		// servers before 8.0 and MariaDB do not cache the statistics
+		if e, ok := err.(*mysql.MySQLError); !ok || e.Number != errUnknownSystemVariable {
+			c.Close()
+			return nil, nil
+		}
+	}
+
+	return c, nil
+}
+
+// Fingerprints - returns the creation and last update times, row count and data length of the given
+// tables. Tables never updated since the server started, or updated during the last seconds, which
+// the second precision of the update time cannot tell apart from later updates, are left out.
+// InnoDB does not persist the update time: after a restart it is NULL until the table is updated
+// again, so every table is compared again by the next sync.
+//
+// When the table statistics can't be read fresh, no fingerprints are returned rather than stale ones.
+func (conn *Connection) Fingerprints(ctx context.Context, tables ...string) (map[string]string, error) {
+	c, err := conn.statisticsConn(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Fingerprints: %s", err)
+	}
+
+	if c == nil {
+		return nil, nil
+	}
+	defer c.Close()
+
+	rows, err := c.QueryContext(ctx,
+		"select `TABLE_NAME`, `CREATE_TIME`, `UPDATE_TIME`, `TABLE_ROWS`, `DATA_LENGTH`, "+
+			"`UPDATE_TIME` >= now() - interval 2 second from `information_schema`.`TABLES` "+
+			"where `TABLE_SCHEMA` = ? and `TABLE_TYPE` = 'BASE TABLE'",
+		conn.cfg.Schema,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Fingerprints: %s", err)
+	}
+	defer rows.Close()
+
+	wanted := make(map[string]bool, len(tables))
+	for _, table := range tables {
+		wanted[table] = true
+	}
+
+	fps := make(map[string]string, len(tables))
+	for rows.Next() {
+		var table string
+		var created, updated sql.NullString
+		var count, length sql.NullInt64
+		var recent sql.NullBool
+		if err := rows.Scan(&table, &created, &updated, &count, &length, &recent); err != nil {
+			return nil, fmt.Errorf("Fingerprints: %s", err)
+		}
+
+		if !wanted[table] || !updated.Valid || recent.Bool {
+			continue
+		}
+
+		fps[table] = fmt.Sprintf("%s:%s:%d:%d", created.String, updated.String, count.Int64, length.Int64)
+	}
+
+	return fps, rows.Err()
+}
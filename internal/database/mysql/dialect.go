@@ -0,0 +1,35 @@
+package mysql
+
+import (
+	"fmt"
+
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+// DropTableStatement - returns the statement dropping a table
+func (conn *Connection) DropTableStatement(table string) string {
+	return fmt.Sprintf("drop table if exists `%s`", table)
+}
+
+// ForeignKeyChecksStatement - returns the statement enabling or disabling the foreign key checks
+func (conn *Connection) ForeignKeyChecksStatement(enabled bool) string {
+	if enabled {
+		return "set foreign_key_checks = 1"
+	}
+
+	return "set foreign_key_checks = 0"
+}
+
+// DropObjectStatement - returns the statement dropping an object
+func (conn *Connection) DropObjectStatement(o database.Object) string {
+	return fmt.Sprintf("drop %s if exists `%s`", o.Type, o.Name)
+}
+
+// CreateObjectStatement - returns the statement creating an object
+func (conn *Connection) CreateObjectStatement(o database.Object) string {
+	if o.Type == database.ObjectView {
+		return o.Definition + ";"
+	}
+
+	return fmt.Sprintf("DELIMITER ;;\n%s ;;\nDELIMITER ;", o.Definition)
+}
@@ -1,30 +1,241 @@
 package mysql
 
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
 // Dumper - dumps database sql
 type Dumper struct {
-	cfg ConnectionConfig
+	cfg    database.ConnectionConfig
+	masker Masker
 }
 
 // NewDumper - constructor
-func NewDumper(cfg ConnectionConfig) *Dumper {
+func NewDumper(cfg database.ConnectionConfig) *Dumper {
 	return &Dumper{
 		cfg: cfg,
 	}
 }
 
-// DumpTables - dump tables sql
-func (d *Dumper) DumpTables(tables ...string) (string, error) {
+// SetMasker - masks the rows of the dumped tables before they leave the dumper
+func (d *Dumper) SetMasker(m Masker) {
+	d.masker = m
+}
+
+// DumpTables - dump tables sql. Filtered tables only include the rows matching their filter.
+func (d *Dumper) DumpTables(ctx context.Context, tables ...string) (string, error) {
+	var unfiltered []string
+	for _, table := range tables {
+		if _, ok := d.cfg.Filter(table); !ok {
+			unfiltered = append(unfiltered, table)
+		}
+	}
+
+	var out string
+	if len(unfiltered) > 0 {
+		dump, err := d.dump(ctx, unfiltered, nil)
+		if err != nil {
+			return "", err
+		}
+
+		out += dump
+	}
+
+	for _, table := range tables {
+		batches, ok := d.cfg.Batches(table)
+		if !ok {
+			continue
+		}
+
+		dump, err := d.dumpBatches(ctx, table, batches, nil)
+		if err != nil {
+			return "", err
+		}
+
+		out += dump
+	}
+
+	return compressMySQLDump(out), nil
+}
+
+// RefreshTables - dump sql which deletes the rows of the filtered tables matching their
+// filter and inserts them again, keeping the table and the rest of its rows. Rows are
+// written as REPLACE statements, as a master row may now match the filter while its key
+// is still used by a slave row outside of it.
+func (d *Dumper) RefreshTables(ctx context.Context, tables ...string) (string, error) {
+	var out string
+	for _, table := range tables {
+		filter, ok := d.cfg.Filter(table)
+		if !ok {
+			return "", fmt.Errorf("refresh %s: table has no filter", table)
+		}
+
+		batches, _ := d.cfg.Batches(table)
+		dump, err := d.dumpBatches(ctx, table, batches, []string{"--no-create-info"})
+		if err != nil {
+			return "", err
+		}
+
+		out += GenerateDeleteStatement(table, filter) + ReplaceInserts(dump)
+	}
+
+	return compressMySQLDump(out), nil
+}
+
+// dumpBatches - dumps the rows of a filtered table batch by batch, the batches after the first
+// one without create statement
+func (d *Dumper) dumpBatches(ctx context.Context, table string, batches []string, options []string) (string, error) {
+	var out string
+	for i, batch := range batches {
+		dump, err := d.dump(ctx, []string{table}, batchOptions(options, batch, i))
+		if err != nil {
+			return "", err
+		}
+
+		out += dump
+	}
+
+	return out, nil
+}
+
+func (d *Dumper) dump(ctx context.Context, tables []string, options []string) (string, error) {
 	out, err := mysqlDump(
+		ctx,
 		d.cfg.Username,
 		d.cfg.Password,
 		d.cfg.Host,
 		d.cfg.Port,
 		d.cfg.Schema,
+		append(d.options(tables...), options...),
 		tables...,
 	)
 	if err != nil {
 		return "", err
 	}
 
-	return compressMySQLDump(out), nil
+	if d.masks(tables...) {
+		return maskDump(out, d.masker)
+	}
+
+	return out, nil
+}
+
+// DumpTableTo - streams the dump of a single table to w
+func (d *Dumper) DumpTableTo(ctx context.Context, w io.Writer, table string) error {
+	if batches, ok := d.cfg.Batches(table); ok {
+		return d.dumpBatchesTo(ctx, w, table, batches, nil)
+	}
+
+	return d.dumpTableTo(ctx, w, table, nil)
+}
+
+// DumpTableSchemaTo - streams the create statement of a single table to w
+func (d *Dumper) DumpTableSchemaTo(ctx context.Context, w io.Writer, table string) error {
+	return d.mysqlDumpTo(ctx, w, table, []string{"--no-data"})
+}
+
+// DumpTableDataTo - streams the insert statements of a single table to w
+func (d *Dumper) DumpTableDataTo(ctx context.Context, w io.Writer, table string) error {
+	options := []string{"--no-create-info"}
+	if batches, ok := d.cfg.Batches(table); ok {
+		return d.dumpBatchesTo(ctx, w, table, batches, options)
+	}
+
+	return d.dumpTableTo(ctx, w, table, options)
+}
+
+// RefreshTableTo - streams the refresh sql of a single filtered table to w, see RefreshTables
+func (d *Dumper) RefreshTableTo(ctx context.Context, w io.Writer, table string) error {
+	filter, ok := d.cfg.Filter(table)
+	if !ok {
+		return fmt.Errorf("refresh %s: table has no filter", table)
+	}
+
+	if _, err := io.WriteString(w, GenerateDeleteStatement(table, filter)); err != nil {
+		return err
+	}
+
+	batches, _ := d.cfg.Batches(table)
+	rw := NewReplaceWriter(w)
+	if err := d.dumpBatchesTo(ctx, rw, table, batches, []string{"--no-create-info"}); err != nil {
+		return err
+	}
+
+	return rw.Close()
+}
+
+// dumpBatchesTo - streams the dump of the rows of a filtered table batch by batch, see dumpBatches
+func (d *Dumper) dumpBatchesTo(ctx context.Context, w io.Writer, table string, batches []string, options []string) error {
+	for i, batch := range batches {
+		if err := d.dumpTableTo(ctx, w, table, batchOptions(options, batch, i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// batchOptions - returns the mysqldump options of the i-th batch of a filtered table
+func batchOptions(options []string, batch string, i int) []string {
+	options = append(append([]string{}, options...), "--where="+batch)
+	if i > 0 {
+		options = append(options, "--no-create-info")
+	}
+
+	return options
+}
+
+func (d *Dumper) dumpTableTo(ctx context.Context, w io.Writer, table string, options []string) error {
+	if !d.masks(table) {
+		return d.mysqlDumpTo(ctx, w, table, options)
+	}
+
+	mw := newMaskWriter(w, d.masker)
+	if err := d.mysqlDumpTo(ctx, mw, table, options); err != nil {
+		return err
+	}
+
+	return mw.Close()
+}
+
+func (d *Dumper) mysqlDumpTo(ctx context.Context, w io.Writer, table string, options []string) error {
+	return mysqlDumpTo(
+		ctx,
+		w,
+		d.cfg.Username,
+		d.cfg.Password,
+		d.cfg.Host,
+		d.cfg.Port,
+		d.cfg.Schema,
+		append(d.options(table), options...),
+		table,
+	)
+}
+
+// masks - returns true when rows of any of the tables are masked
+func (d *Dumper) masks(tables ...string) bool {
+	if d.masker == nil {
+		return false
+	}
+
+	for _, table := range tables {
+		if d.masker.MasksTable(table) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (d *Dumper) options(tables ...string) []string {
+	options := compressionOptions(d.cfg.Compression)
+	if d.masks(tables...) {
+		options = append(options, "--complete-insert")
+	}
+
+	return options
 }
@@ -1,27 +1,61 @@
 package mysql
 
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/vcraescu/dbsync/internal/compress"
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
 // Importer - importer class
 type Importer struct {
-	cfg ConnectionConfig
+	cfg database.ConnectionConfig
 }
 
 // NewImporter - import constructor
-func NewImporter(cfg ConnectionConfig) *Importer {
+func NewImporter(cfg database.ConnectionConfig) *Importer {
 	return &Importer{
 		cfg: cfg,
 	}
 }
 
 // Import - import sql dump
-func (imp *Importer) Import(dump string) error {
+func (imp *Importer) Import(ctx context.Context, dump string) error {
 	_, err := mysqlImport(
+		ctx,
 		imp.cfg.Username,
 		imp.cfg.Password,
 		imp.cfg.Host,
 		imp.cfg.Port,
 		imp.cfg.Schema,
+		compressionOptions(imp.cfg.Compression),
 		dump,
 	)
 
 	return err
 }
+
+// ImportFrom - import sql dump read from r; gzip and zstd compressed dumps are
+// detected by their magic bytes and decompressed
+func (imp *Importer) ImportFrom(ctx context.Context, r io.Reader) error {
+	zr, err := compress.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("Import: %s", err)
+	}
+	defer zr.Close()
+
+	_, err = mysqlImportFrom(
+		ctx,
+		zr,
+		imp.cfg.Username,
+		imp.cfg.Password,
+		imp.cfg.Host,
+		imp.cfg.Port,
+		imp.cfg.Schema,
+		compressionOptions(imp.cfg.Compression),
+	)
+
+	return err
+}
@@ -0,0 +1,345 @@
+package mysql
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const insertPrefix = "INSERT INTO `"
+
+// Masker - masks column values of the rows flowing from the dumper to the importer
+type Masker interface {
+	// MasksTable - returns true when some columns of the table are masked
+	MasksTable(table string) bool
+	// Mask - returns the masked value of a table column, or value itself when the column
+	// is not masked; nil stands for NULL
+	Mask(table, column string, value *string) *string
+}
+
+// sqlValue - value of an INSERT statement tuple
+type sqlValue struct {
+	// raw - literal as written by mysqldump
+	raw string
+	// text - unescaped value of string literals, or the literal itself otherwise
+	text string
+	null bool
+}
+
+// insertStatement - extended INSERT statement written by mysqldump with --complete-insert
+type insertStatement struct {
+	table   string
+	columns []string
+	rows    [][]sqlValue
+}
+
+// parseInsert - parses an INSERT statement line. Returns false when the line is not an INSERT.
+func parseInsert(line string) (*insertStatement, bool, error) {
+	if !strings.HasPrefix(line, insertPrefix) {
+		return nil, false, nil
+	}
+
+	stmt := &insertStatement{}
+	rest := line[len(insertPrefix):]
+	i := strings.IndexByte(rest, '`')
+	if i < 0 {
+		return nil, true, fmt.Errorf("malformed insert: %.64s", line)
+	}
+	stmt.table = rest[:i]
+	rest = rest[i+1:]
+
+	if !strings.HasPrefix(rest, " (") {
+		return nil, true, fmt.Errorf("insert into %s: column names missing, --complete-insert required", stmt.table)
+	}
+
+	i = strings.Index(rest, ") VALUES ")
+	if i < 0 {
+		return nil, true, fmt.Errorf("insert into %s: malformed column list", stmt.table)
+	}
+
+	for _, col := range strings.Split(rest[2:i], ",") {
+		stmt.columns = append(stmt.columns, strings.Trim(col, " `"))
+	}
+	rest = rest[i+len(") VALUES "):]
+
+	for len(rest) > 0 && rest[0] == '(' {
+		row, n, err := parseTuple(rest)
+		if err != nil {
+			return nil, true, fmt.Errorf("insert into %s: %s", stmt.table, err)
+		}
+
+		stmt.rows = append(stmt.rows, row)
+		rest = rest[n:]
+		if len(rest) > 0 && rest[0] == ',' {
+			rest = rest[1:]
+		}
+	}
+
+	if rest != ";" {
+		return nil, true, fmt.Errorf("insert into %s: unexpected trailing %.32q", stmt.table, rest)
+	}
+
+	return stmt, true, nil
+}
+
+// parseTuple - parses a parenthesized value list and returns the number of bytes consumed
+func parseTuple(s string) ([]sqlValue, int, error) {
+	var row []sqlValue
+	i := 1
+	for i < len(s) {
+		start := i
+		var v sqlValue
+
+		if strings.HasPrefix(s[i:], "_binary '") {
+			i += len("_binary ")
+		}
+
+		if s[i] == '\'' {
+			text, n, err := Unquote(s[i:])
+			if err != nil {
+				return nil, 0, err
+			}
+			i += n
+			v.text = text
+		} else {
+			for i < len(s) && s[i] != ',' && s[i] != ')' {
+				i++
+			}
+			v.text = s[start:i]
+			v.null = v.text == "NULL"
+		}
+
+		v.raw = s[start:i]
+		row = append(row, v)
+
+		if i >= len(s) {
+			break
+		}
+
+		if s[i] == ')' {
+			return row, i + 1, nil
+		}
+
+		i++
+	}
+
+	return nil, 0, fmt.Errorf("unterminated row")
+}
+
+// Unquote - unescapes a single quoted mysql string literal and returns the number of bytes consumed
+func Unquote(s string) (string, int, error) {
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		switch c {
+		case '\'':
+			return b.String(), i + 1, nil
+		case '\\':
+			i++
+			if i >= len(s) {
+				break
+			}
+
+			switch s[i] {
+			case '0':
+				b.WriteByte(0)
+			case 'b':
+				b.WriteByte('\b')
+			case 'n':
+				b.WriteByte('\n')
+			case 'r':
+				b.WriteByte('\r')
+			case 't':
+				b.WriteByte('\t')
+			case 'Z':
+				b.WriteByte(26)
+			default:
+				b.WriteByte(s[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", 0, fmt.Errorf("unterminated string")
+}
+
+// quote - escapes a value as a single quoted string literal the same way mysqldump does
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('\'')
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; c {
+		case 0:
+			b.WriteString(`\0`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			b.WriteString(`\r`)
+		case 26:
+			b.WriteString(`\Z`)
+		case '\'', '"', '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('\'')
+
+	return b.String()
+}
+
+func (stmt *insertStatement) String() string {
+	var b strings.Builder
+	b.WriteString(insertPrefix)
+	b.WriteString(stmt.table)
+	b.WriteString("` (`")
+	b.WriteString(strings.Join(stmt.columns, "`, `"))
+	b.WriteString("`) VALUES ")
+	for i, row := range stmt.rows {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+
+		b.WriteByte('(')
+		for j, v := range row {
+			if j > 0 {
+				b.WriteByte(',')
+			}
+
+			b.WriteString(v.raw)
+		}
+		b.WriteByte(')')
+	}
+	b.WriteByte(';')
+
+	return b.String()
+}
+
+// maskInsert - masks the values of an INSERT statement line; other lines are returned unchanged
+func maskInsert(line string, m Masker) (string, error) {
+	if !strings.HasPrefix(line, insertPrefix) {
+		return line, nil
+	}
+
+	table := line[len(insertPrefix):]
+	if i := strings.IndexByte(table, '`'); i >= 0 {
+		table = table[:i]
+	}
+
+	if !m.MasksTable(table) {
+		return line, nil
+	}
+
+	stmt, _, err := parseInsert(line)
+	if err != nil {
+		return "", err
+	}
+
+	for _, row := range stmt.rows {
+		if len(row) != len(stmt.columns) {
+			return "", fmt.Errorf("insert into %s: %d values for %d columns", table, len(row), len(stmt.columns))
+		}
+
+		for i, col := range stmt.columns {
+			var value *string
+			if !row[i].null {
+				text := row[i].text
+				value = &text
+			}
+
+			masked := m.Mask(table, col, value)
+			if masked == value {
+				continue
+			}
+
+			if masked == nil {
+				row[i] = sqlValue{raw: "NULL", text: "NULL", null: true}
+				continue
+			}
+
+			row[i] = sqlValue{raw: quote(*masked), text: *masked}
+		}
+	}
+
+	return stmt.String(), nil
+}
+
+// maskDump - masks every INSERT statement of a dump
+func maskDump(dump string, m Masker) (string, error) {
+	lines := strings.Split(dump, "\n")
+	for i, line := range lines {
+		masked, err := maskInsert(line, m)
+		if err != nil {
+			return "", err
+		}
+
+		lines[i] = masked
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// maskWriter - masks the INSERT statements of a dump stream line by line
+type maskWriter struct {
+	w      io.Writer
+	masker Masker
+	line   bytes.Buffer
+}
+
+func newMaskWriter(w io.Writer, m Masker) *maskWriter {
+	return &maskWriter{
+		w:      w,
+		masker: m,
+	}
+}
+
+func (mw *maskWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			mw.line.Write(p)
+			break
+		}
+
+		mw.line.Write(p[:i+1])
+		p = p[i+1:]
+		if err := mw.flushLine(); err != nil {
+			return 0, err
+		}
+	}
+
+	return n, nil
+}
+
+// Close - writes the last unterminated line
+func (mw *maskWriter) Close() error {
+	return mw.flushLine()
+}
+
+func (mw *maskWriter) flushLine() error {
+	if mw.line.Len() == 0 {
+		return nil
+	}
+
+	line := mw.line.String()
+	mw.line.Reset()
+
+	eol := ""
+	if strings.HasSuffix(line, "\n") {
+		line = line[:len(line)-1]
+		eol = "\n"
+	}
+
+	masked, err := maskInsert(line, mw.masker)
+	if err != nil {
+		return err
+	}
+
+	_, err = io.WriteString(mw.w, masked+eol)
+
+	return err
+}
@@ -0,0 +1,230 @@
+package mysql
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseInsert(t *testing.T) {
+	tests := []struct {
+		name     string
+		line     string
+		isInsert bool
+		want     *insertStatement
+		wantErr  string
+	}{
+		{
+			name: "not an insert",
+			line: "LOCK TABLES `users` WRITE;",
+		},
+		{
+			name:     "extended insert",
+			line:     "INSERT INTO `users` (`id`, `name`, `email`) VALUES (1,'Ann',NULL),(2,'O\\'Brien','ob@x.io');",
+			isInsert: true,
+			want: &insertStatement{
+				table:   "users",
+				columns: []string{"id", "name", "email"},
+				rows: [][]sqlValue{
+					{{raw: "1", text: "1"}, {raw: "'Ann'", text: "Ann"}, {raw: "NULL", text: "NULL", null: true}},
+					{{raw: "2", text: "2"}, {raw: `'O\'Brien'`, text: "O'Brien"}, {raw: "'ob@x.io'", text: "ob@x.io"}},
+				},
+			},
+		},
+		{
+			name:     "separators and escapes in strings",
+			line:     "INSERT INTO `t` (`a`, `b`) VALUES ('x),(y','line\\nbreak\\\\');",
+			isInsert: true,
+			want: &insertStatement{
+				table:   "t",
+				columns: []string{"a", "b"},
+				rows: [][]sqlValue{
+					{{raw: "'x),(y'", text: "x),(y"}, {raw: `'line\nbreak\\'`, text: "line\nbreak\\"}},
+				},
+			},
+		},
+		{
+			name:     "binary strings",
+			line:     "INSERT INTO `t` (`b`) VALUES (_binary 'ab\\0');",
+			isInsert: true,
+			want: &insertStatement{
+				table:   "t",
+				columns: []string{"b"},
+				rows:    [][]sqlValue{{{raw: `_binary 'ab\0'`, text: "ab\x00"}}},
+			},
+		},
+		{
+			name:     "without column names",
+			line:     "INSERT INTO `t` VALUES (1);",
+			isInsert: true,
+			wantErr:  "insert into t: column names missing, --complete-insert required",
+		},
+		{
+			name:     "unterminated string",
+			line:     "INSERT INTO `t` (`a`) VALUES ('abc);",
+			isInsert: true,
+			wantErr:  "insert into t: unterminated string",
+		},
+		{
+			name:     "trailing garbage",
+			line:     "INSERT INTO `t` (`a`) VALUES (1) x",
+			isInsert: true,
+			wantErr:  `insert into t: unexpected trailing " x"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, isInsert, err := parseInsert(tt.line)
+			if isInsert != tt.isInsert {
+				t.Fatalf("parseInsert() insert = %v, want %v", isInsert, tt.isInsert)
+			}
+
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("parseInsert() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil {
+				t.Fatalf("parseInsert() error = %s", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("parseInsert() = %+v, want %+v", got, tt.want)
+			}
+
+			if got != nil && got.String() != tt.line {
+				t.Errorf("String() = %q, want the parsed line %q", got.String(), tt.line)
+			}
+		})
+	}
+}
+
+func TestQuoteUnquote(t *testing.T) {
+	for _, s := range []string{"", "plain", "it's", `back\slash`, "a\"b", "nul\x00", "cr\r\nlf", "ctrl-z\x1a"} {
+		quoted := quote(s)
+		got, n, err := Unquote(quoted)
+		if err != nil || got != s || n != len(quoted) {
+			t.Errorf("Unquote(quote(%q)) = %q, %d, %v", s, got, n, err)
+		}
+	}
+}
+
+// testMasker - masks the columns of its rules with their value, nil masking with NULL
+type testMasker map[string]map[string]*string
+
+func (m testMasker) MasksTable(table string) bool {
+	return len(m[table]) > 0
+}
+
+func (m testMasker) Mask(table, column string, value *string) *string {
+	masked, ok := m[table][column]
+	if !ok {
+		return value
+	}
+
+	return masked
+}
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestMaskInsert(t *testing.T) {
+	m := testMasker{
+		"users": {
+			"email": strPtr("x'@example.com"),
+			"phone": nil,
+		},
+	}
+
+	tests := []struct {
+		name    string
+		line    string
+		want    string
+		wantErr string
+	}{
+		{
+			name: "other statement",
+			line: "CREATE TABLE `users` (",
+			want: "CREATE TABLE `users` (",
+		},
+		{
+			name: "table not masked",
+			line: "INSERT INTO `orders` (`id`, `email`) VALUES (1,'a@b.c');",
+			want: "INSERT INTO `orders` (`id`, `email`) VALUES (1,'a@b.c');",
+		},
+		{
+			name: "masked columns",
+			line: "INSERT INTO `users` (`id`, `email`, `phone`) VALUES (1,'a@b.c','123'),(2,NULL,NULL);",
+			want: "INSERT INTO `users` (`id`, `email`, `phone`) VALUES " +
+				`(1,'x\'@example.com',NULL),(2,'x\'@example.com',NULL);`,
+		},
+		{
+			name:    "values not matching the columns",
+			line:    "INSERT INTO `users` (`id`, `email`) VALUES (1);",
+			wantErr: "insert into users: 1 values for 2 columns",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := maskInsert(tt.line, m)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Fatalf("maskInsert() error = %v, want %q", err, tt.wantErr)
+				}
+
+				return
+			}
+
+			if err != nil || got != tt.want {
+				t.Errorf("maskInsert() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskWriter(t *testing.T) {
+	m := testMasker{"users": {"email": strPtr("masked")}}
+	dump := "-- dump\n" +
+		"INSERT INTO `users` (`id`, `email`) VALUES (1,'a@b.c'),(2,'d@e.f');\n" +
+		"INSERT INTO `orders` (`id`) VALUES (1);"
+	want, err := maskDump(dump, m)
+	if err != nil {
+		t.Fatalf("maskDump() error = %s", err)
+	}
+
+	if strings.Contains(want, "@") {
+		t.Fatalf("maskDump() = %q, emails not masked", want)
+	}
+
+	for _, size := range []int{1, 7, len(dump)} {
+		var out bytes.Buffer
+		mw := newMaskWriter(&out, m)
+		for p := []byte(dump); len(p) > 0; {
+			n := size
+			if n > len(p) {
+				n = len(p)
+			}
+
+			if _, err := mw.Write(p[:n]); err != nil {
+				t.Fatalf("Write() error = %s", err)
+			}
+
+			p = p[n:]
+		}
+
+		if err := mw.Close(); err != nil {
+			t.Fatalf("Close() error = %s", err)
+		}
+
+		if out.String() != want {
+			t.Errorf("writes of %d bytes: got %q, want %q", size, out.String(), want)
+		}
+	}
+}
@@ -1,33 +1,29 @@
 package mysql
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	"strings"
 
 	_ "github.com/go-sql-driver/mysql" // mysql
+	"github.com/vcraescu/dbsync/internal/database"
+	"github.com/vcraescu/dbsync/internal/logger"
 )
 
-const driverName = "mysql"
+// DriverName - name of the mysql driver
+const DriverName = "mysql"
 
-// ConnectionConfig - mysql connection config
-type ConnectionConfig struct {
-	Username string
-	Password string
-	Port     int
-	Host     string
-	Schema   string
-	Timezone string
-}
+const defaultMaxConnections = 4
 
 // Connection - mysql connection
 type Connection struct {
 	db  *sql.DB
-	cfg ConnectionConfig
+	cfg database.ConnectionConfig
 }
 
 // New - creates new mysql connection.
-func New(cfg ConnectionConfig) *Connection {
+func New(cfg database.ConnectionConfig) *Connection {
 	conn := &Connection{
 		cfg: cfg,
 	}
@@ -35,12 +31,34 @@ func New(cfg ConnectionConfig) *Connection {
 	return conn
 }
 
+// Driver - returns the driver name
+func (conn *Connection) Driver() string {
+	return DriverName
+}
+
+// Host - returns the server host
+func (conn *Connection) Host() string {
+	return conn.cfg.Host
+}
+
+// Schema - returns the schema name
+func (conn *Connection) Schema() string {
+	return conn.cfg.Schema
+}
+
+// Filter - returns the where condition of a filtered table
+func (conn *Connection) Filter(table string) (string, bool) {
+	filter, ok := conn.cfg.Filter(table)
+
+	return filter, ok
+}
+
 func (conn *Connection) isOpened() bool {
 	return conn.db != nil
 }
 
 // Open - open connection
-func (conn *Connection) Open() error {
+func (conn *Connection) Open(ctx context.Context) error {
 	if conn.isOpened() {
 		return nil
 	}
@@ -50,11 +68,29 @@ func (conn *Connection) Open() error {
 		return err
 	}
 
-	db, err := sql.Open(driverName, dsn)
+	logger.Debug("Connecting to mysql", "host", conn.cfg.Host, "schema", conn.cfg.Schema)
+	db, err := sql.Open(DriverName, dsn)
+	if err != nil {
+		return err
+	}
+
+	db.SetMaxOpenConns(conn.maxConnections())
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return err
+	}
 
 	conn.db = db
 
-	return err
+	return nil
+}
+
+func (conn *Connection) maxConnections() int {
+	if conn.cfg.MaxConnections <= 0 {
+		return defaultMaxConnections
+	}
+
+	return conn.cfg.MaxConnections
 }
 
 // Close - close mysql connection
@@ -66,9 +102,23 @@ func (conn *Connection) Close() error {
 	return conn.db.Close()
 }
 
-// TableNames - returns table names
-func (conn *Connection) TableNames() ([]string, error) {
-	rows, err := conn.db.Query("show tables")
+// TableNames - returns base table names
+func (conn *Connection) TableNames(ctx context.Context) ([]string, error) {
+	return conn.tableNamesByType(ctx, "BASE TABLE")
+}
+
+// ViewNames - returns view names
+func (conn *Connection) ViewNames(ctx context.Context) ([]string, error) {
+	return conn.tableNamesByType(ctx, "VIEW")
+}
+
+func (conn *Connection) tableNamesByType(ctx context.Context, tableType string) ([]string, error) {
+	rows, err := conn.db.QueryContext(ctx,
+		"select `TABLE_NAME` from `information_schema`.`TABLES` where `TABLE_SCHEMA` = ? and `TABLE_TYPE` = ? "+
+			"order by `TABLE_NAME`",
+		conn.cfg.Schema,
+		tableType,
+	)
 	if err != nil {
 		return nil, err
 	}
@@ -84,18 +134,199 @@ func (conn *Connection) TableNames() ([]string, error) {
 		names = append(names, name)
 	}
 
-	return names, nil
+	return names, rows.Err()
+}
+
+// TableDependencies - returns the tables referenced through foreign keys by every table
+func (conn *Connection) TableDependencies(ctx context.Context) (map[string][]string, error) {
+	rows, err := conn.db.QueryContext(ctx,
+		"select distinct `TABLE_NAME`, `REFERENCED_TABLE_NAME` from `information_schema`.`KEY_COLUMN_USAGE` "+
+			"where `TABLE_SCHEMA` = ? and `REFERENCED_TABLE_SCHEMA` = ? and `REFERENCED_TABLE_NAME` is not null "+
+			"order by `TABLE_NAME`, `REFERENCED_TABLE_NAME`",
+		conn.cfg.Schema,
+		conn.cfg.Schema,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Table Dependencies: %s", err)
+	}
+	defer rows.Close()
+
+	deps := make(map[string][]string)
+	for rows.Next() {
+		var table, referenced string
+		if err := rows.Scan(&table, &referenced); err != nil {
+			return nil, fmt.Errorf("Table Dependencies: %s", err)
+		}
+
+		deps[table] = append(deps[table], referenced)
+	}
+
+	return deps, rows.Err()
+}
+
+// Columns - returns the column names of every table
+func (conn *Connection) Columns(ctx context.Context) (map[string][]string, error) {
+	rows, err := conn.db.QueryContext(ctx,
+		"select `TABLE_NAME`, `COLUMN_NAME` from `information_schema`.`COLUMNS` where `TABLE_SCHEMA` = ? "+
+			"order by `TABLE_NAME`, `ORDINAL_POSITION`",
+		conn.cfg.Schema,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Columns: %s", err)
+	}
+	defer rows.Close()
+
+	columns := make(map[string][]string)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, fmt.Errorf("Columns: %s", err)
+		}
+
+		columns[table] = append(columns[table], column)
+	}
+
+	return columns, rows.Err()
+}
+
+// ForeignKey - foreign key between two tables of the schema
+type ForeignKey struct {
+	Name              string
+	Table             string
+	Columns           []string
+	ReferencedTable   string
+	ReferencedColumns []string
+}
+
+// ForeignKeys - returns the foreign keys between the tables of the schema
+func (conn *Connection) ForeignKeys(ctx context.Context) ([]ForeignKey, error) {
+	rows, err := conn.db.QueryContext(ctx,
+		"select `CONSTRAINT_NAME`, `TABLE_NAME`, `COLUMN_NAME`, `REFERENCED_TABLE_NAME`, `REFERENCED_COLUMN_NAME` "+
+			"from `information_schema`.`KEY_COLUMN_USAGE` "+
+			"where `TABLE_SCHEMA` = ? and `REFERENCED_TABLE_SCHEMA` = ? and `REFERENCED_TABLE_NAME` is not null "+
+			"order by `TABLE_NAME`, `CONSTRAINT_NAME`, `ORDINAL_POSITION`",
+		conn.cfg.Schema,
+		conn.cfg.Schema,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Foreign Keys: %s", err)
+	}
+	defer rows.Close()
+
+	var fks []ForeignKey
+	for rows.Next() {
+		var name, table, column, referencedTable, referencedColumn string
+		if err := rows.Scan(&name, &table, &column, &referencedTable, &referencedColumn); err != nil {
+			return nil, fmt.Errorf("Foreign Keys: %s", err)
+		}
+
+		if n := len(fks); n == 0 || fks[n-1].Table != table || fks[n-1].Name != name {
+			fks = append(fks, ForeignKey{
+				Name:            name,
+				Table:           table,
+				ReferencedTable: referencedTable,
+			})
+		}
+
+		fk := &fks[len(fks)-1]
+		fk.Columns = append(fk.Columns, column)
+		fk.ReferencedColumns = append(fk.ReferencedColumns, referencedColumn)
+	}
+
+	return fks, rows.Err()
+}
+
+// PrimaryKeys - returns the primary key columns of every table which has one
+func (conn *Connection) PrimaryKeys(ctx context.Context) (map[string][]string, error) {
+	rows, err := conn.db.QueryContext(ctx,
+		"select `TABLE_NAME`, `COLUMN_NAME` from `information_schema`.`KEY_COLUMN_USAGE` "+
+			"where `TABLE_SCHEMA` = ? and `CONSTRAINT_NAME` = 'PRIMARY' "+
+			"order by `TABLE_NAME`, `ORDINAL_POSITION`",
+		conn.cfg.Schema,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Primary Keys: %s", err)
+	}
+	defer rows.Close()
+
+	pks := make(map[string][]string)
+	for rows.Next() {
+		var table, column string
+		if err := rows.Scan(&table, &column); err != nil {
+			return nil, fmt.Errorf("Primary Keys: %s", err)
+		}
+
+		pks[table] = append(pks[table], column)
+	}
+
+	return pks, rows.Err()
+}
+
+// CountRows - returns the row counts of the given tables, filtered tables only counting their matching rows
+func (conn *Connection) CountRows(ctx context.Context, names ...string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(names))
+	for _, table := range names {
+		q := fmt.Sprintf("select count(*) from `%s`", table)
+		if filter, ok := conn.cfg.Filter(table); ok {
+			q += fmt.Sprintf(" where (%s)", filter)
+		}
+
+		var count int64
+		if err := conn.db.QueryRowContext(ctx, q).Scan(&count); err != nil {
+			return nil, fmt.Errorf("Count Rows (%s): %s", table, err)
+		}
+
+		counts[table] = count
+	}
+
+	return counts, nil
+}
+
+// EstimateRows - returns the row counts of the given tables estimated by the storage engine, which
+// are approximate for InnoDB. Filtered tables are left out.
+func (conn *Connection) EstimateRows(ctx context.Context, names ...string) (map[string]int64, error) {
+	rows, err := conn.db.QueryContext(ctx,
+		"select `TABLE_NAME`, `TABLE_ROWS` from `information_schema`.`TABLES` "+
+			"where `TABLE_SCHEMA` = ? and `TABLE_TYPE` = 'BASE TABLE'",
+		conn.cfg.Schema,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Estimate Rows: %s", err)
+	}
+	defer rows.Close()
+
+	wanted := make(map[string]bool, len(names))
+	for _, table := range names {
+		if _, ok := conn.cfg.Filter(table); !ok {
+			wanted[table] = true
+		}
+	}
+
+	counts := make(map[string]int64, len(wanted))
+	for rows.Next() {
+		var table string
+		var count sql.NullInt64
+		if err := rows.Scan(&table, &count); err != nil {
+			return nil, fmt.Errorf("Estimate Rows: %s", err)
+		}
+
+		if wanted[table] && count.Valid {
+			counts[table] = count.Int64
+		}
+	}
+
+	return counts, rows.Err()
 }
 
 // TableChecksum - returns table checksum
-func (conn *Connection) TableChecksum(table string) (string, error) {
-	rows, err := conn.db.Query(fmt.Sprintf("select * from %s limit 1", table))
+func (conn *Connection) TableChecksum(ctx context.Context, table string) (string, error) {
+	rows, err := conn.db.QueryContext(ctx, fmt.Sprintf("select * from `%s` limit 1", table))
 	if err != nil {
 		return "", fmt.Errorf("Table Checksum (%s): %s", table, err)
 	}
-	defer rows.Close()
 
 	cols, err := rows.Columns()
+	rows.Close()
 	if err != nil {
 		return "", fmt.Errorf("Table Checksum (%s): %s", table, err)
 	}
@@ -106,10 +337,15 @@ func (conn *Connection) TableChecksum(table string) (string, error) {
 		table,
 	)
 
-	rows, err = conn.db.Query(q)
+	if filter, ok := conn.cfg.Filter(table); ok {
+		q += fmt.Sprintf(" where (%s)", filter)
+	}
+
+	rows, err = conn.db.QueryContext(ctx, q)
 	if err != nil {
 		return "", fmt.Errorf("Table Checksum (%s): %s", table, err)
 	}
+	defer rows.Close()
 
 	var checksum string
 	for rows.Next() {
@@ -122,21 +358,17 @@ func (conn *Connection) TableChecksum(table string) (string, error) {
 }
 
 // TableChecksums - returns checksums of all the tables
-func (conn *Connection) TableChecksums() (map[string]string, error) {
-	names, err := conn.TableNames()
+func (conn *Connection) TableChecksums(ctx context.Context) (map[string]string, error) {
+	names, err := conn.TableNames(ctx)
 	if err != nil {
 		return nil, err
 	}
 
-	chks := make(map[string]string, len(names))
-	for _, name := range names {
-		checksum, err := conn.TableChecksum(name)
-		if err != nil {
-			return nil, err
-		}
-
-		chks[name] = checksum
-	}
+	return conn.ChecksumTables(ctx, names...)
+}
 
-	return chks, nil
+// ChecksumTables - returns content checksums of the given tables, computed in parallel
+// on at most MaxConnections connections
+func (conn *Connection) ChecksumTables(ctx context.Context, names ...string) (map[string]string, error) {
+	return database.ParallelChecksums(ctx, conn.maxConnections(), conn.TableChecksum, names...)
 }
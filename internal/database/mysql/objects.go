@@ -0,0 +1,160 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+var definerRegexp = regexp.MustCompile("DEFINER=`[^`]*`@`[^`]*` ")
+
+// Objects - returns views, triggers, stored routines and events
+func (conn *Connection) Objects(ctx context.Context) ([]database.Object, error) {
+	var objects []database.Object
+
+	views, err := conn.ViewNames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Objects: %s", err)
+	}
+
+	for _, name := range views {
+		objects = append(objects, database.Object{Type: database.ObjectView, Name: name})
+	}
+
+	rows, err := conn.db.QueryContext(ctx,
+		"select `TRIGGER_NAME`, `EVENT_OBJECT_TABLE` from `information_schema`.`TRIGGERS` where `TRIGGER_SCHEMA` = ?",
+		conn.cfg.Schema,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Objects: %s", err)
+	}
+
+	for rows.Next() {
+		o := database.Object{Type: database.ObjectTrigger}
+		if err := rows.Scan(&o.Name, &o.Table); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("Objects: %s", err)
+		}
+
+		objects = append(objects, o)
+	}
+	rows.Close()
+
+	rows, err = conn.db.QueryContext(ctx,
+		"select `ROUTINE_NAME`, lower(`ROUTINE_TYPE`) from `information_schema`.`ROUTINES` where `ROUTINE_SCHEMA` = ?",
+		conn.cfg.Schema,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Objects: %s", err)
+	}
+
+	for rows.Next() {
+		var o database.Object
+		if err := rows.Scan(&o.Name, &o.Type); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("Objects: %s", err)
+		}
+
+		objects = append(objects, o)
+	}
+	rows.Close()
+
+	rows, err = conn.db.QueryContext(ctx,
+		"select `EVENT_NAME` from `information_schema`.`EVENTS` where `EVENT_SCHEMA` = ?",
+		conn.cfg.Schema,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Objects: %s", err)
+	}
+
+	for rows.Next() {
+		o := database.Object{Type: database.ObjectEvent}
+		if err := rows.Scan(&o.Name); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("Objects: %s", err)
+		}
+
+		objects = append(objects, o)
+	}
+	rows.Close()
+
+	for i := range objects {
+		def, err := conn.objectDefinition(ctx, objects[i])
+		if err != nil {
+			return nil, fmt.Errorf("Objects (%s): %s", objects[i], err)
+		}
+
+		objects[i].Definition = conn.normalizeDefinition(def)
+	}
+
+	return objects, nil
+}
+
+func (conn *Connection) objectDefinition(ctx context.Context, o database.Object) (string, error) {
+	switch o.Type {
+	case database.ObjectView:
+		return conn.showCreate(ctx, fmt.Sprintf("show create view `%s`", o.Name), "Create View")
+	case database.ObjectTrigger:
+		return conn.showCreate(ctx, fmt.Sprintf("show create trigger `%s`", o.Name), "SQL Original Statement")
+	case database.ObjectProcedure:
+		return conn.showCreate(ctx, fmt.Sprintf("show create procedure `%s`", o.Name), "Create Procedure")
+	case database.ObjectFunction:
+		return conn.showCreate(ctx, fmt.Sprintf("show create function `%s`", o.Name), "Create Function")
+	case database.ObjectEvent:
+		return conn.showCreate(ctx, fmt.Sprintf("show create event `%s`", o.Name), "Create Event")
+	}
+
+	return "", fmt.Errorf("unknown object type %s", o.Type)
+}
+
+// showCreate - runs a show create statement and returns the value of the given column
+func (conn *Connection) showCreate(ctx context.Context, q, column string) (string, error) {
+	rows, err := conn.db.QueryContext(ctx, q)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	values := make([]sql.NullString, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return "", err
+		}
+
+		return "", fmt.Errorf("%s: no result", q)
+	}
+
+	if err := rows.Scan(dest...); err != nil {
+		return "", err
+	}
+
+	for i, col := range cols {
+		if col == column {
+			return values[i].String, nil
+		}
+	}
+
+	return "", fmt.Errorf("%s: column %s not found", q, column)
+}
+
+// normalizeDefinition - removes the definer and the schema qualifier so definitions
+// can be compared between servers and applied to another schema
+func (conn *Connection) normalizeDefinition(def string) string {
+	def = definerRegexp.ReplaceAllString(def, "")
+
+	return strings.Replace(def, fmt.Sprintf("`%s`.", conn.cfg.Schema), "", -1)
+}
@@ -0,0 +1,110 @@
+package mysql
+
+import (
+	"testing"
+
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+func TestNormalizeDefinition(t *testing.T) {
+	conn := New(database.ConnectionConfig{Schema: "shop"})
+
+	tests := []struct {
+		name, def, want string
+	}{
+		{
+			"view",
+			"CREATE ALGORITHM=UNDEFINED DEFINER=`admin`@`%` SQL SECURITY DEFINER VIEW `active_users` AS select `shop`.`users`.`id` AS `id` from `shop`.`users`",
+			"CREATE ALGORITHM=UNDEFINED SQL SECURITY DEFINER VIEW `active_users` AS select `users`.`id` AS `id` from `users`",
+		},
+		{
+			"trigger",
+			"CREATE DEFINER=`root`@`localhost` TRIGGER `audit` AFTER INSERT ON `orders` FOR EACH ROW insert into `shop`.`logs` values (new.id)",
+			"CREATE TRIGGER `audit` AFTER INSERT ON `orders` FOR EACH ROW insert into `logs` values (new.id)",
+		},
+		{
+			"procedure",
+			"CREATE DEFINER=`deploy`@`10.0.0.%` PROCEDURE `archive`()\nBEGIN\n  DELETE FROM `logs`;\nEND",
+			"CREATE PROCEDURE `archive`()\nBEGIN\n  DELETE FROM `logs`;\nEND",
+		},
+		{
+			"other schemas",
+			"CREATE DEFINER=`root`@`%` EVENT `cleanup` ON SCHEDULE EVERY 1 DAY DO DELETE FROM `shop_archive`.`logs`",
+			"CREATE EVENT `cleanup` ON SCHEDULE EVERY 1 DAY DO DELETE FROM `shop_archive`.`logs`",
+		},
+		{
+			"without definer",
+			"CREATE FUNCTION `total`() RETURNS int RETURN 1",
+			"CREATE FUNCTION `total`() RETURNS int RETURN 1",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := conn.normalizeDefinition(tt.def); got != tt.want {
+				t.Errorf("normalizeDefinition() =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeDefinitionOfOtherServers(t *testing.T) {
+	master := New(database.ConnectionConfig{Schema: "prod"})
+	slave := New(database.ConnectionConfig{Schema: "local"})
+
+	// definitions differing only by definer and schema are equal once normalized
+	masterDef := master.normalizeDefinition(
+		"CREATE DEFINER=`app`@`%` TRIGGER `audit` AFTER INSERT ON `orders` FOR EACH ROW insert into `prod`.`logs` values (new.id)",
+	)
+	slaveDef := slave.normalizeDefinition(
+		"CREATE DEFINER=`root`@`localhost` TRIGGER `audit` AFTER INSERT ON `orders` FOR EACH ROW insert into `local`.`logs` values (new.id)",
+	)
+	if masterDef != slaveDef {
+		t.Errorf("normalized definitions differ:\n%s\n%s", masterDef, slaveDef)
+	}
+}
+
+func TestObjectStatements(t *testing.T) {
+	conn := New(database.ConnectionConfig{Schema: "shop"})
+
+	tests := []struct {
+		o            database.Object
+		create, drop string
+	}{
+		{
+			database.Object{Type: database.ObjectView, Name: "active_users", Definition: "CREATE VIEW `active_users` AS select 1"},
+			"CREATE VIEW `active_users` AS select 1;",
+			"drop view if exists `active_users`",
+		},
+		{
+			database.Object{Type: database.ObjectTrigger, Name: "audit", Table: "orders", Definition: "CREATE TRIGGER `audit` AFTER INSERT ON `orders` FOR EACH ROW BEGIN END"},
+			"DELIMITER ;;\nCREATE TRIGGER `audit` AFTER INSERT ON `orders` FOR EACH ROW BEGIN END ;;\nDELIMITER ;",
+			"drop trigger if exists `audit`",
+		},
+		{
+			database.Object{Type: database.ObjectProcedure, Name: "archive", Definition: "CREATE PROCEDURE `archive`() BEGIN END"},
+			"DELIMITER ;;\nCREATE PROCEDURE `archive`() BEGIN END ;;\nDELIMITER ;",
+			"drop procedure if exists `archive`",
+		},
+		{
+			database.Object{Type: database.ObjectFunction, Name: "total", Definition: "CREATE FUNCTION `total`() RETURNS int RETURN 1"},
+			"DELIMITER ;;\nCREATE FUNCTION `total`() RETURNS int RETURN 1 ;;\nDELIMITER ;",
+			"drop function if exists `total`",
+		},
+		{
+			database.Object{Type: database.ObjectEvent, Name: "cleanup", Definition: "CREATE EVENT `cleanup` ON SCHEDULE EVERY 1 DAY DO DELETE FROM `logs`"},
+			"DELIMITER ;;\nCREATE EVENT `cleanup` ON SCHEDULE EVERY 1 DAY DO DELETE FROM `logs` ;;\nDELIMITER ;",
+			"drop event if exists `cleanup`",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := conn.CreateObjectStatement(tt.o); got != tt.create {
+			t.Errorf("CreateObjectStatement(%s) = %q, want %q", tt.o, got, tt.create)
+		}
+
+		if got := conn.DropObjectStatement(tt.o); got != tt.drop {
+			t.Errorf("DropObjectStatement(%s) = %q, want %q", tt.o, got, tt.drop)
+		}
+	}
+}
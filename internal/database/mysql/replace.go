@@ -0,0 +1,109 @@
+package mysql
+
+import (
+	"bytes"
+	"io"
+	"strings"
+)
+
+const replacePrefix = "REPLACE INTO `"
+
+// ReplaceInserts - rewrites the INSERT statements of a dump to REPLACE statements, so rows whose
+// key already exists on the slave are overwritten instead of failing with a duplicate key error
+func ReplaceInserts(dump string) string {
+	lines := strings.Split(dump, "\n")
+	for i, line := range lines {
+		if strings.HasPrefix(line, insertPrefix) {
+			lines[i] = replacePrefix + line[len(insertPrefix):]
+		}
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+// ReplaceWriter - rewrites the INSERT statements of a dump stream to REPLACE statements. Only the
+// start of each line is buffered, so extended inserts of any size stream through.
+type ReplaceWriter struct {
+	w io.Writer
+	// head - start of the current line, until it is known whether it is an INSERT
+	head []byte
+	// inLine - true once the start of the current line was written
+	inLine bool
+}
+
+// NewReplaceWriter - constructor
+func NewReplaceWriter(w io.Writer) *ReplaceWriter {
+	return &ReplaceWriter{w: w}
+}
+
+func (rw *ReplaceWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		if rw.inLine {
+			i := bytes.IndexByte(p, '\n')
+			if i < 0 {
+				if _, err := rw.w.Write(p); err != nil {
+					return 0, err
+				}
+
+				break
+			}
+
+			if _, err := rw.w.Write(p[:i+1]); err != nil {
+				return 0, err
+			}
+
+			p = p[i+1:]
+			rw.inLine = false
+			continue
+		}
+
+		chunk := p
+		if need := len(insertPrefix) - len(rw.head); len(chunk) > need {
+			chunk = chunk[:need]
+		}
+
+		if i := bytes.IndexByte(chunk, '\n'); i >= 0 {
+			chunk = chunk[:i+1]
+		}
+
+		rw.head = append(rw.head, chunk...)
+		p = p[len(chunk):]
+		if len(rw.head) < len(insertPrefix) && !bytes.HasSuffix(rw.head, []byte("\n")) {
+			continue
+		}
+
+		if err := rw.flushHead(); err != nil {
+			return 0, err
+		}
+	}
+
+	return n, nil
+}
+
+// Close - writes the start of the last unterminated line
+func (rw *ReplaceWriter) Close() error {
+	return rw.flushHead()
+}
+
+func (rw *ReplaceWriter) flushHead() error {
+	if len(rw.head) == 0 {
+		return nil
+	}
+
+	head := rw.head
+	rw.head = rw.head[:0]
+	rw.inLine = head[len(head)-1] != '\n'
+
+	if bytes.HasPrefix(head, []byte(insertPrefix)) {
+		if _, err := io.WriteString(rw.w, replacePrefix); err != nil {
+			return err
+		}
+
+		head = head[len(insertPrefix):]
+	}
+
+	_, err := rw.w.Write(head)
+
+	return err
+}
@@ -0,0 +1,131 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestReplaceInserts(t *testing.T) {
+	dump := "DROP TABLE IF EXISTS `t`;\nINSERT INTO `t` VALUES (1);\n-- INSERT INTO `t` VALUES (2);\n"
+	want := "DROP TABLE IF EXISTS `t`;\nREPLACE INTO `t` VALUES (1);\n-- INSERT INTO `t` VALUES (2);\n"
+	if got := ReplaceInserts(dump); got != want {
+		t.Errorf("ReplaceInserts() = %q, want %q", got, want)
+	}
+}
+
+func TestReplaceWriter(t *testing.T) {
+	dump := "INSERT INTO `t` VALUES (1,'INSERT INTO `t`'),(2,'\\n');\n" +
+		"\n" +
+		"INSERT\n" +
+		"LOCK TABLES `t` WRITE;\n" +
+		"INSERT INTO `t` VALUES " + strings.Repeat("(3),", 10) + "(4);\n" +
+		"INSERT INTO `t` VALUES (5)"
+	want := strings.Replace(dump, "\nINSERT INTO `t` VALUES ", "\nREPLACE INTO `t` VALUES ", -1)
+	want = "REPLACE" + strings.TrimPrefix(want, "INSERT")
+
+	for _, size := range []int{1, 3, 5, len(insertPrefix), len(dump)} {
+		var out bytes.Buffer
+		rw := NewReplaceWriter(&out)
+		for p := []byte(dump); len(p) > 0; {
+			n := size
+			if n > len(p) {
+				n = len(p)
+			}
+
+			if _, err := rw.Write(p[:n]); err != nil {
+				t.Fatalf("Write() error = %s", err)
+			}
+
+			p = p[n:]
+		}
+
+		if err := rw.Close(); err != nil {
+			t.Fatalf("Close() error = %s", err)
+		}
+
+		if out.String() != want {
+			t.Errorf("writes of %d bytes = %q, want %q", size, out.String(), want)
+		}
+	}
+}
+
+func TestTranslateReplace(t *testing.T) {
+	table := &Table{
+		Name:       "users",
+		Columns:    []Column{{Name: "id"}, {Name: "name"}},
+		PrimaryKey: `("id")`,
+	}
+
+	tests := []struct {
+		name       string
+		primaryKey string
+		want       string
+	}{
+		{
+			name:       "upsert by primary key",
+			primaryKey: `("id")`,
+			want: `INSERT INTO "users" ("id", "name") VALUES (1,'a'),(2,'b') ` +
+				`ON CONFLICT ("id") DO UPDATE SET "id" = excluded."id", "name" = excluded."name";`,
+		},
+		{
+			name: "insert without primary key",
+			want: `INSERT INTO "users" ("id", "name") VALUES (1,'a'),(2,'b');`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			table.PrimaryKey = tt.primaryKey
+			tw := newTranslator(io.Discard, testTarget{})
+			tw.replaced = table
+
+			got, err := tw.translateReplace("REPLACE INTO `users` (`id`, `name`) VALUES (1,'a'),(2,'b');")
+			if err != nil || got != tt.want {
+				t.Errorf("translateReplace() = %q, %v, want %q", got, err, tt.want)
+			}
+		})
+	}
+}
+
+// refreshDumper - dumper writing a fixed refresh of a table, and its schema
+type refreshDumper struct {
+	schema  string
+	refresh string
+}
+
+func (d refreshDumper) DumpTableTo(ctx context.Context, w io.Writer, table string) error {
+	_, err := io.WriteString(w, d.schema)
+	return err
+}
+
+func (d refreshDumper) RefreshTableTo(ctx context.Context, w io.Writer, table string) error {
+	_, err := io.WriteString(NewReplaceWriter(w), d.refresh)
+	return err
+}
+
+func (d refreshDumper) DumpTableSchemaTo(ctx context.Context, w io.Writer, table string) error {
+	_, err := io.WriteString(w, d.schema)
+	return err
+}
+
+func TestTranslateRefresh(t *testing.T) {
+	d := Translate(refreshDumper{
+		schema:  "CREATE TABLE `t` (\n  `id` int NOT NULL,\n  `v` text,\n  PRIMARY KEY (`id`)\n) ENGINE=InnoDB;\n",
+		refresh: "INSERT INTO `t` (`id`, `v`) VALUES (1,'x');\n",
+	}, testTarget{})
+
+	var out bytes.Buffer
+	if err := d.RefreshTableTo(context.Background(), &out, "t"); err != nil {
+		t.Fatalf("RefreshTableTo() error = %s", err)
+	}
+
+	want := "begin;\n" +
+		`INSERT INTO "t" ("id", "v") VALUES (1,'x') ON CONFLICT ("id") DO UPDATE SET "id" = excluded."id", "v" = excluded."v";` +
+		"\ncommit;\n"
+	if out.String() != want {
+		t.Errorf("RefreshTableTo() = %q, want %q", out.String(), want)
+	}
+}
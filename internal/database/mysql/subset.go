@@ -0,0 +1,356 @@
+package mysql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const subsetBatchSize = 500
+
+// maxFilterLength - maximum length of a subset filter batch, each batch being passed to mysqldump
+// as a single argument, which linux limits to 128 KiB
+const maxFilterLength = 64 * 1024
+
+// subsetRow - row selected into the subset
+type subsetRow struct {
+	// values - sql literals by column, missing for NULL values
+	values map[string]string
+	// expanded - true once the rows referencing this row were followed
+	expanded bool
+}
+
+type subsetTask struct {
+	table string
+	where string
+	// down - follow the rows referencing the selected rows too
+	down bool
+}
+
+// subsetter - collects the rows of a referentially intact subset
+type subsetter struct {
+	conn *Connection
+	// keys - columns identifying a row of every table
+	keys map[string][]string
+	// columns - columns fetched for every table: keys and foreign key columns
+	columns  map[string][]string
+	parents  map[string][]ForeignKey
+	children map[string][]ForeignKey
+	rows     map[string]map[string]*subsetRow
+	// conditions - seed conditions of tables without identifying columns
+	conditions map[string][]string
+	queue      []subsetTask
+}
+
+// GenerateSubset - returns where conditions by table selecting a referentially intact subset of
+// the database. The subset starts with the rows matching the seed conditions of the root tables,
+// follows the rows referencing them and every row referenced by the selected rows. The rows of
+// a table are selected by disjoint batches of conditions, see database.ConnectionConfig.FilterBatches.
+func GenerateSubset(ctx context.Context, conn *Connection, seeds map[string]string) (map[string][]string, error) {
+	if err := conn.Open(ctx); err != nil {
+		return nil, err
+	}
+
+	tables, err := conn.TableNames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Subset: %s", err)
+	}
+
+	pks, err := conn.PrimaryKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Subset: %s", err)
+	}
+
+	fks, err := conn.ForeignKeys(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("Subset: %s", err)
+	}
+
+	s := newSubsetter(conn, tables, pks, fks)
+
+	var roots []string
+	for table := range seeds {
+		roots = append(roots, table)
+	}
+	sort.Strings(roots)
+
+	for _, root := range roots {
+		table, ok := findTable(tables, root)
+		if !ok {
+			return nil, fmt.Errorf("Subset: root table %s not found", root)
+		}
+
+		s.queue = append(s.queue, subsetTask{table: table, where: seeds[root], down: true})
+	}
+
+	for len(s.queue) > 0 {
+		task := s.queue[0]
+		s.queue = s.queue[1:]
+		if err := s.process(ctx, task); err != nil {
+			return nil, fmt.Errorf("Subset (%s): %s", task.table, err)
+		}
+	}
+
+	return s.filters(tables), nil
+}
+
+// findTable - returns the table named name, matched case-insensitively when there is no exact
+// match, as the config file keys are lowercased
+func findTable(tables []string, name string) (string, bool) {
+	for _, table := range tables {
+		if table == name {
+			return table, true
+		}
+	}
+
+	for _, table := range tables {
+		if strings.EqualFold(table, name) {
+			return table, true
+		}
+	}
+
+	return "", false
+}
+
+func newSubsetter(conn *Connection, tables []string, pks map[string][]string, fks []ForeignKey) *subsetter {
+	s := &subsetter{
+		conn:       conn,
+		keys:       make(map[string][]string, len(tables)),
+		columns:    make(map[string][]string, len(tables)),
+		parents:    make(map[string][]ForeignKey),
+		children:   make(map[string][]ForeignKey),
+		rows:       make(map[string]map[string]*subsetRow, len(tables)),
+		conditions: make(map[string][]string),
+	}
+
+	related := make(map[string][]string)
+	for _, fk := range fks {
+		s.parents[fk.Table] = append(s.parents[fk.Table], fk)
+		s.children[fk.ReferencedTable] = append(s.children[fk.ReferencedTable], fk)
+		related[fk.Table] = append(related[fk.Table], fk.Columns...)
+		related[fk.ReferencedTable] = append(related[fk.ReferencedTable], fk.ReferencedColumns...)
+	}
+
+	for _, table := range tables {
+		related[table] = uniqueColumns(related[table])
+
+		// tables without primary key are identified by their foreign key columns
+		keys, ok := pks[table]
+		if !ok {
+			keys = related[table]
+		}
+
+		s.keys[table] = keys
+		s.columns[table] = uniqueColumns(append(append([]string{}, keys...), related[table]...))
+		s.rows[table] = make(map[string]*subsetRow)
+	}
+
+	return s
+}
+
+func uniqueColumns(columns []string) []string {
+	seen := make(map[string]bool, len(columns))
+	var unique []string
+	for _, col := range columns {
+		if !seen[col] {
+			seen[col] = true
+			unique = append(unique, col)
+		}
+	}
+
+	return unique
+}
+
+// process - selects the rows matching a task and queues the rows they reference
+// and, for downward tasks, the rows referencing them
+func (s *subsetter) process(ctx context.Context, task subsetTask) error {
+	if len(s.keys[task.table]) == 0 {
+		s.conditions[task.table] = append(s.conditions[task.table], task.where)
+		return nil
+	}
+
+	rows, err := s.fetch(ctx, task.table, task.where)
+	if err != nil {
+		return err
+	}
+
+	var added, expanded []*subsetRow
+	for _, row := range rows {
+		id := idOf(row.values, s.keys[task.table])
+		existing, ok := s.rows[task.table][id]
+		if !ok {
+			s.rows[task.table][id] = row
+			existing = row
+			added = append(added, row)
+		}
+
+		if task.down && !existing.expanded {
+			existing.expanded = true
+			expanded = append(expanded, existing)
+		}
+	}
+
+	for _, fk := range s.parents[task.table] {
+		s.follow(fk.ReferencedTable, fk.ReferencedColumns, added, fk.Columns, false)
+	}
+
+	for _, fk := range s.children[task.table] {
+		s.follow(fk.Table, fk.Columns, expanded, fk.ReferencedColumns, true)
+	}
+
+	return nil
+}
+
+// follow - queues the rows of table whose columns match the values of the given row columns
+func (s *subsetter) follow(table string, columns []string, rows []*subsetRow, rowColumns []string, down bool) {
+	seen := make(map[string]bool)
+	var tuples []string
+	for _, row := range rows {
+		tuple, ok := tupleOf(row.values, rowColumns)
+		if !ok || seen[tuple] {
+			continue
+		}
+
+		seen[tuple] = true
+		tuples = append(tuples, tuple)
+	}
+
+	for start := 0; start < len(tuples); start += subsetBatchSize {
+		end := start + subsetBatchSize
+		if end > len(tuples) {
+			end = len(tuples)
+		}
+
+		s.queue = append(s.queue, subsetTask{
+			table: table,
+			where: generateInCondition(columns, tuples[start:end]),
+			down:  down,
+		})
+	}
+}
+
+func (s *subsetter) fetch(ctx context.Context, table, where string) ([]*subsetRow, error) {
+	columns := s.columns[table]
+	rows, err := s.conn.db.QueryContext(ctx, fmt.Sprintf(
+		"select distinct `%s` from `%s` where (%s)",
+		strings.Join(columns, "`, `"),
+		table,
+		where,
+	))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	values := make([]sql.RawBytes, len(columns))
+	dest := make([]interface{}, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	var result []*subsetRow
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return nil, err
+		}
+
+		row := &subsetRow{
+			values: make(map[string]string, len(columns)),
+		}
+		for i, col := range columns {
+			if values[i] != nil {
+				row.values[col] = quote(string(values[i]))
+			}
+		}
+
+		result = append(result, row)
+	}
+
+	return result, rows.Err()
+}
+
+// filters - returns the batches of where conditions selecting the subset rows of every table.
+// Tables without identifying columns are only reached by their seed condition.
+func (s *subsetter) filters(tables []string) map[string][]string {
+	filters := make(map[string][]string, len(tables))
+	for _, table := range tables {
+		if conditions, ok := s.conditions[table]; ok {
+			filters[table] = []string{"(" + strings.Join(conditions, ") or (") + ")"}
+			continue
+		}
+
+		var tuples []string
+		for id := range s.rows[table] {
+			tuples = append(tuples, id)
+		}
+
+		if len(tuples) == 0 {
+			filters[table] = []string{"1 = 0"}
+			continue
+		}
+
+		sort.Strings(tuples)
+		filters[table] = generateInConditions(s.keys[table], tuples, maxFilterLength)
+	}
+
+	return filters
+}
+
+// tupleOf - returns the sql tuple of the row values of the given columns;
+// false when any of the values is NULL
+func tupleOf(values map[string]string, columns []string) (string, bool) {
+	literals := make([]string, len(columns))
+	for i, col := range columns {
+		v, ok := values[col]
+		if !ok {
+			return "", false
+		}
+
+		literals[i] = v
+	}
+
+	return "(" + strings.Join(literals, ",") + ")", true
+}
+
+// idOf - returns the sql tuple of the row values of the given columns, NULL values included
+func idOf(values map[string]string, columns []string) string {
+	literals := make([]string, len(columns))
+	for i, col := range columns {
+		v, ok := values[col]
+		if !ok {
+			v = "NULL"
+		}
+
+		literals[i] = v
+	}
+
+	return "(" + strings.Join(literals, ",") + ")"
+}
+
+func generateInCondition(columns []string, tuples []string) string {
+	return fmt.Sprintf("(`%s`) in (%s)", strings.Join(columns, "`, `"), strings.Join(tuples, ","))
+}
+
+// generateInConditions - splits the tuples in conditions no longer than max, unless a single
+// tuple is
+func generateInConditions(columns []string, tuples []string, max int) []string {
+	prefix := len(generateInCondition(columns, nil))
+
+	var conditions []string
+	start, length := 0, prefix
+	for i, tuple := range tuples {
+		if i > start && length+1+len(tuple) > max {
+			conditions = append(conditions, generateInCondition(columns, tuples[start:i]))
+			start, length = i, prefix
+		}
+
+		if i > start {
+			length++
+		}
+		length += len(tuple)
+	}
+
+	return append(conditions, generateInCondition(columns, tuples[start:]))
+}
@@ -0,0 +1,193 @@
+package mysql
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGenerateInCondition(t *testing.T) {
+	tests := []struct {
+		columns []string
+		tuples  []string
+		want    string
+	}{
+		{[]string{"id"}, []string{"(1)", "(2)"}, "(`id`) in ((1),(2))"},
+		{[]string{"order_id", "line"}, []string{"(1,1)", "(1,'a')"}, "(`order_id`, `line`) in ((1,1),(1,'a'))"},
+	}
+
+	for _, tt := range tests {
+		if got := generateInCondition(tt.columns, tt.tuples); got != tt.want {
+			t.Errorf("generateInCondition(%v, %v) = %q, want %q", tt.columns, tt.tuples, got, tt.want)
+		}
+	}
+}
+
+func TestGenerateInConditions(t *testing.T) {
+	columns := []string{"id"}
+	prefix := len(generateInCondition(columns, nil))
+
+	tests := []struct {
+		name   string
+		tuples []string
+		max    int
+		want   []string
+	}{
+		{
+			name:   "single batch",
+			tuples: []string{"(1)", "(2)", "(3)"},
+			max:    1000,
+			want:   []string{"(`id`) in ((1),(2),(3))"},
+		},
+		{
+			name:   "exactly max",
+			tuples: []string{"(1)", "(2)"},
+			max:    prefix + len("(1),(2)"),
+			want:   []string{"(`id`) in ((1),(2))"},
+		},
+		{
+			name:   "split beyond max",
+			tuples: []string{"(1)", "(2)", "(3)"},
+			max:    prefix + len("(1),(2)"),
+			want:   []string{"(`id`) in ((1),(2))", "(`id`) in ((3))"},
+		},
+		{
+			name:   "tuple longer than max alone",
+			tuples: []string{"(1)", "('long value')", "(2)"},
+			max:    prefix + len("(1)"),
+			want:   []string{"(`id`) in ((1))", "(`id`) in (('long value'))", "(`id`) in ((2))"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := generateInConditions(columns, tt.tuples, tt.max); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("generateInConditions() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGenerateInConditionsCoversEveryTuple(t *testing.T) {
+	var tuples []string
+	for i := 0; i < 5000; i++ {
+		tuples = append(tuples, fmt.Sprintf("(%d,'%s')", i, strings.Repeat("x", i%17)))
+	}
+
+	columns := []string{"id", "name"}
+	max := 4096
+	conditions := generateInConditions(columns, tuples, max)
+	if len(conditions) < 2 {
+		t.Fatalf("generateInConditions() = %d conditions, want several", len(conditions))
+	}
+
+	prefix := "(`id`, `name`) in ("
+	var got []string
+	for _, c := range conditions {
+		if len(c) > max {
+			t.Errorf("condition of %d bytes, longer than %d", len(c), max)
+		}
+
+		if !strings.HasPrefix(c, prefix) || !strings.HasSuffix(c, ")") {
+			t.Fatalf("malformed condition %.64q", c)
+		}
+
+		got = append(got, c[len(prefix):len(c)-1])
+	}
+
+	if strings.Join(got, ",") != strings.Join(tuples, ",") {
+		t.Errorf("the conditions do not select every tuple once, in order")
+	}
+}
+
+func TestSubsetFilters(t *testing.T) {
+	s := newSubsetter(nil, []string{"users", "orders", "logs", "tags"}, map[string][]string{
+		"users":  {"id"},
+		"orders": {"id"},
+	}, nil)
+	s.rows["users"]["(2)"] = &subsetRow{}
+	s.rows["users"]["(1)"] = &subsetRow{}
+	s.conditions["logs"] = []string{"level = 'error'", "id < 10"}
+
+	want := map[string][]string{
+		"users":  {"(`id`) in ((1),(2))"},
+		"orders": {"1 = 0"},
+		"logs":   {"(level = 'error') or (id < 10)"},
+		"tags":   {"1 = 0"},
+	}
+	if got := s.filters([]string{"users", "orders", "logs", "tags"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("filters() = %q, want %q", got, want)
+	}
+}
+
+func TestSubsetKeys(t *testing.T) {
+	fks := []ForeignKey{
+		{Table: "order_tags", Columns: []string{"order_id"}, ReferencedTable: "orders", ReferencedColumns: []string{"id"}},
+		{Table: "order_tags", Columns: []string{"tag_id"}, ReferencedTable: "tags", ReferencedColumns: []string{"id"}},
+	}
+	s := newSubsetter(nil, []string{"orders", "tags", "order_tags"}, map[string][]string{
+		"orders": {"id"},
+		"tags":   {"id"},
+	}, fks)
+
+	// tables without primary key are identified by their foreign key columns
+	if got, want := s.keys["order_tags"], []string{"order_id", "tag_id"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("keys of order_tags = %v, want %v", got, want)
+	}
+
+	if got, want := s.columns["orders"], []string{"id"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("columns of orders = %v, want %v", got, want)
+	}
+}
+
+func TestTupleOf(t *testing.T) {
+	values := map[string]string{"id": "1", "name": "'a'"}
+	if got, ok := tupleOf(values, []string{"id", "name"}); !ok || got != "(1,'a')" {
+		t.Errorf("tupleOf() = %q, %v", got, ok)
+	}
+
+	if _, ok := tupleOf(values, []string{"id", "parent_id"}); ok {
+		t.Errorf("tupleOf() with a NULL value = ok")
+	}
+
+	if got := idOf(values, []string{"id", "parent_id"}); got != "(1,NULL)" {
+		t.Errorf("idOf() = %q, want (1,NULL)", got)
+	}
+}
+
+func TestFindTable(t *testing.T) {
+	tables := []string{"Users", "users_archive", "users"}
+	tests := []struct {
+		name   string
+		want   string
+		wantOk bool
+	}{
+		{"users", "users", true},
+		{"Users", "Users", true},
+		{"USERS_ARCHIVE", "users_archive", true},
+		{"orders", "", false},
+	}
+
+	for _, tt := range tests {
+		if got, ok := findTable(tables, tt.name); got != tt.want || ok != tt.wantOk {
+			t.Errorf("findTable(%q) = %q, %v, want %q, %v", tt.name, got, ok, tt.want, tt.wantOk)
+		}
+	}
+}
+
+func TestBatchOptions(t *testing.T) {
+	options := []string{"--single-transaction"}
+	if got, want := batchOptions(options, "id < 10", 0), []string{"--single-transaction", "--where=id < 10"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("batchOptions() of the first batch = %q, want %q", got, want)
+	}
+
+	want := []string{"--single-transaction", "--where=id >= 10", "--no-create-info"}
+	if got := batchOptions(options, "id >= 10", 1); !reflect.DeepEqual(got, want) {
+		t.Errorf("batchOptions() of a later batch = %q, want %q", got, want)
+	}
+
+	if len(options) != 1 {
+		t.Errorf("batchOptions() modified its options: %q", options)
+	}
+}
@@ -0,0 +1,588 @@
+package mysql
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+var (
+	columnAttributeRegexps = []*regexp.Regexp{
+		regexp.MustCompile(`(?i)\s+CHARACTER SET \w+`),
+		regexp.MustCompile(`(?i)\s+COLLATE \w+`),
+		regexp.MustCompile(`(?i)\s+ON UPDATE CURRENT_TIMESTAMP(\(\d*\))?`),
+		regexp.MustCompile(`(?i)\s+COMMENT '(?:[^'\\]|\\.|'')*'`),
+		regexp.MustCompile(`(?i)\s+SRID \d+`),
+		regexp.MustCompile(`\s*/\*!\d+ [^*]*\*/`),
+	}
+	autoIncrementRegexp    = regexp.MustCompile(`(?i)\s+AUTO_INCREMENT\b`)
+	currentTimestampRegexp = regexp.MustCompile(`(?i)CURRENT_TIMESTAMP\(\d*\)`)
+	bitLiteralRegexp       = regexp.MustCompile(`\bb'([01]+)'`)
+	keyPartLengthRegexp    = regexp.MustCompile("`\\(\\d+\\)")
+	keyOptionsRegexp       = regexp.MustCompile(`(?i)\s+USING \w+`)
+	keyRegexp              = regexp.MustCompile("^(?:(UNIQUE|FULLTEXT|SPATIAL) )?KEY `([^`]+)` (\\(.*\\))")
+)
+
+// Target - engine the mysqldump output of a master is translated to, its slave using another driver
+type Target interface {
+	// QuoteIdent - quotes a table or column name
+	QuoteIdent(name string) string
+	// Literal - returns the literal of a string value; binary values hold raw bytes
+	Literal(text string, binary bool) string
+	// Number - returns the literal of a numeric value
+	Number(text string) string
+	// DropTableStatement - returns the statement dropping a table
+	DropTableStatement(table string) string
+	// CreateTableStatement - returns the statements creating a table and its indexes
+	CreateTableStatement(t *Table) string
+	// InsertedStatement - returns the statements run once the rows of a created table are inserted
+	InsertedStatement(t *Table) string
+}
+
+// Translate - returns a dumper translating the mysqldump output of d to statements of target
+func Translate(d database.Dumper, target Target) database.Dumper {
+	return &translatingDumper{
+		dumper: d,
+		target: target,
+	}
+}
+
+type translatingDumper struct {
+	dumper database.Dumper
+	target Target
+}
+
+// DumpTableTo - streams the translated dump of a single table to w
+func (d *translatingDumper) DumpTableTo(ctx context.Context, w io.Writer, table string) error {
+	tw := newTranslator(w, d.target)
+	if err := d.dumper.DumpTableTo(ctx, tw, table); err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// RefreshTableTo - streams the translated refresh of a single table to w. The REPLACE statements
+// of the refresh become inserts updating the rows whose primary key already exists.
+func (d *translatingDumper) RefreshTableTo(ctx context.Context, w io.Writer, table string) error {
+	schema, err := d.tableSchema(ctx, table)
+	if err != nil {
+		return err
+	}
+
+	tw := newTranslator(w, d.target)
+	tw.replaced = schema
+	if err := d.dumper.RefreshTableTo(ctx, tw, table); err != nil {
+		return err
+	}
+
+	return tw.Close()
+}
+
+// schemaDumper - dumper streaming the create table statement of a table
+type schemaDumper interface {
+	DumpTableSchemaTo(ctx context.Context, w io.Writer, table string) error
+}
+
+// tableSchema - parses the create table statement of a table of the dumper
+func (d *translatingDumper) tableSchema(ctx context.Context, table string) (*Table, error) {
+	sd, ok := d.dumper.(schemaDumper)
+	if !ok {
+		return nil, fmt.Errorf("refresh %s: the table schema can't be dumped", table)
+	}
+
+	tw := newTranslator(ioutil.Discard, d.target)
+	if err := sd.DumpTableSchemaTo(ctx, tw, table); err != nil {
+		return nil, err
+	}
+
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+
+	for _, created := range tw.created {
+		if created.Name == table {
+			return created, nil
+		}
+	}
+
+	return nil, fmt.Errorf("refresh %s: create table statement not found", table)
+}
+
+// translator - rewrites a mysqldump stream to statements of the target line by line. The statements
+// run in a single transaction, which makes loading large tables much faster.
+type translator struct {
+	w      io.Writer
+	target Target
+	line   bytes.Buffer
+	// table - create table statement being collected
+	table *Table
+	// created - tables created by the stream
+	created []*Table
+	// replaced - refreshed table, the target of the REPLACE statements of the stream
+	replaced *Table
+	// comment - true inside a multi line conditional comment
+	comment bool
+	begun   bool
+}
+
+func newTranslator(w io.Writer, target Target) *translator {
+	return &translator{
+		w:      w,
+		target: target,
+	}
+}
+
+func (t *translator) Write(p []byte) (int, error) {
+	n := len(p)
+	for len(p) > 0 {
+		i := bytes.IndexByte(p, '\n')
+		if i < 0 {
+			t.line.Write(p)
+			break
+		}
+
+		t.line.Write(p[:i])
+		p = p[i+1:]
+		if err := t.flushLine(); err != nil {
+			return 0, err
+		}
+	}
+
+	return n, nil
+}
+
+// Close - translates the last unterminated line and commits the transaction
+func (t *translator) Close() error {
+	if err := t.flushLine(); err != nil {
+		return err
+	}
+
+	if t.table != nil {
+		return fmt.Errorf("create table %s: unterminated statement", t.table.Name)
+	}
+
+	for _, table := range t.created {
+		if err := t.writeStatement(t.target.InsertedStatement(table)); err != nil {
+			return err
+		}
+	}
+
+	if !t.begun {
+		return nil
+	}
+
+	_, err := io.WriteString(t.w, "commit;\n")
+
+	return err
+}
+
+func (t *translator) flushLine() error {
+	line := t.line.String()
+	t.line.Reset()
+
+	out, err := t.translate(line)
+	if err != nil {
+		return err
+	}
+
+	return t.writeStatement(out)
+}
+
+func (t *translator) writeStatement(stmt string) error {
+	if stmt == "" {
+		return nil
+	}
+
+	if !t.begun {
+		stmt = "begin;\n" + stmt
+		t.begun = true
+	}
+
+	_, err := io.WriteString(t.w, stmt+"\n")
+
+	return err
+}
+
+// translate - returns the target statements of a mysqldump line; comments, mysql specific
+// statements and the lines of create table statements still being collected translate to nothing
+func (t *translator) translate(line string) (string, error) {
+	if t.comment {
+		t.comment = !strings.Contains(line, "*/")
+		return "", nil
+	}
+
+	if t.table != nil {
+		if strings.HasPrefix(line, ")") {
+			stmt := t.target.CreateTableStatement(t.table)
+			t.created = append(t.created, t.table)
+			t.table = nil
+
+			return stmt, nil
+		}
+
+		return "", t.table.add(line, t.target.QuoteIdent)
+	}
+
+	switch {
+	case strings.HasPrefix(line, "/*!"):
+		t.comment = !strings.Contains(line, "*/")
+		return "", nil
+	case line == "",
+		strings.HasPrefix(line, "--"),
+		strings.HasPrefix(line, "LOCK TABLES "),
+		strings.HasPrefix(line, "UNLOCK TABLES"):
+		return "", nil
+	case strings.HasPrefix(line, "DROP TABLE IF EXISTS "):
+		name, _, err := parseIdent(strings.TrimPrefix(line, "DROP TABLE IF EXISTS "))
+		if err != nil {
+			return "", fmt.Errorf("drop table: %s", err)
+		}
+
+		return t.target.DropTableStatement(name) + ";", nil
+	case strings.HasPrefix(line, "CREATE TABLE "):
+		table, err := newTable(line)
+		t.table = table
+
+		return "", err
+	case strings.HasPrefix(line, "INSERT INTO "):
+		return t.translateInsert(line)
+	case strings.HasPrefix(line, "REPLACE INTO "):
+		return t.translateReplace(line)
+	case strings.HasPrefix(line, "set foreign_key_checks"),
+		strings.HasPrefix(line, "set "+foreignKeyChecksVariable):
+		// foreign key checks are disabled by the slave dialect around the whole sync
+		return "", nil
+	}
+
+	return requoteIdents(line, t.target.QuoteIdent), nil
+}
+
+// Column - column of a create table statement
+type Column struct {
+	Name string
+	// Type - mysql type, e.g. int unsigned or varchar(255)
+	Type string
+	// Attributes - nullability, default and generation of the column, mysql specific ones left out
+	Attributes    string
+	AutoIncrement bool
+}
+
+// Index - non unique key of a create table statement
+type Index struct {
+	Name string
+	// Parts - parenthesized key columns, quoted for the target
+	Parts string
+}
+
+// Table - create table statement as printed by SHOW CREATE TABLE. Key parts and constraints
+// are quoted for the target.
+type Table struct {
+	Name       string
+	Columns    []Column
+	PrimaryKey string
+	// Constraints - unique, foreign key and check constraints
+	Constraints []string
+	Indexes     []Index
+}
+
+func newTable(line string) (*Table, error) {
+	name, _, err := parseIdent(strings.TrimPrefix(line, "CREATE TABLE "))
+	if err != nil {
+		return nil, fmt.Errorf("create table: %s", err)
+	}
+
+	return &Table{
+		Name: name,
+	}, nil
+}
+
+// add - adds a column, key or constraint line
+func (table *Table) add(line string, quote func(string) string) error {
+	line = strings.TrimSuffix(strings.TrimSpace(line), ",")
+
+	switch {
+	case strings.HasPrefix(line, "`"):
+		col, err := parseColumn(line, quote)
+		if err != nil {
+			return fmt.Errorf("create table %s: %s", table.Name, err)
+		}
+
+		table.Columns = append(table.Columns, col)
+	case strings.HasPrefix(line, "PRIMARY KEY "):
+		table.PrimaryKey = keyParts(strings.TrimPrefix(line, "PRIMARY KEY "), quote)
+	case strings.HasPrefix(line, "CONSTRAINT "):
+		table.Constraints = append(table.Constraints, requoteIdents(line, quote))
+	default:
+		m := keyRegexp.FindStringSubmatch(line)
+		if m == nil || strings.HasPrefix(m[3], "((") {
+			// functional and other keys without equivalent in other engines are left out
+			return nil
+		}
+
+		if m[1] == "UNIQUE" {
+			table.Constraints = append(table.Constraints, "UNIQUE "+keyParts(m[3], quote))
+			return nil
+		}
+
+		table.Indexes = append(table.Indexes, Index{
+			Name:  m[2],
+			Parts: keyParts(m[3], quote),
+		})
+	}
+
+	return nil
+}
+
+// parseColumn - parses a column line of a create table statement
+func parseColumn(line string, quote func(string) string) (Column, error) {
+	name, rest, err := parseIdent(line)
+	if err != nil {
+		return Column{}, err
+	}
+
+	typ, attributes := splitType(strings.TrimSpace(rest))
+	for _, re := range columnAttributeRegexps {
+		attributes = re.ReplaceAllString(attributes, "")
+	}
+
+	col := Column{
+		Name: name,
+		Type: typ,
+	}
+	if autoIncrementRegexp.MatchString(attributes) {
+		col.AutoIncrement = true
+		attributes = autoIncrementRegexp.ReplaceAllString(attributes, "")
+	}
+
+	attributes = currentTimestampRegexp.ReplaceAllString(attributes, "CURRENT_TIMESTAMP")
+	attributes = bitLiteralRegexp.ReplaceAllStringFunc(attributes, func(s string) string {
+		n, _ := strconv.ParseInt(s[2:len(s)-1], 2, 64)
+		return "'" + strconv.FormatInt(n, 10) + "'"
+	})
+
+	col.Attributes = requoteIdents(attributes, quote)
+
+	return col, nil
+}
+
+// splitType - splits a column definition in its type, including length, values and
+// the unsigned and zerofill modifiers, and the rest of the attributes
+func splitType(def string) (string, string) {
+	i := 0
+	for i < len(def) && (def[i] >= 'a' && def[i] <= 'z' || def[i] >= 'A' && def[i] <= 'Z') {
+		i++
+	}
+
+	if i < len(def) && def[i] == '(' {
+		depth := 0
+		quoted := false
+		for ; i < len(def); i++ {
+			c := def[i]
+			if quoted {
+				if c == '\\' {
+					i++
+				} else if c == '\'' {
+					quoted = false
+				}
+				continue
+			}
+
+			if c == '\'' {
+				quoted = true
+			} else if c == '(' {
+				depth++
+			} else if c == ')' {
+				depth--
+				if depth == 0 {
+					i++
+					break
+				}
+			}
+		}
+	}
+
+	typ, rest := def[:i], def[i:]
+	for _, modifier := range []string{" unsigned", " zerofill"} {
+		if strings.HasPrefix(rest, modifier) {
+			typ += modifier
+			rest = rest[len(modifier):]
+		}
+	}
+
+	return typ, rest
+}
+
+// keyParts - returns the column list of a key without mysql prefix lengths and index options
+func keyParts(parts string, quote func(string) string) string {
+	parts = keyOptionsRegexp.ReplaceAllString(keyPartLengthRegexp.ReplaceAllString(parts, "`"), "")
+
+	return requoteIdents(parts, quote)
+}
+
+// parseIdent - parses a backtick quoted identifier and returns it with the rest of s
+func parseIdent(s string) (string, string, error) {
+	if !strings.HasPrefix(s, "`") {
+		return "", "", fmt.Errorf("identifier expected: %.32q", s)
+	}
+
+	i := strings.IndexByte(s[1:], '`')
+	if i < 0 {
+		return "", "", fmt.Errorf("unterminated identifier: %.32q", s)
+	}
+
+	return s[1 : i+1], s[i+2:], nil
+}
+
+// requoteIdents - quotes the backtick quoted identifiers of s with quote, leaving string literals alone
+func requoteIdents(s string, quote func(string) string) string {
+	if !strings.Contains(s, "`") {
+		return s
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(s); {
+		switch s[i] {
+		case '\'':
+			_, n, err := Unquote(s[i:])
+			if err != nil {
+				b.WriteString(s[i:])
+				return b.String()
+			}
+
+			b.WriteString(s[i : i+n])
+			i += n
+		case '`':
+			name, rest, err := parseIdent(s[i:])
+			if err != nil {
+				b.WriteString(s[i:])
+				return b.String()
+			}
+
+			b.WriteString(quote(name))
+			i = len(s) - len(rest)
+		default:
+			b.WriteByte(s[i])
+			i++
+		}
+	}
+
+	return b.String()
+}
+
+// translateInsert - rewrites the identifiers and values of a mysqldump INSERT statement for the target
+func (t *translator) translateInsert(line string) (string, error) {
+	i := strings.Index(line, " VALUES ")
+	if i < 0 {
+		return "", fmt.Errorf("malformed insert: %.64s", line)
+	}
+
+	var b strings.Builder
+	b.WriteString(requoteIdents(line[:i+len(" VALUES ")], t.target.QuoteIdent))
+
+	rest := line[i+len(" VALUES "):]
+	start := false
+	for j := 0; j < len(rest); {
+		c := rest[j]
+		if !start || c == '(' {
+			b.WriteByte(c)
+			start = c == '(' || c == ','
+			j++
+			continue
+		}
+
+		switch {
+		case c == '\'' || strings.HasPrefix(rest[j:], "_binary '"):
+			binary := c != '\''
+			if binary {
+				j += len("_binary ")
+			}
+
+			text, n, err := Unquote(rest[j:])
+			if err != nil {
+				return "", fmt.Errorf("insert: %s", err)
+			}
+
+			b.WriteString(t.target.Literal(text, binary))
+			j += n
+		case strings.HasPrefix(rest[j:], "0x"):
+			k := j + 2
+			for k < len(rest) && isHexDigit(rest[k]) {
+				k++
+			}
+
+			data, err := hex.DecodeString(rest[j+2 : k])
+			if err != nil {
+				return "", fmt.Errorf("insert: %s", err)
+			}
+
+			b.WriteString(t.target.Literal(string(data), true))
+			j = k
+		default:
+			k := j
+			for k < len(rest) && rest[k] != ',' && rest[k] != ')' {
+				k++
+			}
+
+			value := rest[j:k]
+			if m := bitLiteralRegexp.FindStringSubmatch(value); m != nil {
+				n, _ := strconv.ParseInt(m[1], 2, 64)
+				value = strconv.FormatInt(n, 10)
+			}
+
+			if value == "NULL" {
+				b.WriteString(value)
+			} else {
+				b.WriteString(t.target.Number(value))
+			}
+			j = k
+		}
+		start = false
+	}
+
+	return b.String(), nil
+}
+
+// translateReplace - rewrites a REPLACE statement of a refresh to an insert updating the rows whose
+// primary key already exists. Rows of tables without primary key are simply inserted.
+func (t *translator) translateReplace(line string) (string, error) {
+	rest := strings.TrimPrefix(line, "REPLACE INTO ")
+	name, _, err := parseIdent(rest)
+	if err != nil {
+		return "", fmt.Errorf("replace: %s", err)
+	}
+
+	if t.replaced == nil || t.replaced.Name != name {
+		return "", fmt.Errorf("replace into %s: table schema unknown", name)
+	}
+
+	stmt, err := t.translateInsert("INSERT INTO " + rest)
+	if err != nil || t.replaced.PrimaryKey == "" {
+		return stmt, err
+	}
+
+	sets := make([]string, len(t.replaced.Columns))
+	for i, col := range t.replaced.Columns {
+		name := t.target.QuoteIdent(col.Name)
+		sets[i] = name + " = excluded." + name
+	}
+
+	return fmt.Sprintf(
+		"%s ON CONFLICT %s DO UPDATE SET %s;",
+		strings.TrimSuffix(stmt, ";"),
+		t.replaced.PrimaryKey,
+		strings.Join(sets, ", "),
+	), nil
+}
+
+func isHexDigit(c byte) bool {
+	return c >= '0' && c <= '9' || c >= 'a' && c <= 'f' || c >= 'A' && c <= 'F'
+}
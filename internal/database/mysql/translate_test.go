@@ -0,0 +1,225 @@
+package mysql
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// testTarget - target quoting identifiers with double quotes and writing readable statements
+type testTarget struct{}
+
+func (testTarget) QuoteIdent(name string) string {
+	return `"` + name + `"`
+}
+
+func (testTarget) Literal(text string, binary bool) string {
+	if binary {
+		return fmt.Sprintf("X'%x'", text)
+	}
+
+	return "'" + strings.Replace(text, "'", "''", -1) + "'"
+}
+
+func (testTarget) Number(text string) string {
+	return text
+}
+
+func (testTarget) DropTableStatement(table string) string {
+	return `drop table "` + table + `"`
+}
+
+func (testTarget) CreateTableStatement(t *Table) string {
+	var cols []string
+	for _, col := range t.Columns {
+		cols = append(cols, fmt.Sprintf("%s %s%s", col.Name, col.Type, col.Attributes))
+	}
+
+	return fmt.Sprintf("create %s (%s) pk %s;", t.Name, strings.Join(cols, ", "), t.PrimaryKey)
+}
+
+func (testTarget) InsertedStatement(t *Table) string {
+	return "-- inserted " + t.Name
+}
+
+const testDump = "-- MySQL dump 10.13\n" +
+	"/*!40101 SET @OLD_CHARACTER_SET_CLIENT=@@CHARACTER_SET_CLIENT */;\n" +
+	"/*!40101 SET character_set_client = utf8mb4\n" +
+	"   spanning lines */;\n" +
+	"DROP TABLE IF EXISTS `users`;\n" +
+	"CREATE TABLE `users` (\n" +
+	"  `id` int unsigned NOT NULL AUTO_INCREMENT,\n" +
+	"  `name` varchar(255) CHARACTER SET utf8mb4 COLLATE utf8mb4_bin NOT NULL DEFAULT '' COMMENT 'the `name`',\n" +
+	"  `status` enum('new','do,ne') NOT NULL,\n" +
+	"  `flags` bit(3) DEFAULT b'101',\n" +
+	"  `updated_at` timestamp(3) NULL DEFAULT CURRENT_TIMESTAMP(3) ON UPDATE CURRENT_TIMESTAMP(3),\n" +
+	"  PRIMARY KEY (`id`),\n" +
+	"  UNIQUE KEY `name` (`name`(10)),\n" +
+	"  KEY `status` (`status`,`id`) USING BTREE,\n" +
+	"  KEY `expr` ((lower(`name`))),\n" +
+	"  CONSTRAINT `fk` FOREIGN KEY (`id`) REFERENCES `accounts` (`id`)\n" +
+	") ENGINE=InnoDB DEFAULT CHARSET=utf8mb4;\n" +
+	"LOCK TABLES `users` WRITE;\n" +
+	"INSERT INTO `users` (`id`, `name`, `status`, `flags`, `updated_at`) VALUES " +
+	"(1,'O\\'Brien','new',b'1',NULL),(2,'a),(b','do,ne',0x00ff,'2020-01-02 03:04:05.000');\n" +
+	"UNLOCK TABLES;\n"
+
+func TestTranslator(t *testing.T) {
+	want := "begin;\n" +
+		`drop table "users";` + "\n" +
+		"create users (" +
+		"id int unsigned NOT NULL, " +
+		"name varchar(255) NOT NULL DEFAULT '', " +
+		"status enum('new','do,ne') NOT NULL, " +
+		"flags bit(3) DEFAULT '5', " +
+		"updated_at timestamp(3) NULL DEFAULT CURRENT_TIMESTAMP" +
+		`) pk ("id");` + "\n" +
+		`INSERT INTO "users" ("id", "name", "status", "flags", "updated_at") VALUES ` +
+		`(1,'O''Brien','new',1,NULL),(2,'a),(b','do,ne',X'00ff','2020-01-02 03:04:05.000');` + "\n" +
+		"-- inserted users\n" +
+		"commit;\n"
+
+	for _, size := range []int{1, 13, len(testDump)} {
+		var out bytes.Buffer
+		tw := newTranslator(&out, testTarget{})
+		for p := []byte(testDump); len(p) > 0; {
+			n := size
+			if n > len(p) {
+				n = len(p)
+			}
+
+			if _, err := tw.Write(p[:n]); err != nil {
+				t.Fatalf("Write() error = %s", err)
+			}
+
+			p = p[n:]
+		}
+
+		if err := tw.Close(); err != nil {
+			t.Fatalf("Close() error = %s", err)
+		}
+
+		if out.String() != want {
+			t.Errorf("writes of %d bytes:\n%s\nwant:\n%s", size, out.String(), want)
+		}
+	}
+}
+
+func TestTranslatorTable(t *testing.T) {
+	tw := newTranslator(io.Discard, testTarget{})
+	if _, err := io.WriteString(tw, testDump); err != nil {
+		t.Fatalf("Write() error = %s", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() error = %s", err)
+	}
+
+	if len(tw.created) != 1 {
+		t.Fatalf("created %d tables, want 1", len(tw.created))
+	}
+
+	table := tw.created[0]
+	if want := []string{`UNIQUE ("name")`, `CONSTRAINT "fk" FOREIGN KEY ("id") REFERENCES "accounts" ("id")`}; !reflect.DeepEqual(table.Constraints, want) {
+		t.Errorf("constraints = %q, want %q", table.Constraints, want)
+	}
+
+	if want := []Index{{Name: "status", Parts: `("status","id")`}}; !reflect.DeepEqual(table.Indexes, want) {
+		t.Errorf("indexes = %+v, want %+v", table.Indexes, want)
+	}
+
+	if !table.Columns[0].AutoIncrement || table.Columns[1].AutoIncrement {
+		t.Errorf("auto increment columns = %+v", table.Columns)
+	}
+}
+
+func TestTranslatorDeleteStatement(t *testing.T) {
+	del := GenerateDeleteStatement("orders", "total > 0")
+	want := "set @dbsync_foreign_key_checks = @@foreign_key_checks, foreign_key_checks = 0;\n" +
+		"delete from `orders` where (total > 0);\n" +
+		"set foreign_key_checks = @dbsync_foreign_key_checks;\n"
+	if del != want {
+		t.Fatalf("GenerateDeleteStatement() =\n%s\nwant\n%s", del, want)
+	}
+
+	// the foreign key checks are left to the slave dialect
+	var out bytes.Buffer
+	tw := newTranslator(&out, testTarget{})
+	if _, err := io.WriteString(tw, del); err != nil {
+		t.Fatalf("Write() error = %s", err)
+	}
+
+	if err := tw.Close(); err != nil {
+		t.Fatalf("Close() error = %s", err)
+	}
+
+	if want := "begin;\ndelete from \"orders\" where (total > 0);\ncommit;\n"; out.String() != want {
+		t.Errorf("translated delete =\n%s\nwant\n%s", out.String(), want)
+	}
+}
+
+func TestTranslatorErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		dump string
+		want string
+	}{
+		{"unterminated create table", "CREATE TABLE `t` (\n  `id` int NOT NULL\n", "create table t: unterminated statement"},
+		{"unquoted table", "CREATE TABLE t (\n", "create table: identifier expected: \"t (\""},
+		{"unterminated string", "INSERT INTO `t` (`a`) VALUES ('abc);\n", "insert: unterminated string"},
+		{"replace without schema", "REPLACE INTO `t` (`a`) VALUES (1);\n", "replace into t: table schema unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tw := newTranslator(io.Discard, testTarget{})
+			_, err := io.WriteString(tw, tt.dump)
+			if err == nil {
+				err = tw.Close()
+			}
+
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitColumnType(t *testing.T) {
+	tests := []struct {
+		def, typ, rest string
+	}{
+		{"int NOT NULL", "int", " NOT NULL"},
+		{"int(10) unsigned zerofill NOT NULL", "int(10) unsigned zerofill", " NOT NULL"},
+		{"enum('a)','b''c','d\\'') DEFAULT 'a)'", "enum('a)','b''c','d\\'')", " DEFAULT 'a)'"},
+		{"decimal(10,2)", "decimal(10,2)", ""},
+		{"json", "json", ""},
+	}
+
+	for _, tt := range tests {
+		if typ, rest := splitType(tt.def); typ != tt.typ || rest != tt.rest {
+			t.Errorf("splitType(%q) = %q, %q, want %q, %q", tt.def, typ, rest, tt.typ, tt.rest)
+		}
+	}
+}
+
+func TestRequoteIdents(t *testing.T) {
+	quote := testTarget{}.QuoteIdent
+	tests := []struct {
+		s, want string
+	}{
+		{"no identifiers", "no identifiers"},
+		{"(`a`,`b`)", `("a","b")`},
+		{"`a` = 'it''s `b`'", `"a" = 'it''s ` + "`b`'"},
+		{"`a` = 'unterminated `b`", `"a" = 'unterminated ` + "`b`"},
+	}
+
+	for _, tt := range tests {
+		if got := requoteIdents(tt.s, quote); got != tt.want {
+			t.Errorf("requoteIdents(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
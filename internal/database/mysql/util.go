@@ -2,16 +2,22 @@ package mysql
 
 import (
 	"bytes"
+	"context"
 	"errors"
 	"fmt"
+	"io"
 	"net/url"
 	"os/exec"
 	"path/filepath"
 	"strconv"
 	"strings"
+
+	"github.com/vcraescu/dbsync/internal/compress"
+	"github.com/vcraescu/dbsync/internal/database"
+	"github.com/vcraescu/dbsync/internal/logger"
 )
 
-func generateDSN(cfg ConnectionConfig) (string, error) {
+func generateDSN(cfg database.ConnectionConfig) (string, error) {
 	dsn := fmt.Sprintf(
 		"%s:%s@tcp(%s:%d)/%s",
 		cfg.Username,
@@ -36,11 +42,31 @@ func generateDSN(cfg ConnectionConfig) (string, error) {
 	return u.String(), nil
 }
 
-func generateDropTableStatement(table string) string {
-	return fmt.Sprintf("drop table if exists `%s`", table)
+// foreignKeyChecksVariable - user variable holding the foreign_key_checks of the session while rows are deleted
+const foreignKeyChecksVariable = "@dbsync_foreign_key_checks"
+
+// GenerateDeleteStatement - returns the statements deleting the rows matching filter
+// with foreign key checks disabled, setting them back to their previous value afterwards
+func GenerateDeleteStatement(table, filter string) string {
+	return fmt.Sprintf(
+		"set %s = @@foreign_key_checks, foreign_key_checks = 0;\ndelete from `%s` where (%s);\nset foreign_key_checks = %s;\n",
+		foreignKeyChecksVariable,
+		table,
+		filter,
+		foreignKeyChecksVariable,
+	)
+}
+
+func mysqlDump(ctx context.Context, username, password, host string, port int, schema string, options []string, tables ...string) (string, error) {
+	var out bytes.Buffer
+	if err := mysqlDumpTo(ctx, &out, username, password, host, port, schema, options, tables...); err != nil {
+		return "", err
+	}
+
+	return out.String(), nil
 }
 
-func mysqlDump(username, password, host string, port int, schema string, tables ...string) (string, error) {
+func mysqlDumpTo(ctx context.Context, w io.Writer, username, password, host string, port int, schema string, options []string, tables ...string) error {
 	args := []string{
 		"-h",
 		host,
@@ -49,33 +75,52 @@ func mysqlDump(username, password, host string, port int, schema string, tables
 		"-u",
 		username,
 		fmt.Sprintf("-p%s", password),
-		schema,
+		"--skip-triggers",
 	}
+	args = append(args, options...)
+	args = append(args, schema)
 	args = append(args, tables...)
 
 	path, err := exec.LookPath("mysqldump")
 	if err != nil {
 		path, err = filepath.Abs("bin/mysqldump")
 		if err != nil {
-			return "", errors.New("mysqldump not found")
+			return errors.New("mysqldump not found")
 		}
 	}
 
-	cmd := exec.Command(path, args...)
+	logger.Debug("Running mysqldump", "host", host, "schema", schema)
+	cmd := exec.CommandContext(ctx, path, args...)
 
-	var out bytes.Buffer
 	var stderr bytes.Buffer
 
-	cmd.Stdout = &out
+	cmd.Stdout = w
 	cmd.Stderr = &stderr
 	if err := cmd.Run(); err != nil {
-		return "", fmt.Errorf("%s: %s", err, stderr.String())
+		return fmt.Errorf("%s: %s", err, stderr.String())
 	}
 
-	return out.String(), nil
+	return nil
 }
 
-func mysqlImport(username, password, host string, port int, schema, dump string) (string, error) {
+// compressionOptions - returns the mysqldump and mysql client options compressing the
+// traffic with the server, which is what crosses the ssh tunnel
+func compressionOptions(a compress.Algorithm) []string {
+	switch a {
+	case compress.Gzip:
+		return []string{"--compression-algorithms=zlib"}
+	case compress.Zstd:
+		return []string{"--compression-algorithms=zstd"}
+	}
+
+	return nil
+}
+
+func mysqlImport(ctx context.Context, username, password, host string, port int, schema string, options []string, dump string) (string, error) {
+	return mysqlImportFrom(ctx, strings.NewReader(dump), username, password, host, port, schema, options)
+}
+
+func mysqlImportFrom(ctx context.Context, r io.Reader, username, password, host string, port int, schema string, options []string) (string, error) {
 	args := []string{
 		"-h",
 		host,
@@ -84,8 +129,9 @@ func mysqlImport(username, password, host string, port int, schema, dump string)
 		"-u",
 		username,
 		fmt.Sprintf("-p%s", password),
-		schema,
 	}
+	args = append(args, options...)
+	args = append(args, schema)
 
 	path, err := exec.LookPath("mysql")
 	if err != nil {
@@ -95,17 +141,15 @@ func mysqlImport(username, password, host string, port int, schema, dump string)
 		}
 	}
 
-	cmd := exec.Command(path, args...)
+	logger.Debug("Running mysql", "host", host, "schema", schema)
+	cmd := exec.CommandContext(ctx, path, args...)
 
 	var out bytes.Buffer
 	var stderr bytes.Buffer
-	var stdin bytes.Buffer
-
-	stdin.WriteString(dump)
 
 	cmd.Stdout = &out
 	cmd.Stderr = &stderr
-	cmd.Stdin = &stdin
+	cmd.Stdin = r
 	if err := cmd.Run(); err != nil {
 		return "", errors.New(fmt.Sprintf("%s: %s", err, stderr.String()))
 	}
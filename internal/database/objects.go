@@ -0,0 +1,108 @@
+package database
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+)
+
+// ObjectType - type of a schema object which is not a base table
+type ObjectType string
+
+// Schema object types
+const (
+	ObjectView      ObjectType = "view"
+	ObjectTrigger   ObjectType = "trigger"
+	ObjectProcedure ObjectType = "procedure"
+	ObjectFunction  ObjectType = "function"
+	ObjectEvent     ObjectType = "event"
+)
+
+// objectCreateOrder - order in which object types are created; objects are dropped in reverse order
+var objectCreateOrder = map[ObjectType]int{
+	ObjectFunction:  0,
+	ObjectProcedure: 1,
+	ObjectView:      2,
+	ObjectTrigger:   3,
+	ObjectEvent:     4,
+}
+
+// Object - view, trigger, stored procedure, function or event
+type Object struct {
+	Type ObjectType
+	Name string
+	// Table - table the trigger is defined on
+	Table string
+	// Definition - create statement stripped of definer and schema name
+	Definition string
+}
+
+func (o Object) String() string {
+	return fmt.Sprintf("%s %s", o.Type, o.Name)
+}
+
+func (o Object) key() string {
+	return string(o.Type) + ":" + o.Name
+}
+
+// references - returns true when the object definition mentions the table or object name
+func (o Object) references(name string) bool {
+	return regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`).MatchString(o.Definition)
+}
+
+// sortObjects - sorts objects in creation order: functions, procedures, views, triggers and events.
+// Views are sorted so that a view comes after the views it selects from.
+func sortObjects(objects []Object) []Object {
+	sorted := make([]Object, len(objects))
+	copy(sorted, objects)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Type != sorted[j].Type {
+			return objectCreateOrder[sorted[i].Type] < objectCreateOrder[sorted[j].Type]
+		}
+
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	views := make(map[string]Object)
+	var viewNames []string
+	for _, o := range sorted {
+		if o.Type == ObjectView {
+			views[o.Name] = o
+			viewNames = append(viewNames, o.Name)
+		}
+	}
+
+	if len(viewNames) < 2 {
+		return sorted
+	}
+
+	deps := make(map[string][]string, len(viewNames))
+	for _, name := range viewNames {
+		for _, other := range viewNames {
+			if other != name && views[name].references(other) {
+				deps[name] = append(deps[name], other)
+			}
+		}
+	}
+
+	viewNames, _ = sortTables(viewNames, deps)
+	i := 0
+	for j, o := range sorted {
+		if o.Type == ObjectView {
+			sorted[j] = views[viewNames[i]]
+			i++
+		}
+	}
+
+	return sorted
+}
+
+// reverseObjects - returns objects in reverse order
+func reverseObjects(objects []Object) []Object {
+	reversed := make([]Object, len(objects))
+	for i, o := range objects {
+		reversed[len(objects)-1-i] = o
+	}
+
+	return reversed
+}
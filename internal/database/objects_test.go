@@ -0,0 +1,75 @@
+package database
+
+import (
+	"reflect"
+	"testing"
+)
+
+// objectNames - returns the objects as "type name"
+func objectNames(objects []Object) []string {
+	var names []string
+	for _, o := range objects {
+		names = append(names, o.String())
+	}
+
+	return names
+}
+
+func TestSortObjects(t *testing.T) {
+	tests := []struct {
+		name    string
+		objects []Object
+		want    []string
+	}{
+		{
+			name: "by type then name",
+			objects: []Object{
+				{Type: ObjectEvent, Name: "cleanup"},
+				{Type: ObjectTrigger, Name: "b_insert", Table: "users"},
+				{Type: ObjectTrigger, Name: "a_insert", Table: "orders"},
+				{Type: ObjectView, Name: "active_users", Definition: "select * from users"},
+				{Type: ObjectProcedure, Name: "archive"},
+				{Type: ObjectFunction, Name: "total"},
+			},
+			want: []string{
+				"function total", "procedure archive", "view active_users",
+				"trigger a_insert", "trigger b_insert", "event cleanup",
+			},
+		},
+		{
+			name: "views after the views they select from",
+			objects: []Object{
+				{Type: ObjectView, Name: "a_summary", Definition: "select count(*) from `z_active`"},
+				{Type: ObjectView, Name: "m_report", Definition: "select * from `a_summary`"},
+				{Type: ObjectTrigger, Name: "audit", Table: "users"},
+				{Type: ObjectView, Name: "z_active", Definition: "select * from users"},
+			},
+			want: []string{"view z_active", "view a_summary", "view m_report", "trigger audit"},
+		},
+		{
+			name: "names only referenced as part of other words",
+			objects: []Object{
+				{Type: ObjectView, Name: "b", Definition: "select * from a_table"},
+				{Type: ObjectView, Name: "a", Definition: "select 1"},
+			},
+			want: []string{"view a", "view b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := objectNames(sortObjects(tt.objects))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sortObjects() = %q, want %q", got, tt.want)
+			}
+
+			reversed := objectNames(reverseObjects(sortObjects(tt.objects)))
+			for i := range reversed {
+				if reversed[i] != tt.want[len(tt.want)-1-i] {
+					t.Errorf("reverseObjects() = %q, want the reverse of %q", reversed, tt.want)
+					break
+				}
+			}
+		})
+	}
+}
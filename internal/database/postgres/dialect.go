@@ -0,0 +1,99 @@
+package postgres
+
+import (
+	"fmt"
+
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+// DropTableStatement - returns the statement dropping a deleted table. The foreign keys of other
+// tables referencing it are dropped along with it.
+func (conn *Connection) DropTableStatement(table string) string {
+	return generateDropTableStatement(table)
+}
+
+// ForeignKeyChecksStatement - returns the statement enabling or disabling the foreign key checks.
+// Disabling them requires superuser privileges.
+func (conn *Connection) ForeignKeyChecksStatement(enabled bool) string {
+	if enabled {
+		return "set session_replication_role = origin"
+	}
+
+	return "set session_replication_role = replica"
+}
+
+// DropObjectStatement - returns the statement dropping an object
+func (conn *Connection) DropObjectStatement(o database.Object) string {
+	return fmt.Sprintf("drop %s if exists %s", o.Type, qualifiedName(o.Name))
+}
+
+// CreateObjectStatement - returns the statement creating an object
+func (conn *Connection) CreateObjectStatement(o database.Object) string {
+	return o.Definition + ";"
+}
+
+func generateDropTableStatement(table string) string {
+	return fmt.Sprintf("drop table if exists %s cascade", qualifiedName(table))
+}
+
+// referencesTable - temporary table holding the foreign keys of other tables referencing a recreated table
+const referencesTable = "dbsync_references"
+
+// generateRecreateTableStatement - returns the statements dropping a table about to be created again.
+// The foreign keys of other tables referencing it, which the drop cascades to, are saved first and
+// added back by generateRestoreReferencesStatement once the table is loaded. Their definitions
+// name the referenced tables relative to the search path, which is saved along with them, as the
+// statements of pg_dump clear it.
+func generateRecreateTableStatement(table string) string {
+	return fmt.Sprintf(
+		"create temporary table %s as select format('%%I.%%I', n.nspname, t.relname) as tbl, c.conname as name, "+
+			"pg_catalog.pg_get_constraintdef(c.oid) as def, pg_catalog.current_setting('search_path') as path "+
+			"from pg_catalog.pg_constraint c join pg_catalog.pg_class t on t.oid = c.conrelid "+
+			"join pg_catalog.pg_namespace n on n.oid = t.relnamespace "+
+			"where c.contype = 'f' and c.confrelid = to_regclass(%s) and c.conrelid <> c.confrelid;\n%s",
+		quoteIdent(referencesTable),
+		quoteString(qualifiedName(table)),
+		generateDropTableStatement(table),
+	)
+}
+
+// generateRestoreReferencesStatement - returns the statements adding back the foreign keys saved by
+// generateRecreateTableStatement. They are added as not valid, since the rows of the referencing
+// tables may reference rows which are gone, until these tables are synced too.
+func generateRestoreReferencesStatement() string {
+	return fmt.Sprintf(
+		"do $$ declare r record; path text := pg_catalog.current_setting('search_path'); begin "+
+			"for r in select * from %s loop "+
+			"perform pg_catalog.set_config('search_path', r.path, true); "+
+			"execute format('alter table %%s add constraint %%I %%s not valid', r.tbl, r.name, r.def); "+
+			"end loop; perform pg_catalog.set_config('search_path', path, true); end $$;\ndrop table %s;",
+		quoteIdent(referencesTable),
+		quoteIdent(referencesTable),
+	)
+}
+
+// replicationRoleSetting - custom setting holding the session_replication_role of the session
+// while the rows of a refreshed table are deleted and loaded again
+const replicationRoleSetting = "dbsync.session_replication_role"
+
+// GenerateDeleteStatement - returns the statements deleting the rows matching filter
+// with foreign key checks disabled. The previous session_replication_role is saved first
+// and set back by generateRestoreReplicationRoleStatement once the rows are loaded again.
+func GenerateDeleteStatement(table, filter string) string {
+	return fmt.Sprintf(
+		"do $$ begin perform pg_catalog.set_config(%s, pg_catalog.current_setting('session_replication_role'), false); end $$;\n"+
+			"set session_replication_role = replica;\ndelete from %s where (%s);\n",
+		quoteString(replicationRoleSetting),
+		qualifiedName(table),
+		filter,
+	)
+}
+
+// generateRestoreReplicationRoleStatement - returns the statement setting back the
+// session_replication_role saved by GenerateDeleteStatement
+func generateRestoreReplicationRoleStatement() string {
+	return fmt.Sprintf(
+		"do $$ begin perform pg_catalog.set_config('session_replication_role', pg_catalog.current_setting(%s), false); end $$;",
+		quoteString(replicationRoleSetting),
+	)
+}
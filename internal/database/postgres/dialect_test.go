@@ -0,0 +1,98 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+func TestDialectStatements(t *testing.T) {
+	conn := &Connection{}
+
+	tests := []struct {
+		name, got, want string
+	}{
+		{"drop table", conn.DropTableStatement("users"), `drop table if exists "public"."users" cascade`},
+		{"drop quoted table", conn.DropTableStatement(`odd"name`), `drop table if exists "public"."odd""name" cascade`},
+		{"foreign key checks disabled", conn.ForeignKeyChecksStatement(false), "set session_replication_role = replica"},
+		{"foreign key checks enabled", conn.ForeignKeyChecksStatement(true), "set session_replication_role = origin"},
+		{
+			"drop view",
+			conn.DropObjectStatement(database.Object{Type: database.ObjectView, Name: "active_users"}),
+			`drop view if exists "public"."active_users"`,
+		},
+		{
+			"create view",
+			conn.CreateObjectStatement(database.Object{Type: database.ObjectView, Name: "active_users", Definition: "CREATE VIEW active_users AS SELECT 1"}),
+			"CREATE VIEW active_users AS SELECT 1;",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.got != tt.want {
+				t.Errorf("got %q, want %q", tt.got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRecreateTableStatement(t *testing.T) {
+	got := generateRecreateTableStatement(`it's "odd"`)
+
+	// the foreign keys referencing the table are saved before the drop cascades to them
+	wants := []string{
+		`create temporary table "dbsync_references" as select `,
+		"pg_catalog.current_setting('search_path') as path ",
+		"where c.contype = 'f' and c.confrelid = to_regclass('\"public\".\"it''s \"\"odd\"\"\"') and c.conrelid <> c.confrelid;\n",
+	}
+	for _, want := range wants {
+		if !strings.Contains(got, want) {
+			t.Errorf("generateRecreateTableStatement() = %q, want %q in it", got, want)
+		}
+	}
+
+	if want := "\n" + `drop table if exists "public"."it's ""odd""" cascade`; !strings.HasSuffix(got, want) {
+		t.Errorf("generateRecreateTableStatement() = %q, want it to end with %q", got, want)
+	}
+}
+
+func TestRestoreReferencesStatement(t *testing.T) {
+	got := generateRestoreReferencesStatement()
+
+	wants := []string{
+		`for r in select * from "dbsync_references" loop `,
+		"perform pg_catalog.set_config('search_path', r.path, true); ",
+		"execute format('alter table %s add constraint %I %s not valid', r.tbl, r.name, r.def); ",
+		"perform pg_catalog.set_config('search_path', path, true); end $$;\n",
+	}
+	for _, want := range wants {
+		if !strings.Contains(got, want) {
+			t.Errorf("generateRestoreReferencesStatement() = %q, want %q in it", got, want)
+		}
+	}
+
+	if want := `drop table "dbsync_references";`; !strings.HasSuffix(got, want) {
+		t.Errorf("generateRestoreReferencesStatement() = %q, want it to end with %q", got, want)
+	}
+}
+
+func TestDeleteStatement(t *testing.T) {
+	got := GenerateDeleteStatement(`odd"name`, "created_at > now() - interval '1 day'")
+	want := "do $$ begin perform pg_catalog.set_config('dbsync.session_replication_role', " +
+		"pg_catalog.current_setting('session_replication_role'), false); end $$;\n" +
+		"set session_replication_role = replica;\n" +
+		`delete from "public"."odd""name" where (created_at > now() - interval '1 day');` + "\n"
+	if got != want {
+		t.Errorf("GenerateDeleteStatement() =\n%s\nwant\n%s", got, want)
+	}
+
+	// the role saved before the delete is set back once the rows are loaded again
+	restore := generateRestoreReplicationRoleStatement()
+	want = "do $$ begin perform pg_catalog.set_config('session_replication_role', " +
+		"pg_catalog.current_setting('dbsync.session_replication_role'), false); end $$;"
+	if restore != want {
+		t.Errorf("generateRestoreReplicationRoleStatement() =\n%s\nwant\n%s", restore, want)
+	}
+}
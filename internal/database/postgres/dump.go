@@ -0,0 +1,159 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+// refreshTable - temporary table the rows of a refreshed table are loaded in
+const refreshTable = "dbsync_refresh"
+
+// Dumper - dumps tables with pg_dump and psql
+type Dumper struct {
+	cfg database.ConnectionConfig
+}
+
+// NewDumper - constructor
+func NewDumper(cfg database.ConnectionConfig) *Dumper {
+	return &Dumper{
+		cfg: cfg,
+	}
+}
+
+// DumpTableTo - streams the sql dropping and creating a single table and inserting its rows to w.
+// Filtered tables only include the rows matching their filter. The foreign keys of other tables
+// referencing the table are kept.
+func (d *Dumper) DumpTableTo(ctx context.Context, w io.Writer, table string) error {
+	if _, err := io.WriteString(w, generateRecreateTableStatement(table)+";\n"); err != nil {
+		return err
+	}
+
+	if err := d.dumpTableTo(ctx, w, table); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, generateRestoreReferencesStatement()+"\n")
+
+	return err
+}
+
+func (d *Dumper) dumpTableTo(ctx context.Context, w io.Writer, table string) error {
+	filter, ok := d.cfg.Filter(table)
+	if !ok {
+		return pgDumpTo(ctx, w, d.cfg, table)
+	}
+
+	if err := pgDumpTo(ctx, w, d.cfg, table, "--schema-only"); err != nil {
+		return err
+	}
+
+	return d.copyTo(ctx, w, table, qualifiedName(table), filter)
+}
+
+// RefreshTableTo - streams the sql deleting the rows of a filtered table matching its filter
+// and inserting them again. The rows are loaded in a temporary table first, as a master row may now
+// match the filter while its key is still used by a slave row outside of it: the slave rows sharing
+// the primary key of a loaded row are deleted before the rows are inserted. The foreign key checks
+// are disabled meanwhile and set back to their previous state at the end.
+func (d *Dumper) RefreshTableTo(ctx context.Context, w io.Writer, table string) error {
+	filter, ok := d.cfg.Filter(table)
+	if !ok {
+		return fmt.Errorf("refresh %s: table has no filter", table)
+	}
+
+	key, err := d.primaryKey(ctx, table)
+	if err != nil {
+		return err
+	}
+
+	if _, err := io.WriteString(w, GenerateDeleteStatement(table, filter)); err != nil {
+		return err
+	}
+
+	if _, err := fmt.Fprintf(
+		w,
+		"create temporary table %s (like %s);\n",
+		quoteIdent(refreshTable),
+		qualifiedName(table),
+	); err != nil {
+		return err
+	}
+
+	if err := d.copyTo(ctx, w, table, quoteIdent(refreshTable), filter); err != nil {
+		return err
+	}
+
+	if len(key) > 0 {
+		conditions := make([]string, len(key))
+		for i, column := range key {
+			conditions[i] = fmt.Sprintf("t.%s = r.%s", quoteIdent(column), quoteIdent(column))
+		}
+
+		if _, err := fmt.Fprintf(
+			w,
+			"delete from %s t using %s r where %s;\n",
+			qualifiedName(table),
+			quoteIdent(refreshTable),
+			strings.Join(conditions, " and "),
+		); err != nil {
+			return err
+		}
+	}
+
+	_, err = fmt.Fprintf(
+		w,
+		"insert into %s overriding system value select * from %s;\ndrop table %s;\n%s\n",
+		qualifiedName(table),
+		quoteIdent(refreshTable),
+		quoteIdent(refreshTable),
+		generateRestoreReplicationRoleStatement(),
+	)
+
+	return err
+}
+
+// primaryKey - returns the primary key columns of a master table, none when it has no primary key
+func (d *Dumper) primaryKey(ctx context.Context, table string) ([]string, error) {
+	q := fmt.Sprintf(
+		"select a.attname from pg_catalog.pg_index i "+
+			"join pg_catalog.pg_attribute a on a.attrelid = i.indrelid and a.attnum = any(i.indkey) "+
+			"where i.indrelid = %s::regclass and i.indisprimary "+
+			"order by array_position(i.indkey::int2[], a.attnum)",
+		quoteString(qualifiedName(table)),
+	)
+
+	var out bytes.Buffer
+	if err := run(ctx, "psql", d.cfg, []string{"--no-psqlrc", "--quiet", "--tuples-only", "--no-align", "--command=" + q}, nil, &out); err != nil {
+		return nil, fmt.Errorf("Primary Key %s: %s", table, err)
+	}
+
+	var key []string
+	for _, column := range strings.Split(out.String(), "\n") {
+		if column != "" {
+			key = append(key, column)
+		}
+	}
+
+	return key, nil
+}
+
+// copyTo - streams a copy statement loading the rows of table matching filter into target
+func (d *Dumper) copyTo(ctx context.Context, w io.Writer, table, target, filter string) error {
+	if _, err := fmt.Fprintf(w, "copy %s from stdin;\n", target); err != nil {
+		return err
+	}
+
+	q := fmt.Sprintf("copy (select * from %s where (%s)) to stdout", qualifiedName(table), filter)
+	if err := psqlTo(ctx, w, d.cfg, q); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\\.\n")
+
+	return err
+}
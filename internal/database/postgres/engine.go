@@ -0,0 +1,24 @@
+package postgres
+
+import (
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+func init() {
+	database.Register(DriverName, engine{})
+}
+
+// engine - creates postgres connections, dumpers and importers
+type engine struct{}
+
+func (engine) Connection(cfg database.ConnectionConfig) database.Connection {
+	return New(cfg)
+}
+
+func (engine) Dumper(cfg database.ConnectionConfig) database.Dumper {
+	return NewDumper(cfg)
+}
+
+func (engine) Importer(cfg database.ConnectionConfig) database.Importer {
+	return NewImporter(cfg)
+}
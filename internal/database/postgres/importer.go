@@ -0,0 +1,41 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/vcraescu/dbsync/internal/compress"
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+// Importer - runs sql dumps with psql
+type Importer struct {
+	cfg database.ConnectionConfig
+}
+
+// NewImporter - import constructor
+func NewImporter(cfg database.ConnectionConfig) *Importer {
+	return &Importer{
+		cfg: cfg,
+	}
+}
+
+// Import - import sql dump
+func (imp *Importer) Import(ctx context.Context, dump string) error {
+	return imp.ImportFrom(ctx, strings.NewReader(dump))
+}
+
+// ImportFrom - import sql dump read from r; gzip and zstd compressed dumps are
+// detected by their magic bytes and decompressed. The dump runs in a single transaction,
+// so an import cancelled through ctx leaves the database unchanged.
+func (imp *Importer) ImportFrom(ctx context.Context, r io.Reader) error {
+	zr, err := compress.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("Import: %s", err)
+	}
+	defer zr.Close()
+
+	return psqlImportFrom(ctx, zr, imp.cfg)
+}
@@ -0,0 +1,309 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"strconv"
+	"strings"
+
+	_ "github.com/lib/pq" // postgres
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+// DriverName - name of the postgres driver
+const DriverName = "postgres"
+
+const (
+	defaultMaxConnections = 4
+	// namespace - postgres schema holding the synced tables; the configured schema is the database name
+	namespace = "public"
+)
+
+// Connection - postgres connection
+type Connection struct {
+	db  *sql.DB
+	cfg database.ConnectionConfig
+}
+
+// New - creates new postgres connection.
+func New(cfg database.ConnectionConfig) *Connection {
+	conn := &Connection{
+		cfg: cfg,
+	}
+
+	return conn
+}
+
+// Driver - returns the driver name
+func (conn *Connection) Driver() string {
+	return DriverName
+}
+
+// Host - returns the server host
+func (conn *Connection) Host() string {
+	return conn.cfg.Host
+}
+
+// Schema - returns the database name
+func (conn *Connection) Schema() string {
+	return conn.cfg.Schema
+}
+
+// Filter - returns the where condition of a filtered table
+func (conn *Connection) Filter(table string) (string, bool) {
+	filter, ok := conn.cfg.Filter(table)
+
+	return filter, ok
+}
+
+func (conn *Connection) isOpened() bool {
+	return conn.db != nil
+}
+
+// Open - open connection
+func (conn *Connection) Open(ctx context.Context) error {
+	if conn.isOpened() {
+		return nil
+	}
+
+	db, err := sql.Open(DriverName, generateDSN(conn.cfg))
+	if err != nil {
+		return err
+	}
+
+	db.SetMaxOpenConns(conn.maxConnections())
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return err
+	}
+
+	conn.db = db
+
+	return nil
+}
+
+func (conn *Connection) maxConnections() int {
+	if conn.cfg.MaxConnections <= 0 {
+		return defaultMaxConnections
+	}
+
+	return conn.cfg.MaxConnections
+}
+
+// Close - close postgres connection
+func (conn *Connection) Close() error {
+	if conn.db == nil {
+		return nil
+	}
+
+	return conn.db.Close()
+}
+
+// TableNames - returns base table names
+func (conn *Connection) TableNames(ctx context.Context) ([]string, error) {
+	rows, err := conn.db.QueryContext(ctx,
+		"select table_name from information_schema.tables where table_schema = $1 and table_type = 'BASE TABLE' "+
+			"order by table_name",
+		namespace,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var names []string
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return nil, err
+		}
+
+		names = append(names, name)
+	}
+
+	return names, rows.Err()
+}
+
+// TableDependencies - returns the tables referenced through foreign keys by every table
+func (conn *Connection) TableDependencies(ctx context.Context) (map[string][]string, error) {
+	rows, err := conn.db.QueryContext(ctx,
+		"select distinct t.relname, r.relname from pg_catalog.pg_constraint c "+
+			"join pg_catalog.pg_class t on t.oid = c.conrelid "+
+			"join pg_catalog.pg_class r on r.oid = c.confrelid "+
+			"join pg_catalog.pg_namespace tn on tn.oid = t.relnamespace "+
+			"join pg_catalog.pg_namespace rn on rn.oid = r.relnamespace "+
+			"where c.contype = 'f' and tn.nspname = $1 and rn.nspname = $1 "+
+			"order by 1, 2",
+		namespace,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Table Dependencies: %s", err)
+	}
+	defer rows.Close()
+
+	deps := make(map[string][]string)
+	for rows.Next() {
+		var table, referenced string
+		if err := rows.Scan(&table, &referenced); err != nil {
+			return nil, fmt.Errorf("Table Dependencies: %s", err)
+		}
+
+		deps[table] = append(deps[table], referenced)
+	}
+
+	return deps, rows.Err()
+}
+
+// Objects - returns the views. Triggers, functions and materialized views are not synced.
+func (conn *Connection) Objects(ctx context.Context) ([]database.Object, error) {
+	rows, err := conn.db.QueryContext(ctx,
+		"select viewname, pg_catalog.pg_get_viewdef(c.oid) from pg_catalog.pg_views v "+
+			"join pg_catalog.pg_class c on c.relname = v.viewname "+
+			"join pg_catalog.pg_namespace n on n.oid = c.relnamespace and n.nspname = v.schemaname "+
+			"where v.schemaname = $1 order by viewname",
+		namespace,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Objects: %s", err)
+	}
+	defer rows.Close()
+
+	var objects []database.Object
+	for rows.Next() {
+		var name, def string
+		if err := rows.Scan(&name, &def); err != nil {
+			return nil, fmt.Errorf("Objects: %s", err)
+		}
+
+		objects = append(objects, database.Object{
+			Type:       database.ObjectView,
+			Name:       name,
+			Definition: fmt.Sprintf("create view %s as\n%s", quoteIdent(name), strings.TrimRight(strings.TrimSpace(def), ";")),
+		})
+	}
+
+	return objects, rows.Err()
+}
+
+// CountRows - returns the row counts of the given tables, filtered tables only counting their matching rows
+func (conn *Connection) CountRows(ctx context.Context, names ...string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(names))
+	for _, table := range names {
+		q := fmt.Sprintf("select count(*) from %s", qualifiedName(table))
+		if filter, ok := conn.cfg.Filter(table); ok {
+			q += fmt.Sprintf(" where (%s)", filter)
+		}
+
+		var count int64
+		if err := conn.db.QueryRowContext(ctx, q).Scan(&count); err != nil {
+			return nil, fmt.Errorf("Count Rows (%s): %s", table, err)
+		}
+
+		counts[table] = count
+	}
+
+	return counts, nil
+}
+
+// EstimateRows - returns the row counts of the given tables estimated by the last vacuum or
+// analyze. Filtered tables and tables never analyzed are left out.
+func (conn *Connection) EstimateRows(ctx context.Context, names ...string) (map[string]int64, error) {
+	rows, err := conn.db.QueryContext(ctx,
+		"select c.relname, c.reltuples::bigint from pg_catalog.pg_class c "+
+			"join pg_catalog.pg_namespace n on n.oid = c.relnamespace "+
+			"where n.nspname = $1 and c.relkind in ('r', 'p')",
+		namespace,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Estimate Rows: %s", err)
+	}
+	defer rows.Close()
+
+	wanted := make(map[string]bool, len(names))
+	for _, table := range names {
+		if _, ok := conn.cfg.Filter(table); !ok {
+			wanted[table] = true
+		}
+	}
+
+	counts := make(map[string]int64, len(wanted))
+	for rows.Next() {
+		var table string
+		var count int64
+		if err := rows.Scan(&table, &count); err != nil {
+			return nil, fmt.Errorf("Estimate Rows: %s", err)
+		}
+
+		// -1 for tables never analyzed, since postgres 14
+		if wanted[table] && count >= 0 {
+			counts[table] = count
+		}
+	}
+
+	return counts, rows.Err()
+}
+
+// TableChecksum - returns table checksum
+func (conn *Connection) TableChecksum(ctx context.Context, table string) (string, error) {
+	q := fmt.Sprintf(
+		"select coalesce(md5(string_agg(md5(t::text), '' order by md5(t::text))), '') from %s as t",
+		qualifiedName(table),
+	)
+
+	if filter, ok := conn.cfg.Filter(table); ok {
+		q += fmt.Sprintf(" where (%s)", filter)
+	}
+
+	var checksum string
+	if err := conn.db.QueryRowContext(ctx, q).Scan(&checksum); err != nil {
+		return "", fmt.Errorf("Table Checksum (%s): %s", table, err)
+	}
+
+	return checksum, nil
+}
+
+// TableChecksums - returns checksums of all the tables
+func (conn *Connection) TableChecksums(ctx context.Context) (map[string]string, error) {
+	names, err := conn.TableNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return conn.ChecksumTables(ctx, names...)
+}
+
+// ChecksumTables - returns content checksums of the given tables, computed in parallel
+// on at most MaxConnections connections
+func (conn *Connection) ChecksumTables(ctx context.Context, names ...string) (map[string]string, error) {
+	return database.ParallelChecksums(ctx, conn.maxConnections(), conn.TableChecksum, names...)
+}
+
+func generateDSN(cfg database.ConnectionConfig) string {
+	u := url.URL{
+		Scheme: "postgres",
+		User:   url.UserPassword(cfg.Username, cfg.Password),
+		Host:   cfg.Host + ":" + strconv.Itoa(cfg.Port),
+		Path:   "/" + cfg.Schema,
+	}
+
+	q := u.Query()
+	if cfg.Timezone != "" {
+		q.Set("timezone", cfg.Timezone)
+	}
+
+	u.RawQuery = q.Encode()
+
+	return u.String()
+}
+
+// quoteIdent - quotes a table, column or view name
+func quoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// qualifiedName - returns the quoted table name qualified with the namespace
+func qualifiedName(table string) string {
+	return quoteIdent(namespace) + "." + quoteIdent(table)
+}
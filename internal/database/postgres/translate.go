@@ -0,0 +1,180 @@
+package postgres
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/vcraescu/dbsync/internal/database"
+	"github.com/vcraescu/dbsync/internal/database/mysql"
+	"github.com/vcraescu/dbsync/internal/database/typemap"
+)
+
+func init() {
+	typemap.Register(mysql.DriverName, DriverName, typemap.Mapping{
+		Types: map[string]string{
+			"tinyint(1)":         "boolean",
+			"bool":               "boolean",
+			"boolean":            "boolean",
+			"bit(1)":             "boolean",
+			"bit":                "bigint",
+			"tinyint":            "smallint",
+			"tinyint unsigned":   "smallint",
+			"smallint":           "smallint",
+			"smallint unsigned":  "integer",
+			"mediumint":          "integer",
+			"mediumint unsigned": "integer",
+			"int":                "integer",
+			"integer":            "integer",
+			"int unsigned":       "bigint",
+			"integer unsigned":   "bigint",
+			"bigint":             "bigint",
+			"bigint unsigned":    "numeric(20)",
+			"year":               "smallint",
+			"decimal":            "numeric(*)",
+			"numeric":            "numeric(*)",
+			"dec":                "numeric(*)",
+			"fixed":              "numeric(*)",
+			"float":              "real",
+			"double":             "double precision",
+			"real":               "double precision",
+			"date":               "date",
+			"datetime":           "timestamp(*)",
+			"timestamp":          "timestamp(*)",
+			"time":               "time(*)",
+			"char":               "char(*)",
+			"varchar":            "varchar(*)",
+			"json":               "jsonb",
+			"binary":             "bytea",
+			"varbinary":          "bytea",
+			"tinyblob":           "bytea",
+			"blob":               "bytea",
+			"mediumblob":         "bytea",
+			"longblob":           "bytea",
+			"geometry":           "bytea",
+			"point":              "bytea",
+			"linestring":         "bytea",
+			"polygon":            "bytea",
+			"multipoint":         "bytea",
+			"multilinestring":    "bytea",
+			"multipolygon":       "bytea",
+			"geometrycollection": "bytea",
+		},
+		Default: "text",
+	})
+}
+
+// FromMySQL - returns a dumper translating the mysqldump output of d to postgres statements,
+// column types being mapped by types
+func FromMySQL(d database.Dumper, types *typemap.Mapper) database.Dumper {
+	return mysql.Translate(d, &target{
+		types: types,
+	})
+}
+
+// target - translates mysqldump output to postgres statements
+type target struct {
+	types *typemap.Mapper
+}
+
+func (t *target) QuoteIdent(name string) string {
+	return quoteIdent(name)
+}
+
+// Literal - binary values are written as bytea; NUL bytes, which postgres strings cannot hold, are left out
+func (t *target) Literal(text string, binary bool) string {
+	if binary {
+		return `'\x` + hex.EncodeToString([]byte(text)) + `'`
+	}
+
+	return quoteString(strings.Replace(text, "\x00", "", -1))
+}
+
+// Number - numbers are quoted, postgres converting the untyped literal to any column type,
+// e.g. 0 and 1 of mysql booleans
+func (t *target) Number(text string) string {
+	return quoteString(text)
+}
+
+// DropTableStatement - drops a table about to be created again, saving the foreign keys referencing it
+func (t *target) DropTableStatement(table string) string {
+	return generateRecreateTableStatement(table)
+}
+
+func (t *target) CreateTableStatement(table *mysql.Table) string {
+	var defs []string
+	for _, col := range table.Columns {
+		typ := t.types.Map(table.Name, col.Name, col.Type)
+		name := quoteIdent(col.Name)
+
+		def := name + " " + typ.Name
+		if identity(col, typ) {
+			def += " GENERATED BY DEFAULT AS IDENTITY"
+		}
+
+		def += col.Attributes
+		if check := typ.Check(name); check != "" {
+			def += " " + check
+		}
+
+		defs = append(defs, def)
+	}
+
+	if table.PrimaryKey != "" {
+		defs = append(defs, "PRIMARY KEY "+table.PrimaryKey)
+	}
+
+	defs = append(defs, table.Constraints...)
+
+	stmt := fmt.Sprintf("CREATE TABLE %s (\n  %s\n);", qualifiedName(table.Name), strings.Join(defs, ",\n  "))
+	for _, index := range table.Indexes {
+		stmt += fmt.Sprintf(
+			"\nCREATE INDEX %s ON %s %s;",
+			quoteIdent(table.Name+"_"+index.Name),
+			qualifiedName(table.Name),
+			index.Parts,
+		)
+	}
+
+	return stmt
+}
+
+// InsertedStatement - moves the identity sequences past the inserted rows and adds back the foreign
+// keys referencing the table
+func (t *target) InsertedStatement(table *mysql.Table) string {
+	stmts := []string{generateRestoreReferencesStatement()}
+	for _, col := range table.Columns {
+		if !identity(col, t.types.Map(table.Name, col.Name, col.Type)) {
+			continue
+		}
+
+		stmts = append(stmts, fmt.Sprintf(
+			"select setval(pg_get_serial_sequence(%s, %s), coalesce(max(%s), 0) + 1, false) from %s;",
+			quoteString(qualifiedName(table.Name)),
+			quoteString(col.Name),
+			quoteIdent(col.Name),
+			qualifiedName(table.Name),
+		))
+	}
+
+	return strings.Join(stmts, "\n")
+}
+
+// identity - returns true when the column is an auto increment column of an integer type
+func identity(col mysql.Column, typ typemap.Type) bool {
+	if !col.AutoIncrement {
+		return false
+	}
+
+	switch typ.Name {
+	case "smallint", "integer", "bigint":
+		return true
+	}
+
+	return false
+}
+
+// quoteString - quotes a string literal
+func quoteString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
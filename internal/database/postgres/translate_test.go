@@ -0,0 +1,97 @@
+package postgres
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/vcraescu/dbsync/internal/database/mysql"
+	"github.com/vcraescu/dbsync/internal/database/typemap"
+)
+
+func newTarget(t *testing.T) *target {
+	t.Helper()
+
+	types, err := typemap.New(mysql.DriverName, DriverName, nil)
+	if err != nil {
+		t.Fatalf("typemap.New() error = %s", err)
+	}
+
+	return &target{types: types}
+}
+
+func TestTargetLiteral(t *testing.T) {
+	tests := []struct {
+		text   string
+		binary bool
+		want   string
+	}{
+		{"it's", false, "'it''s'"},
+		{"\x00\xff", true, `'\x00ff'`},
+		{"a\x00b", false, "'ab'"},
+		{`back\slash`, false, `'back\slash'`},
+	}
+
+	tg := newTarget(t)
+	for _, tt := range tests {
+		if got := tg.Literal(tt.text, tt.binary); got != tt.want {
+			t.Errorf("Literal(%q, %v) = %s, want %s", tt.text, tt.binary, got, tt.want)
+		}
+	}
+
+	if got := tg.Number("1"); got != "'1'" {
+		t.Errorf("Number() = %s, want '1'", got)
+	}
+}
+
+func TestTargetCreateTableStatement(t *testing.T) {
+	table := &mysql.Table{
+		Name: "users",
+		Columns: []mysql.Column{
+			{Name: "id", Type: "int unsigned", Attributes: " NOT NULL", AutoIncrement: true},
+			{Name: "active", Type: "tinyint(1)", Attributes: " NOT NULL DEFAULT '1'"},
+			{Name: "status", Type: "enum('new','done')"},
+			{Name: "total", Type: "decimal(10,2)"},
+		},
+		PrimaryKey: `("id")`,
+		Indexes:    []mysql.Index{{Name: "status", Parts: `("status")`}},
+	}
+
+	want := "CREATE TABLE \"public\".\"users\" (\n" +
+		"  \"id\" bigint GENERATED BY DEFAULT AS IDENTITY NOT NULL,\n" +
+		"  \"active\" boolean NOT NULL DEFAULT '1',\n" +
+		"  \"status\" text CHECK (\"status\" IN ('new','done')),\n" +
+		"  \"total\" numeric(10,2),\n" +
+		"  PRIMARY KEY (\"id\")\n" +
+		");\n" +
+		"CREATE INDEX \"users_status\" ON \"public\".\"users\" (\"status\");"
+
+	if got := newTarget(t).CreateTableStatement(table); got != want {
+		t.Errorf("CreateTableStatement() =\n%s\nwant:\n%s", got, want)
+	}
+}
+
+func TestTargetInsertedStatement(t *testing.T) {
+	tg := newTarget(t)
+	table := &mysql.Table{
+		Name: "users",
+		Columns: []mysql.Column{
+			{Name: "id", Type: "int", AutoIncrement: true},
+			{Name: "code", Type: "varchar(10)", AutoIncrement: true},
+		},
+	}
+
+	stmts := strings.Split(tg.InsertedStatement(table), "\n")
+	want := `select setval(pg_get_serial_sequence('"public"."users"', 'id'), coalesce(max("id"), 0) + 1, false) from "public"."users";`
+	if last := stmts[len(stmts)-1]; last != want {
+		t.Errorf("InsertedStatement() ends with %s, want %s", last, want)
+	}
+
+	if !strings.HasPrefix(stmts[0], "do $$") {
+		t.Errorf("InsertedStatement() does not restore the references first: %s", stmts[0])
+	}
+
+	table.Columns = table.Columns[1:]
+	if got := tg.InsertedStatement(table); got != generateRestoreReferencesStatement() {
+		t.Errorf("InsertedStatement() without identity = %s", got)
+	}
+}
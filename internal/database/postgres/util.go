@@ -0,0 +1,75 @@
+package postgres
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+func pgDumpTo(ctx context.Context, w io.Writer, cfg database.ConnectionConfig, table string, options ...string) error {
+	args := []string{
+		"--no-owner",
+		"--no-privileges",
+		"--table=" + qualifiedName(table),
+	}
+	args = append(args, options...)
+
+	return run(ctx, "pg_dump", cfg, args, nil, w)
+}
+
+func psqlTo(ctx context.Context, w io.Writer, cfg database.ConnectionConfig, q string) error {
+	return run(ctx, "psql", cfg, []string{"--no-psqlrc", "--quiet", "--command=" + q}, nil, w)
+}
+
+func psqlImportFrom(ctx context.Context, r io.Reader, cfg database.ConnectionConfig) error {
+	var out bytes.Buffer
+
+	return run(ctx, "psql", cfg, []string{"--no-psqlrc", "--quiet", "--set=ON_ERROR_STOP=1", "--single-transaction"}, r, &out)
+}
+
+// run - runs a postgres client program against the configured database.
+// The password is passed through the environment so it does not show up in the process list.
+// The program is killed once ctx is done.
+func run(ctx context.Context, program string, cfg database.ConnectionConfig, options []string, stdin io.Reader, stdout io.Writer) error {
+	args := []string{
+		"--host=" + cfg.Host,
+		"--port=" + strconv.Itoa(cfg.Port),
+		"--username=" + cfg.Username,
+		"--dbname=" + cfg.Schema,
+		"--no-password",
+	}
+	args = append(args, options...)
+
+	path, err := exec.LookPath(program)
+	if err != nil {
+		path, err = filepath.Abs(filepath.Join("bin", program))
+		if err != nil {
+			return fmt.Errorf("%s not found", program)
+		}
+	}
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Env = append(os.Environ(), "PGPASSWORD="+cfg.Password)
+	if cfg.Timezone != "" {
+		cmd.Env = append(cmd.Env, "PGTZ="+cfg.Timezone)
+	}
+
+	var stderr bytes.Buffer
+
+	cmd.Stdin = stdin
+	cmd.Stdout = stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return errors.New(fmt.Sprintf("%s: %s", err, stderr.String()))
+	}
+
+	return nil
+}
@@ -0,0 +1,275 @@
+package database
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Progress - live counters of a diff and of a sync, updated by the drivers and the syncer
+// through the context and read by progress displays. Safe for concurrent use.
+type Progress struct {
+	mu sync.Mutex
+
+	checksumTotal int
+	checksumDone  int
+	checksumStart time.Time
+	checksumLast  string
+
+	tables    []*TableProgress
+	byName    map[string]*TableProgress
+	syncStart time.Time
+}
+
+// TableProgress - counters of a synced table
+type TableProgress struct {
+	Table string
+	// Rows - rows of the master table, -1 when unknown
+	Rows int64
+	// Bytes - size of the sql dumped from master and imported into slave
+	Bytes int64
+	// DumpedRows - rows dumped so far, counted from the inserted values, so approximate
+	DumpedRows int64
+	// Statements - statements read by the import client so far
+	Statements int64
+	Started    time.Time
+	Finished   time.Time
+	Err        error
+}
+
+// ProgressSnapshot - copy of the counters at a point in time
+type ProgressSnapshot struct {
+	// ChecksumDone, ChecksumTotal - table checksums computed on master and slave, and to compute
+	ChecksumDone  int
+	ChecksumTotal int
+	ChecksumStart time.Time
+	// ChecksumLast - table whose checksum was computed last
+	ChecksumLast string
+	// Tables - synced tables, in sync order
+	Tables    []TableProgress
+	SyncStart time.Time
+}
+
+// NewProgress - constructor
+func NewProgress() *Progress {
+	return &Progress{
+		byName: make(map[string]*TableProgress),
+	}
+}
+
+type progressKey struct{}
+
+// WithProgress - returns ctx reporting the progress of the diffs and syncs using it to p
+func WithProgress(ctx context.Context, p *Progress) context.Context {
+	return context.WithValue(ctx, progressKey{}, p)
+}
+
+// ProgressFrom - returns the progress reported to by ctx, nil when none
+func ProgressFrom(ctx context.Context) *Progress {
+	p, _ := ctx.Value(progressKey{}).(*Progress)
+
+	return p
+}
+
+// TableChecksummed - reports the content checksum of a table computed, on either side
+func TableChecksummed(ctx context.Context, table string) {
+	p := ProgressFrom(ctx)
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.checksumDone++
+	p.checksumLast = table
+}
+
+// startChecksums - starts counting the content checksums of the given number of tables on both sides
+func (p *Progress) startChecksums(tables int) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.checksumTotal += 2 * tables
+	if p.checksumStart.IsZero() {
+		p.checksumStart = time.Now()
+	}
+}
+
+// StartSync - starts counting the sync of the given tables, with their master row counts if known
+func (p *Progress) StartSync(tables []string, rows map[string]int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.syncStart = time.Now()
+	for _, table := range tables {
+		if _, ok := p.byName[table]; ok {
+			continue
+		}
+
+		n, ok := rows[table]
+		if !ok {
+			n = -1
+		}
+
+		t := &TableProgress{Table: table, Rows: n}
+		p.tables = append(p.tables, t)
+		p.byName[table] = t
+	}
+}
+
+// update - applies f to the counters of a table, added when not started with the sync
+func (p *Progress) update(table string, f func(t *TableProgress)) {
+	if p == nil {
+		return
+	}
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	t, ok := p.byName[table]
+	if !ok {
+		t = &TableProgress{Table: table, Rows: -1}
+		p.tables = append(p.tables, t)
+		p.byName[table] = t
+	}
+
+	f(t)
+}
+
+// Snapshot - returns a copy of the counters
+func (p *Progress) Snapshot() ProgressSnapshot {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	s := ProgressSnapshot{
+		ChecksumDone:  p.checksumDone,
+		ChecksumTotal: p.checksumTotal,
+		ChecksumStart: p.checksumStart,
+		ChecksumLast:  p.checksumLast,
+		Tables:        make([]TableProgress, 0, len(p.tables)),
+		SyncStart:     p.syncStart,
+	}
+	for _, t := range p.tables {
+		s.Tables = append(s.Tables, *t)
+	}
+
+	return s
+}
+
+// Totals - returns the rows of the synced tables, -1 when any of them is unknown, and the rows
+// and bytes synced so far
+func (s ProgressSnapshot) Totals() (rows, doneRows, bytes int64) {
+	for _, t := range s.Tables {
+		bytes += t.Bytes
+		done := t.DumpedRows
+		// the count is approximate, a synced table is entirely dumped
+		if !t.Finished.IsZero() && t.Err == nil && done < t.Rows {
+			done = t.Rows
+		}
+		doneRows += done
+
+		if t.Rows < 0 || rows < 0 {
+			rows = -1
+			continue
+		}
+
+		rows += t.Rows
+	}
+
+	return rows, doneRows, bytes
+}
+
+// ETA - returns the estimated time left to sync the remaining rows at the average rate so far,
+// false when unknown
+func (s ProgressSnapshot) ETA(now time.Time) (time.Duration, bool) {
+	rows, doneRows, _ := s.Totals()
+	elapsed := now.Sub(s.SyncStart)
+	if s.SyncStart.IsZero() || rows < 0 || doneRows == 0 || elapsed <= 0 {
+		return 0, false
+	}
+
+	if doneRows >= rows {
+		return 0, true
+	}
+
+	rate := float64(doneRows) / elapsed.Seconds()
+
+	return time.Duration(float64(rows-doneRows) / rate * float64(time.Second)), true
+}
+
+// sqlCounter - counts the statements and the inserted rows of a sql stream, from insert and
+// replace statements, extended inserts and copy blocks. Values containing "),(" are miscounted.
+type sqlCounter struct {
+	midLine    bool
+	prefix     []byte
+	inCopy     bool
+	prev       [2]byte
+	statements int64
+	rows       int64
+}
+
+const (
+	insertPrefix  = "insert into "
+	replacePrefix = "replace into "
+)
+
+// count - counts the statements and rows ending or starting in p
+func (c *sqlCounter) count(p []byte) {
+	for _, b := range p {
+		if !c.midLine {
+			c.prefix = c.prefix[:0]
+			c.midLine = true
+		}
+
+		if len(c.prefix) < len(replacePrefix) {
+			if b >= 'A' && b <= 'Z' {
+				b += 'a' - 'A'
+			}
+
+			c.prefix = append(c.prefix, b)
+			if !c.inCopy && (string(c.prefix) == insertPrefix || string(c.prefix) == replacePrefix) {
+				c.rows++
+			}
+		}
+
+		switch {
+		case b == '\n':
+			c.endLine()
+		case !c.inCopy && b == '(' && c.prev[0] == ')' && c.prev[1] == ',':
+			c.rows++
+		}
+
+		c.prev[0], c.prev[1] = c.prev[1], b
+	}
+}
+
+func (c *sqlCounter) endLine() {
+	c.midLine = false
+	line := string(c.prefix)
+	if c.inCopy {
+		if line == "\\.\n" {
+			c.inCopy = false
+			c.statements++
+		} else {
+			c.rows++
+		}
+
+		return
+	}
+
+	if c.prev[1] != ';' {
+		return
+	}
+
+	if len(line) >= 5 && line[:5] == "copy " {
+		c.inCopy = true
+		return
+	}
+
+	c.statements++
+}
@@ -0,0 +1,69 @@
+package database
+
+import (
+	"testing"
+	"time"
+)
+
+func TestSQLCounter(t *testing.T) {
+	tests := []struct {
+		name       string
+		sql        string
+		statements int64
+		rows       int64
+	}{
+		{"empty", "", 0, 0},
+		{"statements", "DROP TABLE IF EXISTS `t`;\nCREATE TABLE `t` (\n  `id` int\n);\n", 2, 0},
+		{"comments", "-- dump\n/*!40101 SET NAMES utf8 */;\n", 1, 0},
+		{"insert", "INSERT INTO `t` VALUES (1);\n", 1, 1},
+		{"extended insert", "INSERT INTO `t` VALUES (1,'a'),(2,'b'),(3,'c');\n", 1, 3},
+		{"lower case insert", "insert into t values (1), (2);\n", 1, 1},
+		{"replace", "REPLACE INTO `t` VALUES (1),(2);\n", 1, 2},
+		{"insert prefix in a value", "INSERT INTO `t` VALUES ('insert into ');\n", 1, 1},
+		{"multi-line value", "INSERT INTO `t` VALUES ('a\nb'),(2);\n", 1, 2},
+		{"miscounted value", "INSERT INTO `t` VALUES ('),(');\n", 1, 2},
+		{"copy", "COPY public.t (id) FROM stdin;\n1\n2\n\\.\n", 1, 2},
+		{"copy then insert", "COPY t (id) FROM stdin;\n1\n\\.\nINSERT INTO t VALUES (2),(3);\n", 2, 3},
+		{"unterminated", "INSERT INTO `t` VALUES (1),(2)", 0, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for _, size := range []int{1, 4, len(tt.sql) + 1} {
+				var c sqlCounter
+				for p := []byte(tt.sql); len(p) > 0; {
+					n := size
+					if n > len(p) {
+						n = len(p)
+					}
+
+					c.count(p[:n])
+					p = p[n:]
+				}
+
+				if c.statements != tt.statements || c.rows != tt.rows {
+					t.Errorf("writes of %d bytes: %d statements, %d rows, want %d, %d",
+						size, c.statements, c.rows, tt.statements, tt.rows)
+				}
+			}
+		})
+	}
+}
+
+func TestProgressSnapshotTotals(t *testing.T) {
+	s := ProgressSnapshot{
+		Tables: []TableProgress{
+			{Table: "a", Rows: 10, DumpedRows: 4, Bytes: 100},
+			{Table: "b", Rows: 5, DumpedRows: 3, Bytes: 50, Finished: time.Now()},
+		},
+	}
+
+	if rows, done, bytes := s.Totals(); rows != 15 || done != 9 || bytes != 150 {
+		t.Errorf("Totals() = %d, %d, %d, want 15, 9, 150", rows, done, bytes)
+	}
+
+	s.Tables = append(s.Tables, TableProgress{Table: "c", Rows: -1, DumpedRows: 2})
+	if rows, done, _ := s.Totals(); rows != -1 || done != 11 {
+		t.Errorf("Totals() with an unknown table = %d, %d, want -1, 11", rows, done)
+	}
+}
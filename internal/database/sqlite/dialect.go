@@ -0,0 +1,35 @@
+package sqlite
+
+import (
+	"fmt"
+
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+// DropTableStatement - returns the statement dropping a table
+func (conn *Connection) DropTableStatement(table string) string {
+	return generateDropTableStatement(table)
+}
+
+// ForeignKeyChecksStatement - returns the statement enabling or disabling the foreign key checks
+func (conn *Connection) ForeignKeyChecksStatement(enabled bool) string {
+	if enabled {
+		return "pragma foreign_keys = on"
+	}
+
+	return "pragma foreign_keys = off"
+}
+
+// DropObjectStatement - returns the statement dropping an object
+func (conn *Connection) DropObjectStatement(o database.Object) string {
+	return fmt.Sprintf("drop %s if exists %s", o.Type, quoteIdent(o.Name))
+}
+
+// CreateObjectStatement - returns the statement creating an object
+func (conn *Connection) CreateObjectStatement(o database.Object) string {
+	return o.Definition + ";"
+}
+
+func generateDropTableStatement(table string) string {
+	return fmt.Sprintf("drop table if exists %s", quoteIdent(table))
+}
@@ -0,0 +1,104 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"io"
+
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+// refreshTable - temporary table the rows of a refreshed table are loaded in
+const refreshTable = "dbsync_refresh"
+
+// Dumper - dumps the tables of a sqlite database
+type Dumper struct {
+	cfg database.ConnectionConfig
+}
+
+// NewDumper - constructor
+func NewDumper(cfg database.ConnectionConfig) *Dumper {
+	return &Dumper{
+		cfg: cfg,
+	}
+}
+
+// DumpTableTo - streams the sql dropping and creating a single table, its indexes and
+// triggers and inserting its rows to w. Filtered tables only include the rows matching their filter.
+func (d *Dumper) DumpTableTo(ctx context.Context, w io.Writer, table string) error {
+	if _, err := io.WriteString(w, generateDropTableStatement(table)+";\n"); err != nil {
+		return err
+	}
+
+	rows, err := query(ctx, d.cfg.Schema, fmt.Sprintf(
+		"select sql from sqlite_master where tbl_name = %s and sql is not null order by type <> 'table', name",
+		quoteString(table),
+	))
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if _, err := io.WriteString(w, row[0]+";\n"); err != nil {
+			return err
+		}
+	}
+
+	return d.insertsTo(ctx, w, table, table)
+}
+
+// RefreshTableTo - streams the sql deleting the rows of a filtered table matching its filter
+// and inserting them again. The rows are loaded in a temporary table first and replace the slave
+// rows sharing their key, as a master row may now match the filter while its key is still used
+// by a slave row outside of it.
+func (d *Dumper) RefreshTableTo(ctx context.Context, w io.Writer, table string) error {
+	filter, ok := d.cfg.Filter(table)
+	if !ok {
+		return fmt.Errorf("refresh %s: table has no filter", table)
+	}
+
+	if _, err := fmt.Fprintf(
+		w,
+		"delete from %s where (%s);\ncreate temp table %s as select * from main.%s where 0;\n",
+		quoteIdent(table),
+		filter,
+		quoteIdent(refreshTable),
+		quoteIdent(table),
+	); err != nil {
+		return err
+	}
+
+	if err := d.insertsTo(ctx, w, table, refreshTable); err != nil {
+		return err
+	}
+
+	_, err := fmt.Fprintf(
+		w,
+		"insert or replace into main.%s select * from temp.%s;\ndrop table temp.%s;\n",
+		quoteIdent(table),
+		quoteIdent(refreshTable),
+		quoteIdent(refreshTable),
+	)
+
+	return err
+}
+
+// insertsTo - streams the statements inserting the rows of table into target in a single transaction
+func (d *Dumper) insertsTo(ctx context.Context, w io.Writer, table, target string) error {
+	q := fmt.Sprintf("select * from %s", quoteIdent(table))
+	if filter, ok := d.cfg.Filter(table); ok {
+		q += fmt.Sprintf(" where (%s)", filter)
+	}
+
+	if _, err := io.WriteString(w, "begin;\n"); err != nil {
+		return err
+	}
+
+	if err := runTo(ctx, w, d.cfg.Schema, []string{"-cmd", ".mode insert " + quoteIdent(target), q}, nil); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "commit;\n")
+
+	return err
+}
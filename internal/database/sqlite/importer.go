@@ -0,0 +1,48 @@
+package sqlite
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/vcraescu/dbsync/internal/compress"
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+// Importer - runs sql dumps with the sqlite3 shell. Sqlite allows a single writer,
+// so imports run one at a time.
+type Importer struct {
+	cfg database.ConnectionConfig
+	mu  sync.Mutex
+}
+
+// NewImporter - import constructor
+func NewImporter(cfg database.ConnectionConfig) *Importer {
+	return &Importer{
+		cfg: cfg,
+	}
+}
+
+// Import - import sql dump
+func (imp *Importer) Import(ctx context.Context, dump string) error {
+	return imp.ImportFrom(ctx, strings.NewReader(dump))
+}
+
+// ImportFrom - import sql dump read from r; gzip and zstd compressed dumps are
+// detected by their magic bytes and decompressed
+func (imp *Importer) ImportFrom(ctx context.Context, r io.Reader) error {
+	zr, err := compress.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("Import: %s", err)
+	}
+	defer zr.Close()
+
+	imp.mu.Lock()
+	defer imp.mu.Unlock()
+
+	_, err = run(ctx, imp.cfg.Schema, nil, zr)
+
+	return err
+}
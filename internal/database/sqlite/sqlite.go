@@ -0,0 +1,223 @@
+package sqlite
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+// DriverName - name of the sqlite driver; sqlite databases are files, the configured schema is their path
+const DriverName = "sqlite"
+
+// fingerprintQuietPeriod - time since the last modification of the database files under which
+// their fingerprint is unknown
+const fingerprintQuietPeriod = 2 * time.Second
+
+// Connection - sqlite database file accessed through the sqlite3 command line shell
+type Connection struct {
+	cfg database.ConnectionConfig
+}
+
+// New - creates new sqlite connection.
+func New(cfg database.ConnectionConfig) *Connection {
+	return &Connection{
+		cfg: cfg,
+	}
+}
+
+// Driver - returns the driver name
+func (conn *Connection) Driver() string {
+	return DriverName
+}
+
+// Host - sqlite databases have no host
+func (conn *Connection) Host() string {
+	return ""
+}
+
+// Schema - returns the database file
+func (conn *Connection) Schema() string {
+	return conn.cfg.Schema
+}
+
+// Filter - returns the where condition of a filtered table
+func (conn *Connection) Filter(table string) (string, bool) {
+	filter, ok := conn.cfg.Filter(table)
+
+	return filter, ok
+}
+
+// Open - checks the sqlite3 shell is available; the database file is created on first write
+func (conn *Connection) Open(ctx context.Context) error {
+	_, err := sqlite3Path()
+
+	return err
+}
+
+// Close - nothing to close, every query runs its own sqlite3 process
+func (conn *Connection) Close() error {
+	return nil
+}
+
+// TableNames - returns table names
+func (conn *Connection) TableNames(ctx context.Context) ([]string, error) {
+	rows, err := query(
+		ctx,
+		conn.cfg.Schema,
+		"select name from sqlite_master where type = 'table' and name not like 'sqlite_%' order by name",
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	var names []string
+	for _, row := range rows {
+		names = append(names, row[0])
+	}
+
+	return names, nil
+}
+
+// TableDependencies - returns the tables referenced through foreign keys by every table
+func (conn *Connection) TableDependencies(ctx context.Context) (map[string][]string, error) {
+	rows, err := query(
+		ctx,
+		conn.cfg.Schema,
+		"select distinct m.name, p.\"table\" from sqlite_master m join pragma_foreign_key_list(m.name) p "+
+			"where m.type = 'table' order by 1, 2",
+	)
+	if err != nil {
+		return nil, fmt.Errorf("Table Dependencies: %s", err)
+	}
+
+	deps := make(map[string][]string)
+	for _, row := range rows {
+		deps[row[0]] = append(deps[row[0]], row[1])
+	}
+
+	return deps, nil
+}
+
+// Objects - views and triggers are not synced to sqlite
+func (conn *Connection) Objects(ctx context.Context) ([]database.Object, error) {
+	return nil, nil
+}
+
+// CountRows - returns the row counts of the given tables, filtered tables only counting their matching rows
+func (conn *Connection) CountRows(ctx context.Context, names ...string) (map[string]int64, error) {
+	counts := make(map[string]int64, len(names))
+	for _, table := range names {
+		q := fmt.Sprintf("select count(*) from %s", quoteIdent(table))
+		if filter, ok := conn.cfg.Filter(table); ok {
+			q += fmt.Sprintf(" where (%s)", filter)
+		}
+
+		rows, err := query(ctx, conn.cfg.Schema, q)
+		if err != nil {
+			return nil, fmt.Errorf("Count Rows (%s): %s", table, err)
+		}
+
+		if len(rows) == 0 {
+			return nil, fmt.Errorf("Count Rows (%s): no result", table)
+		}
+
+		count, err := strconv.ParseInt(rows[0][0], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("Count Rows (%s): %s", table, err)
+		}
+
+		counts[table] = count
+	}
+
+	return counts, nil
+}
+
+// EstimateRows - returns the exact row counts of the given tables, counting the rows of a local
+// file being cheap enough
+func (conn *Connection) EstimateRows(ctx context.Context, names ...string) (map[string]int64, error) {
+	return conn.CountRows(ctx, names...)
+}
+
+// TableChecksum - returns the md5 of the table rows
+func (conn *Connection) TableChecksum(ctx context.Context, table string) (string, error) {
+	q := fmt.Sprintf("select * from %s", quoteIdent(table))
+	if filter, ok := conn.cfg.Filter(table); ok {
+		q += fmt.Sprintf(" where (%s)", filter)
+	}
+
+	out, err := run(ctx, conn.cfg.Schema, []string{"-ascii", "-noheader", q}, nil)
+	if err != nil {
+		return "", fmt.Errorf("Table Checksum (%s): %s", table, err)
+	}
+
+	return rowsChecksum(out), nil
+}
+
+// rowsChecksum - returns the md5 of the rows of the ascii output of a query, empty when there are
+// none. Without an order by, the rows are not returned in a stable order, so they are sorted first.
+func rowsChecksum(out []byte) string {
+	if len(out) == 0 {
+		return ""
+	}
+
+	rows := strings.Split(strings.TrimSuffix(string(out), recordSeparator), recordSeparator)
+	sort.Strings(rows)
+	sum := md5.Sum([]byte(strings.Join(rows, recordSeparator)))
+
+	return hex.EncodeToString(sum[:])
+}
+
+// ChecksumTables - returns content checksums of the given tables
+func (conn *Connection) ChecksumTables(ctx context.Context, names ...string) (map[string]string, error) {
+	chks := make(map[string]string, len(names))
+	for _, name := range names {
+		checksum, err := conn.TableChecksum(ctx, name)
+		if err != nil {
+			return nil, err
+		}
+
+		chks[name] = checksum
+		database.TableChecksummed(ctx, name)
+	}
+
+	return chks, nil
+}
+
+// Fingerprints - returns the size and modification time of the database file and of its
+// write-ahead log for every given table, any write changing the fingerprint of every table.
+// Returns none when the files were modified during the last seconds, since the modification
+// time may not tell apart later writes.
+func (conn *Connection) Fingerprints(_ context.Context, tables ...string) (map[string]string, error) {
+	var fp string
+	for _, file := range []string{conn.cfg.Schema, conn.cfg.Schema + "-wal"} {
+		info, err := os.Stat(file)
+		if os.IsNotExist(err) {
+			continue
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("Fingerprints: %s", err)
+		}
+
+		if time.Since(info.ModTime()) < fingerprintQuietPeriod {
+			return nil, nil
+		}
+
+		fp += fmt.Sprintf("%d:%d;", info.Size(), info.ModTime().UnixNano())
+	}
+
+	fps := make(map[string]string, len(tables))
+	for _, table := range tables {
+		fps[table] = fp
+	}
+
+	return fps, nil
+}
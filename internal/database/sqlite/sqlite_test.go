@@ -0,0 +1,36 @@
+package sqlite
+
+import (
+	"testing"
+)
+
+func TestRowsChecksum(t *testing.T) {
+	rows := func(records ...string) []byte {
+		var out string
+		for _, record := range records {
+			out += record + recordSeparator
+		}
+
+		return []byte(out)
+	}
+
+	a := "1" + unitSeparator + "a"
+	b := "2" + unitSeparator + "b"
+	c := "3" + unitSeparator + "c"
+
+	if got := rowsChecksum(nil); got != "" {
+		t.Errorf("rowsChecksum(no rows) = %q, want empty", got)
+	}
+
+	if rowsChecksum(rows(a, b, c)) != rowsChecksum(rows(c, a, b)) {
+		t.Errorf("rowsChecksum() depends on the order of the rows")
+	}
+
+	if rowsChecksum(rows(a, b)) == rowsChecksum(rows(a, b, c)) {
+		t.Errorf("rowsChecksum() of different rows are equal")
+	}
+
+	if rowsChecksum(rows(a, b)) == rowsChecksum(rows(a+b)) {
+		t.Errorf("rowsChecksum() does not separate the rows")
+	}
+}
@@ -0,0 +1,126 @@
+package sqlite
+
+import (
+	"encoding/hex"
+	"fmt"
+	"strings"
+
+	"github.com/vcraescu/dbsync/internal/database"
+	"github.com/vcraescu/dbsync/internal/database/mysql"
+	"github.com/vcraescu/dbsync/internal/database/typemap"
+)
+
+func init() {
+	// types are mapped to the sqlite type with the matching affinity
+	typemap.Register(mysql.DriverName, DriverName, typemap.Mapping{
+		Types: map[string]string{
+			"tinyint(1)":         "BOOLEAN",
+			"tinyint":            "INTEGER",
+			"smallint":           "INTEGER",
+			"mediumint":          "INTEGER",
+			"int":                "INTEGER",
+			"integer":            "INTEGER",
+			"bigint":             "INTEGER",
+			"bit":                "INTEGER",
+			"bool":               "BOOLEAN",
+			"boolean":            "BOOLEAN",
+			"year":               "INTEGER",
+			"decimal":            "NUMERIC",
+			"numeric":            "NUMERIC",
+			"dec":                "NUMERIC",
+			"fixed":              "NUMERIC",
+			"float":              "REAL",
+			"double":             "REAL",
+			"real":               "REAL",
+			"binary":             "BLOB",
+			"varbinary":          "BLOB",
+			"tinyblob":           "BLOB",
+			"blob":               "BLOB",
+			"mediumblob":         "BLOB",
+			"longblob":           "BLOB",
+			"geometry":           "BLOB",
+			"point":              "BLOB",
+			"linestring":         "BLOB",
+			"polygon":            "BLOB",
+			"multipoint":         "BLOB",
+			"multilinestring":    "BLOB",
+			"multipolygon":       "BLOB",
+			"geometrycollection": "BLOB",
+		},
+		Default: "TEXT",
+	})
+}
+
+// FromMySQL - returns a dumper translating the mysqldump output of d to sqlite statements,
+// column types being mapped by types
+func FromMySQL(d database.Dumper, types *typemap.Mapper) database.Dumper {
+	return mysql.Translate(d, &target{
+		types: types,
+	})
+}
+
+// target - translates mysqldump output to sqlite statements
+type target struct {
+	types *typemap.Mapper
+}
+
+func (t *target) QuoteIdent(name string) string {
+	return quoteIdent(name)
+}
+
+// Literal - binary values and values holding NUL bytes, which sqlite strings cannot, are written as blobs
+func (t *target) Literal(text string, binary bool) string {
+	if binary || strings.IndexByte(text, 0) >= 0 {
+		return "X'" + hex.EncodeToString([]byte(text)) + "'"
+	}
+
+	return quoteString(text)
+}
+
+func (t *target) Number(text string) string {
+	return text
+}
+
+func (t *target) DropTableStatement(table string) string {
+	return generateDropTableStatement(table)
+}
+
+func (t *target) CreateTableStatement(table *mysql.Table) string {
+	var defs []string
+	rowid := false
+	for _, col := range table.Columns {
+		typ := t.types.Map(table.Name, col.Name, col.Type)
+		name := quoteIdent(col.Name)
+
+		def := name + " " + typ.Name
+		// a single auto increment integer primary key becomes the rowid alias
+		if col.AutoIncrement && table.PrimaryKey == "("+name+")" && typ.Name == "INTEGER" {
+			def += " PRIMARY KEY AUTOINCREMENT"
+			rowid = true
+		}
+
+		def += col.Attributes
+		if check := typ.Check(name); check != "" {
+			def += " " + check
+		}
+
+		defs = append(defs, def)
+	}
+
+	if table.PrimaryKey != "" && !rowid {
+		defs = append(defs, "PRIMARY KEY "+table.PrimaryKey)
+	}
+
+	defs = append(defs, table.Constraints...)
+
+	stmt := fmt.Sprintf("CREATE TABLE %s (\n  %s\n);", quoteIdent(table.Name), strings.Join(defs, ",\n  "))
+	for _, index := range table.Indexes {
+		stmt += fmt.Sprintf("\nCREATE INDEX %s ON %s %s;", quoteIdent(table.Name+"_"+index.Name), quoteIdent(table.Name), index.Parts)
+	}
+
+	return stmt
+}
+
+func (t *target) InsertedStatement(table *mysql.Table) string {
+	return ""
+}
@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"testing"
+
+	"github.com/vcraescu/dbsync/internal/database/mysql"
+	"github.com/vcraescu/dbsync/internal/database/typemap"
+)
+
+func newTarget(t *testing.T) *target {
+	t.Helper()
+
+	types, err := typemap.New(mysql.DriverName, DriverName, nil)
+	if err != nil {
+		t.Fatalf("typemap.New() error = %s", err)
+	}
+
+	return &target{types: types}
+}
+
+func TestTargetLiteral(t *testing.T) {
+	tests := []struct {
+		text   string
+		binary bool
+		want   string
+	}{
+		{"it's", false, "'it''s'"},
+		{"\x00\xff", true, "X'00ff'"},
+		{"a\x00b", false, "X'610062'"},
+		{"", false, "''"},
+	}
+
+	tg := newTarget(t)
+	for _, tt := range tests {
+		if got := tg.Literal(tt.text, tt.binary); got != tt.want {
+			t.Errorf("Literal(%q, %v) = %s, want %s", tt.text, tt.binary, got, tt.want)
+		}
+	}
+}
+
+func TestTargetCreateTableStatement(t *testing.T) {
+	tests := []struct {
+		name  string
+		table *mysql.Table
+		want  string
+	}{
+		{
+			name: "rowid alias",
+			table: &mysql.Table{
+				Name: "users",
+				Columns: []mysql.Column{
+					{Name: "id", Type: "int unsigned", Attributes: " NOT NULL", AutoIncrement: true},
+					{Name: "status", Type: "enum('new','done')", Attributes: " NOT NULL"},
+				},
+				PrimaryKey: `("id")`,
+				Indexes:    []mysql.Index{{Name: "status", Parts: `("status")`}},
+			},
+			want: "CREATE TABLE \"users\" (\n" +
+				"  \"id\" INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL,\n" +
+				"  \"status\" TEXT NOT NULL CHECK (\"status\" IN ('new','done'))\n" +
+				");\n" +
+				"CREATE INDEX \"users_status\" ON \"users\" (\"status\");",
+		},
+		{
+			name: "composite primary key",
+			table: &mysql.Table{
+				Name: "roles",
+				Columns: []mysql.Column{
+					{Name: "user_id", Type: "int", Attributes: " NOT NULL", AutoIncrement: true},
+					{Name: "role", Type: "varchar(20)", Attributes: " NOT NULL"},
+				},
+				PrimaryKey:  `("user_id","role")`,
+				Constraints: []string{`FOREIGN KEY ("user_id") REFERENCES "users" ("id")`},
+			},
+			want: "CREATE TABLE \"roles\" (\n" +
+				"  \"user_id\" INTEGER NOT NULL,\n" +
+				"  \"role\" TEXT NOT NULL,\n" +
+				"  PRIMARY KEY (\"user_id\",\"role\"),\n" +
+				"  FOREIGN KEY (\"user_id\") REFERENCES \"users\" (\"id\")\n" +
+				");",
+		},
+	}
+
+	tg := newTarget(t)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tg.CreateTableStatement(tt.table); got != tt.want {
+				t.Errorf("CreateTableStatement() =\n%s\nwant:\n%s", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package sqlite
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// unitSeparator, recordSeparator - field and row separators of the sqlite3 ascii output mode
+	unitSeparator   = "\x1f"
+	recordSeparator = "\x1e"
+)
+
+func sqlite3Path() (string, error) {
+	path, err := exec.LookPath("sqlite3")
+	if err == nil {
+		return path, nil
+	}
+
+	path, err = filepath.Abs("bin/sqlite3")
+	if err != nil {
+		return "", errors.New("sqlite3 not found")
+	}
+
+	return path, nil
+}
+
+// query - runs a query and returns its rows
+func query(ctx context.Context, file, q string) ([][]string, error) {
+	out, err := run(ctx, file, []string{"-ascii", "-noheader", q}, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows [][]string
+	for _, record := range strings.Split(string(out), recordSeparator) {
+		if record == "" {
+			continue
+		}
+
+		rows = append(rows, strings.Split(record, unitSeparator))
+	}
+
+	return rows, nil
+}
+
+// run - runs the sqlite3 shell on file with the given arguments, reading sql from stdin when not nil.
+// The shell is killed once ctx is done.
+func run(ctx context.Context, file string, args []string, stdin io.Reader) ([]byte, error) {
+	var out bytes.Buffer
+	if err := runTo(ctx, &out, file, args, stdin); err != nil {
+		return nil, err
+	}
+
+	return out.Bytes(), nil
+}
+
+func runTo(ctx context.Context, w io.Writer, file string, args []string, stdin io.Reader) error {
+	path, err := sqlite3Path()
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, path, append([]string{"-batch", "-bail", file}, args...)...)
+
+	var stderr bytes.Buffer
+
+	cmd.Stdin = stdin
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("%s: %s", err, stderr.String())
+	}
+
+	return nil
+}
+
+// quoteIdent - quotes a table or column name
+func quoteIdent(name string) string {
+	return `"` + strings.Replace(name, `"`, `""`, -1) + `"`
+}
+
+// quoteString - quotes a string literal
+func quoteString(s string) string {
+	return "'" + strings.Replace(s, "'", "''", -1) + "'"
+}
@@ -0,0 +1,288 @@
+package database
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const defaultWorkers = 4
+
+// Syncer - applies a diff by dumping and importing every table in its own stream
+type Syncer struct {
+	dumper   Dumper
+	importer Importer
+	workers  int
+	timeouts Timeouts
+	onTable  func(TableResult)
+}
+
+// sync phases
+const (
+	PhaseDrop          = "drop"
+	PhaseDump          = "dump"
+	PhaseImport        = "import"
+	PhaseCreateObjects = "create objects"
+)
+
+// PhaseError - error of a sync phase, of a single table when Table is set
+type PhaseError struct {
+	Phase string
+	Table string
+	Err   error
+}
+
+func (e *PhaseError) Error() string {
+	if e.Table == "" {
+		return fmt.Sprintf("%s: %s", e.Phase, e.Err)
+	}
+
+	return fmt.Sprintf("%s %s: %s", e.Phase, e.Table, e.Err)
+}
+
+// Unwrap - returns the error of the phase
+func (e *PhaseError) Unwrap() error {
+	return e.Err
+}
+
+// TableResult - outcome of the sync of a single table
+type TableResult struct {
+	Table string
+	// Refresh - true when the matching rows of a filtered table were replaced
+	Refresh bool
+	// Deleted - true when the table was dropped from slave
+	Deleted bool
+	// Bytes - size of the sql streamed from master to slave
+	Bytes    int64
+	Duration time.Duration
+	Err      error
+}
+
+// NewSyncer - constructor
+func NewSyncer(dumper Dumper, importer Importer, workers int) *Syncer {
+	if workers <= 0 {
+		workers = defaultWorkers
+	}
+
+	return &Syncer{
+		dumper:   dumper,
+		importer: importer,
+		workers:  workers,
+	}
+}
+
+// SetTimeouts - limits the duration of the dump and import phases
+func (s *Syncer) SetTimeouts(t Timeouts) {
+	s.timeouts = t
+}
+
+// SetTableCallback - sets the function called once each table is synced or failed.
+// It is called from the workers, so it must be safe for concurrent use.
+func (s *Syncer) SetTableCallback(f func(TableResult)) {
+	s.onTable = f
+}
+
+// Sync - drops deleted tables and objects, then dumps and imports the created and refreshed
+// tables in parallel, a level of the foreign key graph at a time, and finally creates the new objects.
+// Once ctx is done, or the dump or import phase times out, the running clients are killed and
+// no further table is started.
+func (s *Syncer) Sync(ctx context.Context, diff *Diff) error {
+	dumpCtx, cancelDump := WithTimeout(ctx, s.timeouts.Dump)
+	defer cancelDump()
+
+	importCtx, cancelImport := WithTimeout(ctx, s.timeouts.Import)
+	defer cancelImport()
+
+	start := time.Now()
+	err := s.importStatements(importCtx, diff, diff.dropSQL())
+	if s.onTable != nil {
+		for _, table := range diff.Delete {
+			s.onTable(TableResult{Table: table, Deleted: true, Duration: time.Since(start), Err: err})
+		}
+	}
+
+	if err != nil {
+		return &PhaseError{Phase: PhaseDrop, Err: err}
+	}
+
+	refresh := make(map[string]bool, len(diff.Refresh))
+	for _, table := range diff.Refresh {
+		refresh[table] = true
+	}
+
+	tables := append(append([]string{}, diff.Create...), diff.Refresh...)
+	for _, level := range tableLevels(tables, diff.Dependencies) {
+		if err := s.syncTables(dumpCtx, importCtx, level, refresh); err != nil {
+			return err
+		}
+	}
+
+	if err := s.importStatements(importCtx, diff, diff.createObjectsSQL()); err != nil {
+		return &PhaseError{Phase: PhaseCreateObjects, Err: err}
+	}
+
+	return nil
+}
+
+func (s *Syncer) importStatements(ctx context.Context, diff *Diff, dump string) error {
+	if dump == "" {
+		return nil
+	}
+
+	if diff.Cyclic {
+		dump = diff.foreignKeyChecksSQL(false) + dump
+	}
+
+	return s.importer.Import(ctx, strings.TrimRight(dump, "\n"))
+}
+
+// syncTables - syncs the tables in parallel; once either context is done no further table is started
+func (s *Syncer) syncTables(dumpCtx, importCtx context.Context, tables []string, refresh map[string]bool) error {
+	sem := make(chan struct{}, s.workers)
+	errs := make(chan error, len(tables)+1)
+
+	var wg sync.WaitGroup
+	for _, table := range tables {
+		sem <- struct{}{}
+		if err := dumpCtx.Err(); err != nil {
+			errs <- &PhaseError{Phase: PhaseDump, Err: err}
+			break
+		}
+
+		if err := importCtx.Err(); err != nil {
+			errs <- &PhaseError{Phase: PhaseImport, Err: err}
+			break
+		}
+
+		wg.Add(1)
+		go func(table string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			start := time.Now()
+			n, err := s.syncTable(dumpCtx, importCtx, table, refresh[table])
+			ProgressFrom(importCtx).update(table, func(t *TableProgress) {
+				t.Finished = time.Now()
+				t.Err = err
+			})
+			if s.onTable != nil {
+				s.onTable(TableResult{
+					Table:    table,
+					Refresh:  refresh[table],
+					Bytes:    n,
+					Duration: time.Since(start),
+					Err:      err,
+				})
+			}
+
+			errs <- err
+		}(table)
+	}
+
+	wg.Wait()
+	close(errs)
+
+	for err := range errs {
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// syncTable - spools the table dump to a temporary file and imports it once complete, returning the
+// size of the dump. The import client runs the statements as they come, so importing a dump which
+// fails halfway would leave the slave table dropped or partially loaded; a failed dump leaves the
+// slave untouched instead. A failed import may still leave the table incomplete.
+func (s *Syncer) syncTable(dumpCtx, importCtx context.Context, table string, refresh bool) (int64, error) {
+	dump := s.dumper.DumpTableTo
+	if refresh {
+		dump = s.dumper.RefreshTableTo
+	}
+
+	progress := ProgressFrom(importCtx)
+	progress.update(table, func(t *TableProgress) { t.Started = time.Now() })
+
+	f, err := ioutil.TempFile("", "dbsync-*.sql")
+	if err != nil {
+		return 0, &PhaseError{Phase: PhaseDump, Table: table, Err: err}
+	}
+	defer os.Remove(f.Name())
+	defer f.Close()
+
+	bw := bufio.NewWriter(f)
+	w := &countingWriter{w: bw, table: table, progress: progress}
+	if err := dump(dumpCtx, w, table); err != nil {
+		return w.n, &PhaseError{Phase: PhaseDump, Table: table, Err: contextError(dumpCtx, err)}
+	}
+
+	if err := bw.Flush(); err != nil {
+		return w.n, &PhaseError{Phase: PhaseDump, Table: table, Err: err}
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return w.n, &PhaseError{Phase: PhaseDump, Table: table, Err: err}
+	}
+
+	if err := s.importer.ImportFrom(importCtx, bufio.NewReader(f)); err != nil {
+		err = &IncompleteError{Err: contextError(importCtx, err)}
+
+		return w.n, &PhaseError{Phase: PhaseImport, Table: table, Err: err}
+	}
+
+	return w.n, nil
+}
+
+// IncompleteError - error of an import which may have left the slave table dropped or partially
+// loaded, the statements run before the failure being applied already
+type IncompleteError struct {
+	Err error
+}
+
+func (e *IncompleteError) Error() string {
+	return fmt.Sprintf("%s (the slave table may be incomplete, sync it again)", e.Err)
+}
+
+func (e *IncompleteError) Unwrap() error {
+	return e.Err
+}
+
+// countingWriter - counts the bytes written through it and, with a progress, the statements and rows
+type countingWriter struct {
+	w        io.Writer
+	n        int64
+	table    string
+	progress *Progress
+	sql      sqlCounter
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	if w.progress != nil && n > 0 {
+		w.sql.count(p[:n])
+		w.progress.update(w.table, func(t *TableProgress) {
+			t.Bytes = w.n
+			t.DumpedRows = w.sql.rows
+			t.Statements = w.sql.statements
+		})
+	}
+
+	return n, err
+}
+
+// contextError - returns the error of ctx when done, as killed clients only report the signal
+func contextError(ctx context.Context, err error) error {
+	if ctxErr := ctx.Err(); ctxErr != nil {
+		return ctxErr
+	}
+
+	return err
+}
@@ -0,0 +1,425 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// errKilled - error of a client killed once its context is done
+var errKilled = errors.New("signal: killed")
+
+// fakeDumper - writes a comment naming the dumped table, failing for the tables of errs. The dump
+// of the hang table only ends, killed, once its context is done.
+type fakeDumper struct {
+	errs map[string]error
+	hang string
+
+	mu     sync.Mutex
+	dumped []string
+}
+
+func (d *fakeDumper) DumpTableTo(ctx context.Context, w io.Writer, table string) error {
+	if table == d.hang {
+		<-ctx.Done()
+		return errKilled
+	}
+
+	return d.dump(w, "dump", table)
+}
+
+func (d *fakeDumper) RefreshTableTo(ctx context.Context, w io.Writer, table string) error {
+	return d.dump(w, "refresh", table)
+}
+
+func (d *fakeDumper) dump(w io.Writer, kind, table string) error {
+	d.mu.Lock()
+	d.dumped = append(d.dumped, table)
+	d.mu.Unlock()
+
+	if _, err := fmt.Fprintf(w, "-- %s %s\n", kind, table); err != nil {
+		return err
+	}
+
+	return d.errs[table]
+}
+
+// fakeImporter - records the imported sql, failing for the dumps mentioning a table of errs.
+// It also records the spooled dumps found in tmpDir while importing. The import of the dump of
+// the hang table only ends, killed, once its context is done.
+type fakeImporter struct {
+	errs   map[string]error
+	hang   string
+	tmpDir string
+
+	mu sync.Mutex
+	// statements - sql imported by Import
+	statements []string
+	// imported - dumps imported by ImportFrom, in completion order
+	imported []string
+	// spooled - number of spooled dumps while importing each dump
+	spooled []int
+}
+
+func (im *fakeImporter) Import(ctx context.Context, dump string) error {
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	im.statements = append(im.statements, dump)
+
+	return im.errs[dump]
+}
+
+func (im *fakeImporter) ImportFrom(ctx context.Context, r io.Reader) error {
+	data, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+
+	if im.hang != "" && strings.HasSuffix(string(data), " "+im.hang+"\n") {
+		<-ctx.Done()
+		return errKilled
+	}
+
+	files, _ := filepath.Glob(filepath.Join(im.tmpDir, "dbsync-*.sql"))
+
+	im.mu.Lock()
+	defer im.mu.Unlock()
+
+	im.imported = append(im.imported, string(data))
+	im.spooled = append(im.spooled, len(files))
+
+	for table, err := range im.errs {
+		if strings.HasSuffix(string(data), " "+table+"\n") {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// spoolTo - spools the dumps of the test to a temporary directory, returned
+func spoolTo(t *testing.T) string {
+	dir := t.TempDir()
+	tmp, ok := os.LookupEnv("TMPDIR")
+	os.Setenv("TMPDIR", dir)
+	t.Cleanup(func() {
+		if ok {
+			os.Setenv("TMPDIR", tmp)
+			return
+		}
+
+		os.Unsetenv("TMPDIR")
+	})
+
+	return dir
+}
+
+// assertNotSpooled - fails when spooled dumps were left in dir
+func assertNotSpooled(t *testing.T, dir string) {
+	t.Helper()
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(files) != 0 {
+		t.Errorf("%d spooled dumps left after the sync", len(files))
+	}
+}
+
+// testDiff - diff creating users, then orders referencing users, then items referencing orders,
+// refreshing logs and replacing a view
+func testDiff() *Diff {
+	view := Object{Type: ObjectView, Name: "active_users", Definition: "create view active_users"}
+
+	return &Diff{
+		Create:        []string{"users", "orders", "items"},
+		Refresh:       []string{"logs"},
+		Delete:        []string{"old"},
+		Updated:       []string{"users"},
+		CreateObjects: []Object{view},
+		DropObjects:   []Object{view},
+		Dependencies: map[string][]string{
+			"orders": {"users"},
+			"items":  {"orders"},
+		},
+		dialect: &fakeConn{},
+	}
+}
+
+func TestSyncerSync(t *testing.T) {
+	dir := spoolTo(t)
+	dumper := &fakeDumper{}
+	importer := &fakeImporter{tmpDir: dir}
+
+	var mu sync.Mutex
+	results := make(map[string]TableResult)
+	s := NewSyncer(dumper, importer, 2)
+	s.SetTableCallback(func(r TableResult) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		results[r.Table] = r
+	})
+
+	if err := s.Sync(context.Background(), testDiff()); err != nil {
+		t.Fatalf("Sync() error = %s", err)
+	}
+
+	wantStatements := []string{
+		"drop view active_users;\ndrop table old;",
+		"create view active_users;",
+	}
+	if !reflect.DeepEqual(importer.statements, wantStatements) {
+		t.Errorf("statements = %q, want %q", importer.statements, wantStatements)
+	}
+
+	// a level of the foreign key graph at a time, the tables of a level in any order
+	first := append([]string{}, importer.imported[:2]...)
+	sort.Strings(first)
+	got := append(first, importer.imported[2:]...)
+	want := []string{"-- dump users\n", "-- refresh logs\n", "-- dump orders\n", "-- dump items\n"}
+	sort.Strings(want[:2])
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("imported = %q, want %q", got, want)
+	}
+
+	for i, n := range importer.spooled {
+		if n != 1 {
+			t.Errorf("%d spooled dumps while importing %q, want 1", n, importer.imported[i])
+		}
+	}
+	assertNotSpooled(t, dir)
+
+	if r := results["old"]; !r.Deleted || r.Err != nil {
+		t.Errorf("old result = %+v, want deleted", r)
+	}
+
+	if r := results["logs"]; !r.Refresh || r.Bytes != int64(len("-- refresh logs\n")) {
+		t.Errorf("logs result = %+v, want refreshed", r)
+	}
+
+	if r := results["items"]; r.Refresh || r.Deleted || r.Bytes != int64(len("-- dump items\n")) || r.Err != nil {
+		t.Errorf("items result = %+v, want created", r)
+	}
+}
+
+func TestSyncerSyncCyclic(t *testing.T) {
+	spoolTo(t)
+	importer := &fakeImporter{}
+	diff := testDiff()
+	diff.Cyclic = true
+
+	if err := NewSyncer(&fakeDumper{}, importer, 1).Sync(context.Background(), diff); err != nil {
+		t.Fatalf("Sync() error = %s", err)
+	}
+
+	for _, statements := range importer.statements {
+		if !strings.HasPrefix(statements, "set foreign_key_checks = false;\n") {
+			t.Errorf("statements %q do not disable the foreign key checks", statements)
+		}
+	}
+}
+
+func TestSyncerSyncErrors(t *testing.T) {
+	errFailed := errors.New("failed")
+
+	tests := []struct {
+		name         string
+		dumpErrs     map[string]error
+		importErrs   map[string]error
+		phase        string
+		table        string
+		incomplete   bool
+		wantDumped   []string
+		wantImported int
+	}{
+		{
+			name:         "drop",
+			importErrs:   map[string]error{"drop view active_users;\ndrop table old;": errFailed},
+			phase:        PhaseDrop,
+			wantImported: 0,
+		},
+		{
+			name:         "dump",
+			dumpErrs:     map[string]error{"orders": errFailed},
+			phase:        PhaseDump,
+			table:        "orders",
+			wantDumped:   []string{"logs", "orders", "users"},
+			wantImported: 2,
+		},
+		{
+			name:         "import",
+			importErrs:   map[string]error{"orders": errFailed},
+			phase:        PhaseImport,
+			table:        "orders",
+			incomplete:   true,
+			wantDumped:   []string{"logs", "orders", "users"},
+			wantImported: 3,
+		},
+		{
+			name:         "create objects",
+			importErrs:   map[string]error{"create view active_users;": errFailed},
+			phase:        PhaseCreateObjects,
+			wantDumped:   []string{"items", "logs", "orders", "users"},
+			wantImported: 4,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := spoolTo(t)
+			dumper := &fakeDumper{errs: tt.dumpErrs}
+			importer := &fakeImporter{errs: tt.importErrs}
+
+			var deleted []TableResult
+			s := NewSyncer(dumper, importer, 2)
+			s.SetTableCallback(func(r TableResult) {
+				if r.Deleted {
+					deleted = append(deleted, r)
+				}
+			})
+
+			err := s.Sync(context.Background(), testDiff())
+
+			var pe *PhaseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("Sync() error = %v, want a PhaseError", err)
+			}
+
+			if pe.Phase != tt.phase || pe.Table != tt.table {
+				t.Errorf("phase error of %q %q, want %q %q", pe.Phase, pe.Table, tt.phase, tt.table)
+			}
+
+			var ie *IncompleteError
+			if errors.As(err, &ie) != tt.incomplete {
+				t.Errorf("Sync() error = %q, incomplete %v", err, tt.incomplete)
+			}
+
+			if !errors.Is(err, errFailed) {
+				t.Errorf("Sync() error = %q does not wrap the failure", err)
+			}
+
+			// the failing level is completed, the next ones are not started
+			sort.Strings(dumper.dumped)
+			if !reflect.DeepEqual(dumper.dumped, tt.wantDumped) {
+				t.Errorf("dumped = %q, want %q", dumper.dumped, tt.wantDumped)
+			}
+
+			if len(importer.imported) != tt.wantImported {
+				t.Errorf("imported %q, want %d dumps", importer.imported, tt.wantImported)
+			}
+
+			if len(deleted) != 1 || (tt.phase == PhaseDrop) != (deleted[0].Err != nil) {
+				t.Errorf("deleted results = %+v", deleted)
+			}
+
+			assertNotSpooled(t, dir)
+		})
+	}
+}
+
+func TestSyncerTimeouts(t *testing.T) {
+	tests := []struct {
+		name       string
+		timeouts   Timeouts
+		dumpHang   string
+		importHang string
+		phase      string
+		incomplete bool
+	}{
+		{name: "dump", timeouts: Timeouts{Dump: 20 * time.Millisecond}, dumpHang: "orders", phase: PhaseDump},
+		{name: "import", timeouts: Timeouts{Import: 20 * time.Millisecond}, importHang: "orders", phase: PhaseImport, incomplete: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := spoolTo(t)
+			importer := &fakeImporter{hang: tt.importHang}
+			s := NewSyncer(&fakeDumper{hang: tt.dumpHang}, importer, 2)
+			s.SetTimeouts(tt.timeouts)
+
+			done := make(chan error)
+			go func() {
+				done <- s.Sync(context.Background(), testDiff())
+			}()
+
+			var err error
+			select {
+			case err = <-done:
+			case <-time.After(time.Second):
+				t.Fatal("Sync() still running after the timeout")
+			}
+
+			var pe *PhaseError
+			if !errors.As(err, &pe) || pe.Phase != tt.phase || pe.Table != "orders" {
+				t.Fatalf("Sync() error = %v, want a %s error of orders", err, tt.phase)
+			}
+
+			// the killed client only reports the signal
+			if !errors.Is(err, context.DeadlineExceeded) || errors.Is(err, errKilled) {
+				t.Errorf("Sync() error = %q, want the deadline", err)
+			}
+
+			var ie *IncompleteError
+			if errors.As(err, &ie) != tt.incomplete {
+				t.Errorf("Sync() error = %q, incomplete %v", err, tt.incomplete)
+			}
+
+			// the level of orders is not imported past it, the next level is not started
+			for _, dump := range importer.imported {
+				if strings.Contains(dump, " items\n") {
+					t.Errorf("items imported after the timeout")
+				}
+			}
+
+			assertNotSpooled(t, dir)
+		})
+	}
+}
+
+func TestWithTimeout(t *testing.T) {
+	ctx, cancel := WithTimeout(context.Background(), 0)
+	defer cancel()
+	if _, ok := ctx.Deadline(); ok {
+		t.Errorf("WithTimeout(0) has a deadline, want none")
+	}
+
+	ctx, cancel = WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if deadline, ok := ctx.Deadline(); !ok || time.Until(deadline) > time.Minute {
+		t.Errorf("WithTimeout(1m) deadline = %v, %v", deadline, ok)
+	}
+}
+
+func TestErrorMessages(t *testing.T) {
+	tests := []struct {
+		err  error
+		want string
+	}{
+		{&PhaseError{Phase: PhaseDrop, Err: errors.New("denied")}, "drop: denied"},
+		{&PhaseError{Phase: PhaseDump, Table: "users", Err: errors.New("killed")}, "dump users: killed"},
+		{
+			&PhaseError{Phase: PhaseImport, Table: "users", Err: &IncompleteError{Err: errors.New("gone away")}},
+			"import users: gone away (the slave table may be incomplete, sync it again)",
+		},
+	}
+
+	for _, tt := range tests {
+		if got := tt.err.Error(); got != tt.want {
+			t.Errorf("Error() = %q, want %q", got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package typemap
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+)
+
+// Mapping - column types of a source engine and the types of a target engine they map to.
+// Types are looked up by the full source type, e.g. tinyint(1), then by the type without its
+// length, e.g. int unsigned, and then by the type name, e.g. int. A (*) in the target type is
+// replaced with the length or precision of the source type, e.g. varchar(*).
+type Mapping struct {
+	Types map[string]string
+	// Default - target type of the source types missing from the mapping
+	Default string
+}
+
+var (
+	mappingsMu sync.RWMutex
+	mappings   = make(map[string]Mapping)
+)
+
+// Register - makes the default mapping of the source driver types to the target driver types
+// available; called by the engine packages translating dumps of other engines on init
+func Register(source, target string, m Mapping) {
+	mappingsMu.Lock()
+	defer mappingsMu.Unlock()
+
+	k := key(source, target)
+	if _, ok := mappings[k]; ok {
+		panic("typemap: Register called twice for " + k)
+	}
+
+	mappings[k] = m
+}
+
+func key(source, target string) string {
+	return source + " to " + target
+}
+
+// Type - column type of the target engine
+type Type struct {
+	Name string
+	// Values - quoted values the column is restricted to, e.g. the values of a mysql enum
+	Values string
+}
+
+// Check - returns the check constraint restricting the column to its values, empty if it has none
+func (t Type) Check(column string) string {
+	if t.Values == "" {
+		return ""
+	}
+
+	return fmt.Sprintf("CHECK (%s IN (%s))", column, t.Values)
+}
+
+// Mapper - maps source column types to target column types by the registered mapping,
+// unless the column type is overridden
+type Mapper struct {
+	mapping Mapping
+	// overrides - target types by lowercased table and column, as the config file keys are lowercased
+	overrides map[string]map[string]string
+}
+
+// New - creates the mapper of the source driver column types to the target driver ones
+func New(source, target string, overrides map[string]map[string]string) (*Mapper, error) {
+	mappingsMu.RLock()
+	defer mappingsMu.RUnlock()
+
+	m, ok := mappings[key(source, target)]
+	if !ok {
+		return nil, fmt.Errorf("no type mapping from %s to %s", source, target)
+	}
+
+	lowered := make(map[string]map[string]string, len(overrides))
+	for table, columns := range overrides {
+		t := strings.ToLower(table)
+		if lowered[t] == nil {
+			lowered[t] = make(map[string]string, len(columns))
+		}
+
+		for column, typ := range columns {
+			lowered[t][strings.ToLower(column)] = typ
+		}
+	}
+
+	return &Mapper{
+		mapping:   m,
+		overrides: lowered,
+	}, nil
+}
+
+// Map - returns the target type of a table column of the given source type
+func (m *Mapper) Map(table, column, typ string) Type {
+	if t, ok := m.overrides[strings.ToLower(table)][strings.ToLower(column)]; ok {
+		return Type{
+			Name: t,
+		}
+	}
+
+	name, args, modifiers := splitType(typ)
+	lookups := []string{
+		strings.ToLower(strings.TrimSpace(name + args + " " + modifiers)),
+		strings.ToLower(strings.TrimSpace(name + " " + modifiers)),
+		strings.ToLower(name),
+	}
+
+	target := m.mapping.Default
+	for _, lookup := range lookups {
+		if t, ok := m.mapping.Types[lookup]; ok {
+			target = t
+			break
+		}
+	}
+
+	if args == "" {
+		target = strings.Replace(target, "(*)", "", -1)
+	} else {
+		target = strings.Replace(target, "(*)", args, -1)
+	}
+
+	t := Type{
+		Name: target,
+	}
+	if strings.EqualFold(name, "enum") && args != "" {
+		t.Values = args[1 : len(args)-1]
+	}
+
+	return t
+}
+
+// splitType - splits a column type in its name, its parenthesized length, precision or values,
+// and its modifiers, zerofill being left out since no other engine has it
+func splitType(typ string) (string, string, string) {
+	typ = strings.TrimSpace(typ)
+
+	i := strings.IndexAny(typ, "( ")
+	if i < 0 {
+		return typ, "", ""
+	}
+
+	name, rest := typ[:i], typ[i:]
+
+	var args string
+	if strings.HasPrefix(rest, "(") {
+		if j := strings.LastIndexByte(rest, ')'); j > 0 {
+			args, rest = rest[:j+1], rest[j+1:]
+		}
+	}
+
+	var modifiers []string
+	for _, modifier := range strings.Fields(rest) {
+		if !strings.EqualFold(modifier, "zerofill") {
+			modifiers = append(modifiers, modifier)
+		}
+	}
+
+	return name, args, strings.Join(modifiers, " ")
+}
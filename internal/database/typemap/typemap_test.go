@@ -0,0 +1,108 @@
+package typemap
+
+import (
+	"testing"
+)
+
+func init() {
+	Register("source", "target", Mapping{
+		Types: map[string]string{
+			"tinyint(1)":   "boolean",
+			"int unsigned": "bigint",
+			"int":          "integer",
+			"varchar":      "varchar(*)",
+			"decimal":      "numeric(*)",
+			"enum":         "text",
+			"datetime":     "timestamp(*)",
+		},
+		Default: "text",
+	})
+}
+
+func TestSplitType(t *testing.T) {
+	tests := []struct {
+		typ                   string
+		name, args, modifiers string
+	}{
+		{"int", "int", "", ""},
+		{" int(11) ", "int", "(11)", ""},
+		{"int(10) unsigned zerofill", "int", "(10)", "unsigned"},
+		{"int unsigned", "int", "", "unsigned"},
+		{"decimal(10,2)", "decimal", "(10,2)", ""},
+		{"enum('a','b)c')", "enum", "('a','b)c')", ""},
+		{"double precision", "double", "", "precision"},
+		{"BIGINT(20) UNSIGNED ZEROFILL", "BIGINT", "(20)", "UNSIGNED"},
+	}
+
+	for _, tt := range tests {
+		name, args, modifiers := splitType(tt.typ)
+		if name != tt.name || args != tt.args || modifiers != tt.modifiers {
+			t.Errorf("splitType(%q) = %q, %q, %q, want %q, %q, %q",
+				tt.typ, name, args, modifiers, tt.name, tt.args, tt.modifiers)
+		}
+	}
+}
+
+func TestMap(t *testing.T) {
+	m, err := New("source", "target", map[string]map[string]string{
+		"Users": {"Settings": "jsonb"},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %s", err)
+	}
+
+	tests := []struct {
+		name   string
+		table  string
+		column string
+		typ    string
+		want   Type
+	}{
+		{name: "full type first", typ: "tinyint(1)", want: Type{Name: "boolean"}},
+		{name: "type without length", typ: "int(10) unsigned", want: Type{Name: "bigint"}},
+		{name: "type name", typ: "int(11)", want: Type{Name: "integer"}},
+		{name: "case-insensitive lookup", typ: "INT(11)", want: Type{Name: "integer"}},
+		{name: "length kept", typ: "varchar(255)", want: Type{Name: "varchar(255)"}},
+		{name: "precision kept", typ: "decimal(10,2)", want: Type{Name: "numeric(10,2)"}},
+		{name: "placeholder without length", typ: "datetime", want: Type{Name: "timestamp"}},
+		{name: "default", typ: "geometry", want: Type{Name: "text"}},
+		{name: "enum values", typ: "enum('a','b')", want: Type{Name: "text", Values: "'a','b'"}},
+		{name: "override", table: "users", column: "settings", typ: "longtext", want: Type{Name: "jsonb"}},
+		{name: "override of other columns", table: "users", column: "name", typ: "int", want: Type{Name: "integer"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := m.Map(tt.table, tt.column, tt.typ); got != tt.want {
+				t.Errorf("Map(%q) = %+v, want %+v", tt.typ, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewUnknownMapping(t *testing.T) {
+	if _, err := New("target", "source", nil); err == nil || err.Error() != "no type mapping from target to source" {
+		t.Errorf("New() error = %v", err)
+	}
+}
+
+func TestTypeCheck(t *testing.T) {
+	if got := (Type{Name: "text"}).Check(`"status"`); got != "" {
+		t.Errorf("Check() without values = %q", got)
+	}
+
+	want := `CHECK ("status" IN ('new','done'))`
+	if got := (Type{Name: "text", Values: "'new','done'"}).Check(`"status"`); got != want {
+		t.Errorf("Check() = %q, want %q", got, want)
+	}
+}
+
+func TestRegisterTwice(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Register() of an existing mapping did not panic")
+		}
+	}()
+
+	Register("source", "target", Mapping{})
+}
@@ -0,0 +1,323 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Level - severity of a message
+type Level int
+
+// levels, from the most verbose
+const (
+	DebugLevel Level = iota
+	InfoLevel
+	WarnLevel
+	ErrorLevel
+)
+
+var levelNames = map[Level]string{
+	DebugLevel: "debug",
+	InfoLevel:  "info",
+	WarnLevel:  "warn",
+	ErrorLevel: "error",
+}
+
+func (l Level) String() string {
+	return levelNames[l]
+}
+
+// ParseLevel - returns the level of a name: debug, info, warn or error
+func ParseLevel(s string) (Level, error) {
+	for level, name := range levelNames {
+		if strings.EqualFold(s, name) {
+			return level, nil
+		}
+	}
+
+	return InfoLevel, fmt.Errorf("unknown log level %s, expected debug, info, warn or error", s)
+}
+
+// formats
+const (
+	TextFormat = "text"
+	JSONFormat = "json"
+)
+
+// validateFormat - returns an error if the format is unknown
+func validateFormat(format string) error {
+	if format != TextFormat && format != JSONFormat {
+		return fmt.Errorf("unknown log format %s, expected %s or %s", format, TextFormat, JSONFormat)
+	}
+
+	return nil
+}
+
+// minSecretLength - registered secrets shorter than this are not redacted, as they would
+// mangle unrelated words
+const minSecretLength = 3
+
+// Logger - writes leveled messages with key value fields as text or json lines, redacting
+// the registered secrets, passwords and DSNs. Safe for concurrent use.
+type Logger struct {
+	mu      sync.Mutex
+	w       io.Writer
+	level   Level
+	format  string
+	secrets []string
+}
+
+// New - constructor
+func New(w io.Writer, level Level, format string) (*Logger, error) {
+	if err := validateFormat(format); err != nil {
+		return nil, err
+	}
+
+	return &Logger{
+		w:      w,
+		level:  level,
+		format: format,
+	}, nil
+}
+
+// AddSecret - redacts s from every message and field logged from now on
+func (l *Logger) AddSecret(s string) {
+	if len(s) < minSecretLength {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, secret := range l.secrets {
+		if secret == s {
+			return
+		}
+	}
+
+	l.secrets = append(l.secrets, s)
+}
+
+// Format - returns the format of the lines, text or json
+func (l *Logger) Format() string {
+	return l.format
+}
+
+// Enabled - returns true if messages of the level are written
+func (l *Logger) Enabled(level Level) bool {
+	return level >= l.level
+}
+
+// Debug - logs a debug message with key value fields
+func (l *Logger) Debug(msg string, kv ...interface{}) {
+	l.log(DebugLevel, msg, kv)
+}
+
+// Info - logs an info message with key value fields
+func (l *Logger) Info(msg string, kv ...interface{}) {
+	l.log(InfoLevel, msg, kv)
+}
+
+// Warn - logs a warning with key value fields
+func (l *Logger) Warn(msg string, kv ...interface{}) {
+	l.log(WarnLevel, msg, kv)
+}
+
+// Error - logs an error message with key value fields
+func (l *Logger) Error(msg string, kv ...interface{}) {
+	l.log(ErrorLevel, msg, kv)
+}
+
+// Redact - returns s without the registered secrets, passwords and DSN credentials
+func (l *Logger) Redact(s string) string {
+	l.mu.Lock()
+	secrets := l.secrets
+	l.mu.Unlock()
+
+	return redact(s, secrets)
+}
+
+func (l *Logger) log(level Level, msg string, kv []interface{}) {
+	if !l.Enabled(level) {
+		return
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	var line []byte
+	if l.format == JSONFormat {
+		line = l.jsonLine(time.Now(), level, msg, kv)
+	} else {
+		line = l.textLine(time.Now(), level, msg, kv)
+	}
+
+	l.w.Write(line)
+}
+
+// textLine - returns e.g. 2006/01/02 15:04:05 INFO Syncing tables=3 error="dump users: ..."
+func (l *Logger) textLine(t time.Time, level Level, msg string, kv []interface{}) []byte {
+	var b bytes.Buffer
+	b.WriteString(t.Format("2006/01/02 15:04:05 "))
+	b.WriteString(strings.ToUpper(level.String()))
+	b.WriteString(" ")
+	b.WriteString(redact(msg, l.secrets))
+
+	for i := 0; i < len(kv); i += 2 {
+		key, value := field(kv, i)
+		s := redact(formatValue(value), l.secrets)
+		if s == "" || strings.ContainsAny(s, " \t\n\"=") {
+			s = strconv.Quote(s)
+		}
+
+		fmt.Fprintf(&b, " %s=%s", key, s)
+	}
+
+	b.WriteString("\n")
+
+	return b.Bytes()
+}
+
+// jsonLine - returns e.g. {"time":"...","level":"info","msg":"Syncing","tables":3}
+func (l *Logger) jsonLine(t time.Time, level Level, msg string, kv []interface{}) []byte {
+	var b bytes.Buffer
+	b.WriteString(`{"time":`)
+	writeJSON(&b, t.Format(time.RFC3339))
+	b.WriteString(`,"level":`)
+	writeJSON(&b, level.String())
+	b.WriteString(`,"msg":`)
+	writeJSON(&b, redact(msg, l.secrets))
+
+	for i := 0; i < len(kv); i += 2 {
+		key, value := field(kv, i)
+		b.WriteString(",")
+		writeJSON(&b, key)
+		b.WriteString(":")
+
+		switch v := value.(type) {
+		case bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+			writeJSON(&b, v)
+		default:
+			writeJSON(&b, redact(formatValue(value), l.secrets))
+		}
+	}
+
+	b.WriteString("}\n")
+
+	return b.Bytes()
+}
+
+// field - returns the key and value at i, the key of a lone value being "field"
+func field(kv []interface{}, i int) (string, interface{}) {
+	if i+1 >= len(kv) {
+		return "field", kv[i]
+	}
+
+	return fmt.Sprint(kv[i]), kv[i+1]
+}
+
+func formatValue(v interface{}) string {
+	switch v := v.(type) {
+	case error:
+		return v.Error()
+	case []string:
+		return strings.Join(v, ", ")
+	case time.Duration:
+		return v.String()
+	}
+
+	return fmt.Sprint(v)
+}
+
+func writeJSON(b *bytes.Buffer, v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		data, _ = json.Marshal(err.Error())
+	}
+
+	b.Write(data)
+}
+
+// lineWriter - logs every line written at a level
+type lineWriter struct {
+	l     *Logger
+	level Level
+}
+
+func (w *lineWriter) Write(p []byte) (int, error) {
+	for _, line := range strings.Split(strings.TrimRight(string(p), "\n"), "\n") {
+		w.l.log(w.level, line, nil)
+	}
+
+	return len(p), nil
+}
+
+// Writer - returns a writer logging every line written at the level, e.g. for the standard logger
+func (l *Logger) Writer(level Level) io.Writer {
+	return &lineWriter{l: l, level: level}
+}
+
+var std = &Logger{w: os.Stderr, level: InfoLevel, format: TextFormat}
+
+// Configure - sets the level and format of the default logger, writing to stderr
+func Configure(level, format string) error {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return err
+	}
+
+	if err := validateFormat(format); err != nil {
+		return err
+	}
+
+	std.mu.Lock()
+	defer std.mu.Unlock()
+
+	std.level = lvl
+	std.format = format
+
+	return nil
+}
+
+// Default - returns the default logger
+func Default() *Logger {
+	return std
+}
+
+// AddSecret - redacts s from the messages of the default logger
+func AddSecret(s string) {
+	std.AddSecret(s)
+}
+
+// Debug - logs a debug message with the default logger
+func Debug(msg string, kv ...interface{}) {
+	std.log(DebugLevel, msg, kv)
+}
+
+// Info - logs an info message with the default logger
+func Info(msg string, kv ...interface{}) {
+	std.log(InfoLevel, msg, kv)
+}
+
+// Warn - logs a warning with the default logger
+func Warn(msg string, kv ...interface{}) {
+	std.log(WarnLevel, msg, kv)
+}
+
+// Error - logs an error message with the default logger
+func Error(msg string, kv ...interface{}) {
+	std.log(ErrorLevel, msg, kv)
+}
+
+// Fatal - logs an error message with the default logger and exits with code 1
+func Fatal(msg string, kv ...interface{}) {
+	std.log(ErrorLevel, msg, kv)
+	os.Exit(1)
+}
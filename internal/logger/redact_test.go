@@ -0,0 +1,74 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestRedact(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		secrets []string
+		want    string
+	}{
+		{"nothing to redact", "Syncing 3 tables", nil, "Syncing 3 tables"},
+		{"secret", "token s3cr3t rejected", []string{"s3cr3t"}, "token *** rejected"},
+		{"url", "dial postgres://app:s3cr3t@db:5432/app", nil, "dial postgres://app:***@db:5432/app"},
+		{"url without password", "dial postgres://app@db/app", nil, "dial postgres://app@db/app"},
+		{"mysql dsn", "open app:s3cr3t@tcp(db:3306)/app", nil, "open app:***@tcp(db:3306)/app"},
+		{"mysql socket dsn", "open app:s3:cr3t@unix(/tmp/mysql.sock)/app", nil, "open app:***@unix(/tmp/mysql.sock)/app"},
+		{"client argument", "mysqldump -uapp -ps3cr3t app", nil, "mysqldump -uapp -p*** app"},
+		{"client arguments", "mysql -h db -P 3306 -u app -ps3cr3t shop", nil, "mysql -h db -P 3306 -u app -p*** shop"},
+		{"client argument list", "[-uapp,-ps3cr3t,app]", nil, "[-uapp,-p***,app]"},
+		{"client slice", "[-h db -P 3306 -u app -ps3cr3t shop]", nil, "[-h db -P 3306 -u app -p*** shop]"},
+		{"password option", "mysql --user=app --password=s3cr3t shop", nil, "mysql --user=app --password=*** shop"},
+		{"port argument", "mysql -P3306", nil, "mysql -P3306"},
+		{"production flag", "deploy -production", nil, "deploy -production"},
+		{"port option", "pg_dump -port=5432 -p5432", nil, "pg_dump -port=5432 -p5432"},
+		{"argument list without user", "[-pretty,-print]", nil, "[-pretty,-print]"},
+		{"environment", "PGPASSWORD=s3cr3t pg_dump", nil, "PGPASSWORD=*** pg_dump"},
+		{"yaml field", "password: s3cr3t", nil, "password: ***"},
+		{"quoted field", `pwd = "s3 cr3t" host`, nil, "pwd = *** host"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := redact(tt.s, tt.secrets); got != tt.want {
+				t.Errorf("redact(%q) = %q, want %q", tt.s, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLoggerRedactsSecrets(t *testing.T) {
+	var buf bytes.Buffer
+	l, err := New(&buf, DebugLevel, JSONFormat)
+	if err != nil {
+		t.Fatalf("New() error = %s", err)
+	}
+
+	l.AddSecret("ab")
+	l.AddSecret("hunter2")
+	l.Error("Dump failed for hunter2", "error", errors.New("access denied for app:hunter2@tcp(db)"), "rows", 3)
+
+	var line map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &line); err != nil {
+		t.Fatalf("invalid json line %s: %s", buf.String(), err)
+	}
+
+	if strings.Contains(buf.String(), "hunter2") {
+		t.Errorf("secret logged: %s", buf.String())
+	}
+
+	if line["msg"] != "Dump failed for ***" || line["rows"] != float64(3) {
+		t.Errorf("line = %s", buf.String())
+	}
+
+	if got := l.Redact("about"); got != "about" {
+		t.Errorf("Redact() of a short secret = %q, want about", got)
+	}
+}
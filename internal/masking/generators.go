@@ -0,0 +1,69 @@
+package masking
+
+import (
+	"fmt"
+	"strings"
+)
+
+var firstNames = []string{
+	"James", "Mary", "John", "Patricia", "Robert", "Jennifer", "Michael", "Linda", "William", "Elizabeth",
+	"David", "Barbara", "Richard", "Susan", "Joseph", "Jessica", "Thomas", "Sarah", "Charles", "Karen",
+	"Daniel", "Nancy", "Matthew", "Lisa", "Anthony", "Sandra", "Mark", "Ashley", "Paul", "Emily",
+}
+
+var lastNames = []string{
+	"Smith", "Johnson", "Williams", "Brown", "Jones", "Garcia", "Miller", "Davis", "Rodriguez", "Martinez",
+	"Hernandez", "Lopez", "Gonzalez", "Wilson", "Anderson", "Taylor", "Moore", "Jackson", "Martin", "Lee",
+	"Thompson", "White", "Harris", "Clark", "Lewis", "Robinson", "Walker", "Young", "Allen", "King",
+}
+
+var cities = []string{
+	"Springfield", "Riverside", "Fairview", "Franklin", "Greenville", "Bristol", "Clinton", "Salem",
+	"Madison", "Georgetown", "Arlington", "Ashland", "Oxford", "Dover", "Milton", "Newport",
+}
+
+var companies = []string{
+	"Acme", "Globex", "Initech", "Umbrella", "Hooli", "Vandelay", "Stark", "Wayne", "Cyberdyne", "Soylent",
+}
+
+var companySuffixes = []string{"Inc", "LLC", "Ltd", "Group", "Corp"}
+
+var streets = []string{
+	"Main St", "Oak Ave", "Pine St", "Maple Ave", "Cedar Rd", "Elm St", "Lake Dr", "Hill Rd", "Park Ave",
+}
+
+// generators - fake value generators by name
+var generators = map[string]func(s *stream) string{
+	"first_name": func(s *stream) string {
+		return s.pick(firstNames)
+	},
+	"last_name": func(s *stream) string {
+		return s.pick(lastNames)
+	},
+	"name": func(s *stream) string {
+		return s.pick(firstNames) + " " + s.pick(lastNames)
+	},
+	"username": func(s *stream) string {
+		return fmt.Sprintf("%s%d", strings.ToLower(s.pick(firstNames)), s.intn(100000))
+	},
+	"email": func(s *stream) string {
+		return fmt.Sprintf(
+			"%s.%s%d@example.com",
+			strings.ToLower(s.pick(firstNames)),
+			strings.ToLower(s.pick(lastNames)),
+			s.intn(100000),
+		)
+	},
+	"phone": func(s *stream) string {
+		return fmt.Sprintf("555-%03d-%04d", s.intn(1000), s.intn(10000))
+	},
+	"address": func(s *stream) string {
+		return fmt.Sprintf("%d %s", 1+s.intn(9999), s.pick(streets))
+	},
+	"city": func(s *stream) string {
+		return s.pick(cities)
+	},
+	"company": func(s *stream) string {
+		return s.pick(companies) + " " + s.pick(companySuffixes)
+	},
+}
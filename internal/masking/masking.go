@@ -0,0 +1,200 @@
+package masking
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+// Rule types
+const (
+	// TypeFixed - replaces the value with a fixed value
+	TypeFixed = "fixed"
+	// TypeFake - replaces the value with a generated one, e.g. a name or an email
+	TypeFake = "fake"
+	// TypeHash - replaces the value with its keyed hash
+	TypeHash = "hash"
+	// TypeNull - replaces the value with NULL
+	TypeNull = "null"
+	// TypePreserveFormat - replaces every digit and letter keeping punctuation and length
+	TypePreserveFormat = "preserve-format"
+)
+
+// Rule - masking rule of a column
+type Rule struct {
+	Type string
+	// Value - value used by fixed rules
+	Value string
+	// Generator - generator used by fake rules
+	Generator string
+}
+
+// Masker - masks column values by table and column rules. Masked values only depend on
+// the seed and the original value, so equal values are masked equally across tables.
+// Tables and columns are matched case-insensitively, as the config file keys are lowercased.
+type Masker struct {
+	seed []byte
+	// rules - rules by lowercased table and column
+	rules map[string]map[string]Rule
+}
+
+// New - creates a masker from rules by table and column. Hashes without a seed are the
+// plain hmac of the values, which anyone can compute, so hash rules require a seed.
+func New(seed string, rules map[string]map[string]Rule) (*Masker, error) {
+	m := &Masker{
+		seed:  []byte(seed),
+		rules: make(map[string]map[string]Rule, len(rules)),
+	}
+
+	for table, columns := range rules {
+		for column, rule := range columns {
+			if err := rule.validate(); err != nil {
+				return nil, fmt.Errorf("masking rule %s.%s: %s", table, column, err)
+			}
+
+			if rule.Type == TypeHash && seed == "" {
+				return nil, fmt.Errorf("masking rule %s.%s: hash rules require a masking seed", table, column)
+			}
+
+			t := strings.ToLower(table)
+			if m.rules[t] == nil {
+				m.rules[t] = make(map[string]Rule, len(columns))
+			}
+
+			m.rules[t][strings.ToLower(column)] = rule
+		}
+	}
+
+	return m, nil
+}
+
+// Columns - returns the lowercased masked columns by lowercased table
+func (m *Masker) Columns() map[string][]string {
+	columns := make(map[string][]string, len(m.rules))
+	for table, rules := range m.rules {
+		for column := range rules {
+			columns[table] = append(columns[table], column)
+		}
+	}
+
+	return columns
+}
+
+func (r Rule) validate() error {
+	switch r.Type {
+	case TypeFixed, TypeHash, TypeNull, TypePreserveFormat:
+		return nil
+	case TypeFake:
+		if _, ok := generators[r.Generator]; !ok {
+			return fmt.Errorf("unknown generator %q", r.Generator)
+		}
+
+		return nil
+	}
+
+	return fmt.Errorf("unknown type %q", r.Type)
+}
+
+// MasksTable - returns true when some columns of the table are masked
+func (m *Masker) MasksTable(table string) bool {
+	return len(m.rules[strings.ToLower(table)]) > 0
+}
+
+// Mask - returns the masked value of a table column, or value itself when the column
+// is not masked; nil stands for NULL
+func (m *Masker) Mask(table, column string, value *string) *string {
+	rule, ok := m.rules[strings.ToLower(table)][strings.ToLower(column)]
+	if !ok {
+		return value
+	}
+
+	var masked string
+	switch rule.Type {
+	case TypeNull:
+		return nil
+	case TypeFixed:
+		masked = rule.Value
+	case TypeHash:
+		if value == nil {
+			return nil
+		}
+
+		masked = hex.EncodeToString(m.sum(TypeHash, *value))[:32]
+	case TypeFake:
+		if value == nil {
+			return nil
+		}
+
+		masked = generators[rule.Generator](newStream(m.sum(TypeFake+rule.Generator, *value)))
+	case TypePreserveFormat:
+		if value == nil {
+			return nil
+		}
+
+		masked = preserveFormat(*value, newStream(m.sum(TypePreserveFormat, *value)))
+	}
+
+	return &masked
+}
+
+func (m *Masker) sum(kind, value string) []byte {
+	mac := hmac.New(sha256.New, m.seed)
+	mac.Write([]byte(kind))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+
+	return mac.Sum(nil)
+}
+
+// stream - deterministic source of numbers derived from a key
+type stream struct {
+	key     []byte
+	block   []byte
+	counter uint32
+}
+
+func newStream(key []byte) *stream {
+	return &stream{
+		key: key,
+	}
+}
+
+// intn - returns a number in [0, n)
+func (s *stream) intn(n int) int {
+	if len(s.block) < 4 {
+		mac := hmac.New(sha256.New, s.key)
+		binary.Write(mac, binary.BigEndian, s.counter)
+		s.counter++
+		s.block = mac.Sum(nil)
+	}
+
+	v := binary.BigEndian.Uint32(s.block)
+	s.block = s.block[4:]
+
+	return int(v % uint32(n))
+}
+
+func (s *stream) pick(values []string) string {
+	return values[s.intn(len(values))]
+}
+
+func preserveFormat(value string, s *stream) string {
+	var b strings.Builder
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			b.WriteRune('0' + rune(s.intn(10)))
+		case r >= 'a' && r <= 'z':
+			b.WriteRune('a' + rune(s.intn(26)))
+		case r >= 'A' && r <= 'Z':
+			b.WriteRune('A' + rune(s.intn(26)))
+		default:
+			b.WriteRune(r)
+		}
+	}
+
+	return b.String()
+}
@@ -0,0 +1,136 @@
+package masking
+
+import (
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func strPtr(s string) *string {
+	return &s
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		seed string
+		rule Rule
+		want string
+	}{
+		{"unknown type", "seed", Rule{Type: "shuffle"}, `masking rule users.email: unknown type "shuffle"`},
+		{"unknown generator", "seed", Rule{Type: TypeFake, Generator: "ssn"}, `masking rule users.email: unknown generator "ssn"`},
+		{"hash without seed", "", Rule{Type: TypeHash}, "masking rule users.email: hash rules require a masking seed"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New(tt.seed, map[string]map[string]Rule{"users": {"email": tt.rule}})
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("New() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMask(t *testing.T) {
+	m, err := New("seed", map[string]map[string]Rule{
+		"users": {
+			"password": {Type: TypeFixed, Value: "secret"},
+			"token":    {Type: TypeHash},
+			"notes":    {Type: TypeNull},
+			"phone":    {Type: TypePreserveFormat},
+			"email":    {Type: TypeFake, Generator: "email"},
+		},
+	})
+	if err != nil {
+		t.Fatalf("New() error = %s", err)
+	}
+
+	tests := []struct {
+		name    string
+		column  string
+		value   *string
+		want    *regexp.Regexp
+		wantNil bool
+	}{
+		{name: "fixed", column: "password", value: strPtr("hunter2"), want: regexp.MustCompile(`^secret$`)},
+		{name: "fixed NULL", column: "password", want: regexp.MustCompile(`^secret$`)},
+		{name: "hash", column: "token", value: strPtr("abc"), want: regexp.MustCompile(`^[0-9a-f]{32}$`)},
+		{name: "hash NULL", column: "token", wantNil: true},
+		{name: "null", column: "notes", value: strPtr("private"), wantNil: true},
+		{name: "preserve format", column: "phone", value: strPtr("+1 (555) 010-99ab"), want: regexp.MustCompile(`^\+\d \(\d{3}\) \d{3}-\d{2}[a-z]{2}$`)},
+		{name: "fake", column: "email", value: strPtr("ann@corp.io"), want: regexp.MustCompile(`^[a-z]+\.[a-z]+\d+@example\.com$`)},
+		{name: "fake NULL", column: "email", wantNil: true},
+		{name: "column not masked", column: "id", value: strPtr("42"), want: regexp.MustCompile(`^42$`)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := m.Mask("users", tt.column, tt.value)
+			if tt.wantNil {
+				if got != nil {
+					t.Errorf("Mask() = %q, want NULL", *got)
+				}
+
+				return
+			}
+
+			if got == nil || !tt.want.MatchString(*got) {
+				t.Errorf("Mask() = %v, want a match of %s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMaskDeterministic(t *testing.T) {
+	rules := map[string]map[string]Rule{
+		"users":  {"email": {Type: TypeHash}},
+		"orders": {"email": {Type: TypeHash}},
+	}
+	m, _ := New("seed", rules)
+	other, _ := New("other seed", rules)
+
+	a := m.Mask("users", "email", strPtr("ann@corp.io"))
+	b := m.Mask("orders", "email", strPtr("ann@corp.io"))
+	if *a != *b {
+		t.Errorf("equal values masked differently across tables: %s, %s", *a, *b)
+	}
+
+	if c := m.Mask("users", "email", strPtr("bob@corp.io")); *c == *a {
+		t.Errorf("different values masked equally: %s", *c)
+	}
+
+	if c := other.Mask("users", "email", strPtr("ann@corp.io")); *c == *a {
+		t.Errorf("different seeds masked equally: %s", *c)
+	}
+}
+
+func TestMaskCaseInsensitive(t *testing.T) {
+	m, err := New("seed", map[string]map[string]Rule{"Users": {"EMAIL": {Type: TypeNull}}})
+	if err != nil {
+		t.Fatalf("New() error = %s", err)
+	}
+
+	if !m.MasksTable("users") || !m.MasksTable("USERS") || m.MasksTable("orders") {
+		t.Errorf("MasksTable() does not match tables case-insensitively")
+	}
+
+	if got := m.Mask("uSers", "Email", strPtr("a@b.c")); got != nil {
+		t.Errorf("Mask() = %q, want NULL", *got)
+	}
+
+	want := map[string][]string{"users": {"email"}}
+	if got := m.Columns(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Columns() = %v, want %v", got, want)
+	}
+}
+
+func TestGenerators(t *testing.T) {
+	for name, generate := range generators {
+		a := generate(newStream([]byte("key")))
+		b := generate(newStream([]byte("key")))
+		if a == "" || a != b {
+			t.Errorf("generator %s: %q then %q from the same key", name, a, b)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Registry - metrics written in the prometheus text exposition format. Safe for concurrent use.
+type Registry struct {
+	mu       sync.Mutex
+	families []*family
+}
+
+// family - metric with its values by labels
+type family struct {
+	name   string
+	help   string
+	kind   string
+	label  string
+	values map[string]float64
+	// value - returns the value when written, for gauges computed on scrape
+	value func() float64
+}
+
+// Counter - metric which only increases, optionally split by a label
+type Counter struct {
+	r *Registry
+	f *family
+}
+
+// Gauge - metric which goes up and down
+type Gauge struct {
+	r *Registry
+	f *family
+}
+
+// Summary - sum and count of observations, e.g. durations in seconds
+type Summary struct {
+	r *Registry
+	f *family
+}
+
+// NewRegistry - constructor
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) add(name, help, kind, label string) *family {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	f := &family{
+		name:   name,
+		help:   help,
+		kind:   kind,
+		label:  label,
+		values: make(map[string]float64),
+	}
+	r.families = append(r.families, f)
+
+	return f
+}
+
+// NewCounter - registers a counter, split by label when not empty
+func (r *Registry) NewCounter(name, help, label string) *Counter {
+	return &Counter{r: r, f: r.add(name, help, "counter", label)}
+}
+
+// NewGauge - registers a gauge
+func (r *Registry) NewGauge(name, help string) *Gauge {
+	return &Gauge{r: r, f: r.add(name, help, "gauge", "")}
+}
+
+// NewGaugeFunc - registers a gauge whose value is returned by f when written
+func (r *Registry) NewGaugeFunc(name, help string, f func() float64) {
+	r.add(name, help, "gauge", "").value = f
+}
+
+// NewSummary - registers a summary, written as its _sum and _count
+func (r *Registry) NewSummary(name, help string) *Summary {
+	return &Summary{r: r, f: r.add(name, help, "summary", "")}
+}
+
+// Add - increases the counter of the label value by v
+func (c *Counter) Add(labelValue string, v float64) {
+	c.r.mu.Lock()
+	defer c.r.mu.Unlock()
+
+	c.f.values[labelValue] += v
+}
+
+// Inc - increases the counter of the label value by one
+func (c *Counter) Inc(labelValue string) {
+	c.Add(labelValue, 1)
+}
+
+// Set - sets the gauge
+func (g *Gauge) Set(v float64) {
+	g.r.mu.Lock()
+	defer g.r.mu.Unlock()
+
+	g.f.values[""] = v
+}
+
+// Observe - adds an observation
+func (s *Summary) Observe(v float64) {
+	s.r.mu.Lock()
+	defer s.r.mu.Unlock()
+
+	s.f.values["_sum"] += v
+	s.f.values["_count"]++
+}
+
+// WriteTo - writes every metric in the prometheus text format
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var b strings.Builder
+	for _, f := range r.families {
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.name, f.help)
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.kind)
+
+		switch {
+		case f.value != nil:
+			fmt.Fprintf(&b, "%s %s\n", f.name, formatValue(f.value()))
+		case f.kind == "summary":
+			fmt.Fprintf(&b, "%s_sum %s\n", f.name, formatValue(f.values["_sum"]))
+			fmt.Fprintf(&b, "%s_count %s\n", f.name, formatValue(f.values["_count"]))
+		case f.label == "":
+			fmt.Fprintf(&b, "%s %s\n", f.name, formatValue(f.values[""]))
+		default:
+			keys := make([]string, 0, len(f.values))
+			for k := range f.values {
+				keys = append(keys, k)
+			}
+			sort.Strings(keys)
+
+			for _, k := range keys {
+				fmt.Fprintf(&b, "%s{%s=%s} %s\n", f.name, f.label, quote(k), formatValue(f.values[k]))
+			}
+		}
+	}
+
+	n, err := io.WriteString(w, b.String())
+
+	return int64(n), err
+}
+
+func formatValue(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// quote - quotes a label value, escaping backslashes, quotes and new lines
+func quote(s string) string {
+	s = strings.Replace(s, `\`, `\\`, -1)
+	s = strings.Replace(s, `"`, `\"`, -1)
+	s = strings.Replace(s, "\n", `\n`, -1)
+
+	return `"` + s + `"`
+}
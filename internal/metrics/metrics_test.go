@@ -0,0 +1,88 @@
+package metrics
+
+import (
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestRegistryWriteTo(t *testing.T) {
+	r := NewRegistry()
+	syncs := r.NewCounter("dbsync_syncs_total", "Syncs run", "")
+	errs := r.NewCounter("dbsync_errors_total", "Errors by phase", "phase")
+	last := r.NewGauge("dbsync_last_sync_timestamp_seconds", "Time of the last sync")
+	r.NewGaugeFunc("dbsync_up", "Whether the watcher runs", func() float64 { return 1 })
+	durations := r.NewSummary("dbsync_sync_duration_seconds", "Sync durations")
+	r.NewCounter("dbsync_unused_total", "Counter never increased", "phase")
+
+	syncs.Inc("")
+	syncs.Add("", 2)
+	errs.Inc("import")
+	errs.Inc("dump")
+	errs.Add("dump", 2)
+	errs.Inc("weird \"phase\"\\\nname")
+	last.Set(1600000000.5)
+	durations.Observe(1.5)
+	durations.Observe(0.25)
+
+	var b strings.Builder
+	n, err := r.WriteTo(&b)
+	if err != nil {
+		t.Fatalf("WriteTo() error = %s", err)
+	}
+
+	want := `# HELP dbsync_syncs_total Syncs run
+# TYPE dbsync_syncs_total counter
+dbsync_syncs_total 3
+# HELP dbsync_errors_total Errors by phase
+# TYPE dbsync_errors_total counter
+dbsync_errors_total{phase="dump"} 3
+dbsync_errors_total{phase="import"} 1
+dbsync_errors_total{phase="weird \"phase\"\\\nname"} 1
+# HELP dbsync_last_sync_timestamp_seconds Time of the last sync
+# TYPE dbsync_last_sync_timestamp_seconds gauge
+dbsync_last_sync_timestamp_seconds 1.6000000005e+09
+# HELP dbsync_up Whether the watcher runs
+# TYPE dbsync_up gauge
+dbsync_up 1
+# HELP dbsync_sync_duration_seconds Sync durations
+# TYPE dbsync_sync_duration_seconds summary
+dbsync_sync_duration_seconds_sum 1.75
+dbsync_sync_duration_seconds_count 2
+# HELP dbsync_unused_total Counter never increased
+# TYPE dbsync_unused_total counter
+`
+	if b.String() != want {
+		t.Errorf("WriteTo() =\n%s\nwant\n%s", b.String(), want)
+	}
+
+	if n != int64(len(want)) {
+		t.Errorf("WriteTo() = %d bytes, want %d", n, len(want))
+	}
+}
+
+func TestRegistryConcurrentUpdates(t *testing.T) {
+	r := NewRegistry()
+	c := r.NewCounter("c", "c", "l")
+	s := r.NewSummary("s", "s")
+
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < 100; j++ {
+				c.Inc("a")
+				s.Observe(1)
+				r.WriteTo(&strings.Builder{})
+			}
+		}()
+	}
+	wg.Wait()
+
+	var b strings.Builder
+	r.WriteTo(&b)
+	if !strings.Contains(b.String(), "c{l=\"a\"} 1000\n") || !strings.Contains(b.String(), "s_count 1000\n") {
+		t.Errorf("WriteTo() =\n%s", b.String())
+	}
+}
@@ -0,0 +1,111 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/vcraescu/dbsync/internal/logger"
+)
+
+// checkTimeout - maximum duration of a health check
+const checkTimeout = 5 * time.Second
+
+// Check - health check of a component, e.g. a connection or a tunnel, returning an error when unhealthy
+type Check func(ctx context.Context) error
+
+// Server - serves the metrics on /metrics and the health checks on /healthz
+type Server struct {
+	addr     string
+	registry *Registry
+	checks   map[string]Check
+}
+
+// health - body of /healthz
+type health struct {
+	Status string            `json:"status"`
+	Checks map[string]string `json:"checks"`
+}
+
+// NewServer - constructor
+func NewServer(addr string, registry *Registry, checks map[string]Check) *Server {
+	return &Server{
+		addr:     addr,
+		registry: registry,
+		checks:   checks,
+	}
+}
+
+// Start - listens on the address and serves in the background until ctx is done
+func (s *Server) Start(ctx context.Context) error {
+	l, err := net.Listen("tcp", s.addr)
+	if err != nil {
+		return err
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", s.serveMetrics)
+	mux.HandleFunc("/healthz", s.serveHealth)
+
+	srv := &http.Server{Handler: mux}
+	go func() {
+		<-ctx.Done()
+		srv.Close()
+	}()
+
+	go func() {
+		if err := srv.Serve(l); err != nil && err != http.ErrServerClosed {
+			logger.Error("Metrics server failed", "error", err)
+		}
+	}()
+
+	return nil
+}
+
+func (s *Server) serveMetrics(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	s.registry.WriteTo(w)
+}
+
+// serveHealth - runs the health checks in parallel, responding 503 when any of them fails
+func (s *Server) serveHealth(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), checkTimeout)
+	defer cancel()
+
+	names := make([]string, 0, len(s.checks))
+	for name := range s.checks {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	errs := make([]error, len(names))
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		go func(i int, check Check) {
+			defer wg.Done()
+			errs[i] = check(ctx)
+		}(i, s.checks[name])
+	}
+	wg.Wait()
+
+	h := health{Status: "ok", Checks: make(map[string]string, len(names))}
+	for i, name := range names {
+		h.Checks[name] = "ok"
+		if errs[i] != nil {
+			h.Checks[name] = errs[i].Error()
+			h.Status = "failing"
+		}
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if h.Status != "ok" {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+
+	json.NewEncoder(w).Encode(h)
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestServerHealth(t *testing.T) {
+	ok := func(ctx context.Context) error { return nil }
+	failing := func(ctx context.Context) error { return errors.New("connection refused") }
+
+	tests := []struct {
+		name       string
+		checks     map[string]Check
+		wantCode   int
+		wantHealth health
+	}{
+		{
+			name:       "no checks",
+			wantCode:   http.StatusOK,
+			wantHealth: health{Status: "ok", Checks: map[string]string{}},
+		},
+		{
+			name:       "healthy",
+			checks:     map[string]Check{"master": ok, "slave": ok},
+			wantCode:   http.StatusOK,
+			wantHealth: health{Status: "ok", Checks: map[string]string{"master": "ok", "slave": "ok"}},
+		},
+		{
+			name:       "failing",
+			checks:     map[string]Check{"master": ok, "tunnel": failing},
+			wantCode:   http.StatusServiceUnavailable,
+			wantHealth: health{Status: "failing", Checks: map[string]string{"master": "ok", "tunnel": "connection refused"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s := NewServer("", NewRegistry(), tt.checks)
+			rec := httptest.NewRecorder()
+			s.serveHealth(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+
+			if rec.Code != tt.wantCode {
+				t.Errorf("status code = %d, want %d", rec.Code, tt.wantCode)
+			}
+
+			if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+				t.Errorf("content type = %q", ct)
+			}
+
+			var h health
+			if err := json.Unmarshal(rec.Body.Bytes(), &h); err != nil {
+				t.Fatalf("invalid body %q: %s", rec.Body.String(), err)
+			}
+
+			if !reflect.DeepEqual(h, tt.wantHealth) {
+				t.Errorf("health = %+v, want %+v", h, tt.wantHealth)
+			}
+		})
+	}
+}
+
+func TestServerHealthTimeout(t *testing.T) {
+	// checks are given a deadline, the request context being cancelled here
+	blocked := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+
+	s := NewServer("", NewRegistry(), map[string]Check{"slave": blocked})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	rec := httptest.NewRecorder()
+	s.serveHealth(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil).WithContext(ctx))
+	if rec.Code != http.StatusServiceUnavailable || !strings.Contains(rec.Body.String(), "context canceled") {
+		t.Errorf("serveHealth() = %d %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestServerStart(t *testing.T) {
+	r := NewRegistry()
+	r.NewGauge("dbsync_up", "Whether the watcher runs").Set(1)
+	s := NewServer("127.0.0.1:0", r, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	if err := s.Start(ctx); err != nil {
+		t.Fatalf("Start() error = %s", err)
+	}
+
+	rec := httptest.NewRecorder()
+	s.serveMetrics(rec, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/plain; version=0.0.4") {
+		t.Errorf("content type = %q", ct)
+	}
+
+	if !strings.Contains(rec.Body.String(), "dbsync_up 1\n") {
+		t.Errorf("metrics = %q", rec.Body.String())
+	}
+
+	if err := NewServer("256.0.0.1:0", r, nil).Start(ctx); err == nil {
+		t.Errorf("Start() on an invalid address succeeded")
+	}
+}
@@ -0,0 +1,254 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vcraescu/dbsync/internal/logger"
+	"github.com/vcraescu/dbsync/internal/progress"
+)
+
+// Event - what a notification is sent for
+type Event string
+
+// events
+const (
+	// EventSuccess - a sync succeeded, including when there was nothing to sync
+	EventSuccess Event = "success"
+	// EventFailure - a sync failed
+	EventFailure Event = "failure"
+	// EventDiff - watch detected changes on master
+	EventDiff Event = "diff"
+)
+
+// kinds of notifiers
+const (
+	KindWebhook = "webhook"
+	KindSlack   = "slack"
+)
+
+// sendTimeout - maximum duration of the delivery of a notification
+const sendTimeout = 10 * time.Second
+
+// Summary - what happened, sent as json to webhooks
+type Summary struct {
+	Event      Event     `json:"event"`
+	Master     string    `json:"master"`
+	Slave      string    `json:"slave"`
+	User       string    `json:"user,omitempty"`
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	// Create, Refresh, Delete - slave tables created, refreshed and deleted, or to be for a diff
+	Create  []string `json:"create,omitempty"`
+	Refresh []string `json:"refresh,omitempty"`
+	Delete  []string `json:"delete,omitempty"`
+	// Skipped - tables not compared as they are unchanged since the last sync
+	Skipped int    `json:"skipped,omitempty"`
+	Bytes   int64  `json:"bytes,omitempty"`
+	Rows    int64  `json:"rows,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// Text - returns the summary as a chat message
+func (s Summary) Text() string {
+	var b strings.Builder
+	duration := (time.Duration(s.DurationMs) * time.Millisecond).Round(time.Second)
+	switch s.Event {
+	case EventSuccess:
+		fmt.Fprintf(&b, ":white_check_mark: dbsync synced %s to %s in %s", s.Master, s.Slave, duration)
+	case EventFailure:
+		fmt.Fprintf(&b, ":x: dbsync failed to sync %s to %s after %s: %s", s.Master, s.Slave, duration, s.Error)
+	case EventDiff:
+		fmt.Fprintf(&b, ":mag: dbsync detected changes on %s, to be synced to %s", s.Master, s.Slave)
+	}
+
+	if s.Event == EventSuccess && len(s.Create)+len(s.Refresh)+len(s.Delete) == 0 {
+		b.WriteString(", nothing to sync")
+	}
+
+	for _, tables := range []struct {
+		name   string
+		tables []string
+	}{
+		{"Create", s.Create},
+		{"Refresh", s.Refresh},
+		{"Delete", s.Delete},
+	} {
+		if len(tables.tables) > 0 {
+			fmt.Fprintf(&b, "\n%s tables: %s", tables.name, strings.Join(tables.tables, ", "))
+		}
+	}
+
+	if s.Skipped > 0 {
+		fmt.Fprintf(&b, "\nSkipped %d tables unchanged since the last sync", s.Skipped)
+	}
+
+	var stats []string
+	if s.Rows > 0 {
+		stats = append(stats, fmt.Sprintf("%d rows", s.Rows))
+	}
+
+	if s.Bytes > 0 {
+		stats = append(stats, progress.FormatBytes(s.Bytes))
+	}
+
+	if len(stats) > 0 {
+		fmt.Fprintf(&b, "\nSynced %s", strings.Join(stats, ", "))
+	}
+
+	return b.String()
+}
+
+// Sender - delivers a summary
+type Sender interface {
+	Send(ctx context.Context, s Summary) error
+}
+
+// Webhook - posts the summary as json to a url
+type Webhook struct {
+	URL     string
+	Headers map[string]string
+}
+
+// Send - posts the summary
+func (w *Webhook) Send(ctx context.Context, s Summary) error {
+	body, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+
+	return post(ctx, w.URL, w.Headers, body)
+}
+
+// Slack - posts the summary as the text of a slack compatible incoming webhook
+type Slack struct {
+	URL string
+}
+
+// Send - posts the summary
+func (sl *Slack) Send(ctx context.Context, s Summary) error {
+	body, err := json.Marshal(map[string]string{"text": s.Text()})
+	if err != nil {
+		return err
+	}
+
+	return post(ctx, sl.URL, nil, body)
+}
+
+func post(ctx context.Context, url string, headers map[string]string, body []byte) error {
+	req, err := http.NewRequest(http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+
+	req = req.WithContext(ctx)
+	req.Header.Set("Content-Type", "application/json")
+	for name, value := range headers {
+		req.Header.Set(name, value)
+	}
+
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("Webhook responded %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	return nil
+}
+
+// Notifier - sends the summaries of the events it is subscribed to
+type Notifier struct {
+	Name   string
+	sender Sender
+	events map[Event]bool
+}
+
+// New - constructor. kind is webhook or slack; without events the notifier is subscribed to all of them.
+func New(name, kind, url string, headers map[string]string, events []string) (*Notifier, error) {
+	if url == "" {
+		return nil, fmt.Errorf("Notification %s: url is required", name)
+	}
+
+	n := &Notifier{Name: name, events: make(map[Event]bool)}
+	switch kind {
+	case KindWebhook, "":
+		n.sender = &Webhook{URL: url, Headers: headers}
+	case KindSlack:
+		n.sender = &Slack{URL: url}
+	default:
+		return nil, fmt.Errorf("Notification %s: unknown type %s, expected %s or %s", name, kind, KindWebhook, KindSlack)
+	}
+
+	if len(events) == 0 {
+		events = []string{string(EventSuccess), string(EventFailure), string(EventDiff)}
+	}
+
+	for _, e := range events {
+		switch Event(e) {
+		case EventSuccess, EventFailure, EventDiff:
+			n.events[Event(e)] = true
+		default:
+			return nil, fmt.Errorf(
+				"Notification %s: unknown event %s, expected %s, %s or %s",
+				name,
+				e,
+				EventSuccess,
+				EventFailure,
+				EventDiff,
+			)
+		}
+	}
+
+	return n, nil
+}
+
+// Subscribed - returns true if the notifier sends the summaries of the event
+func (n *Notifier) Subscribed(e Event) bool {
+	return n.events[e]
+}
+
+// Send - sends the summary
+func (n *Notifier) Send(ctx context.Context, s Summary) error {
+	return n.sender.Send(ctx, s)
+}
+
+// Notify - sends the summary to the notifiers subscribed to its event, in parallel. Failures are
+// only logged, a notification never failing a sync.
+func Notify(ctx context.Context, notifiers []*Notifier, s Summary) {
+	var wg sync.WaitGroup
+	for _, n := range notifiers {
+		if !n.Subscribed(s.Event) {
+			continue
+		}
+
+		wg.Add(1)
+		go func(n *Notifier) {
+			defer wg.Done()
+
+			ctx, cancel := context.WithTimeout(ctx, sendTimeout)
+			defer cancel()
+
+			if err := n.Send(ctx, s); err != nil {
+				logger.Warn("Can't send the notification", "notification", n.Name, "event", s.Event, "error", err)
+				return
+			}
+
+			logger.Debug("Notification sent", "notification", n.Name, "event", s.Event)
+		}(n)
+	}
+
+	wg.Wait()
+}
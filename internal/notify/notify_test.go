@@ -0,0 +1,223 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type request struct {
+	method  string
+	headers http.Header
+	body    []byte
+}
+
+// newServer - returns a server recording the requests it receives and responding with status
+func newServer(t *testing.T, status int) (*httptest.Server, chan request) {
+	t.Helper()
+
+	reqs := make(chan request, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := ioutil.ReadAll(r.Body)
+		if err != nil {
+			t.Errorf("read body: %s", err)
+		}
+
+		reqs <- request{method: r.Method, headers: r.Header, body: body}
+		w.WriteHeader(status)
+		w.Write([]byte("  nope\n"))
+	}))
+	t.Cleanup(srv.Close)
+
+	return srv, reqs
+}
+
+func testSummary() Summary {
+	return Summary{
+		Event:      EventSuccess,
+		Master:     "production",
+		Slave:      "local",
+		User:       "ci@runner",
+		StartedAt:  time.Date(2024, 5, 2, 2, 0, 0, 0, time.UTC),
+		DurationMs: 73214,
+		Refresh:    []string{"orders", "users"},
+		Skipped:    12,
+		Bytes:      1325467,
+		Rows:       52110,
+	}
+}
+
+func TestWebhookSend(t *testing.T) {
+	srv, reqs := newServer(t, http.StatusNoContent)
+
+	w := &Webhook{URL: srv.URL, Headers: map[string]string{"Authorization": "Bearer token"}}
+	if err := w.Send(context.Background(), testSummary()); err != nil {
+		t.Fatalf("Send: %s", err)
+	}
+
+	req := <-reqs
+	if req.method != http.MethodPost {
+		t.Errorf("method = %s, want POST", req.method)
+	}
+
+	if got := req.headers.Get("Content-Type"); got != "application/json" {
+		t.Errorf("Content-Type = %q, want application/json", got)
+	}
+
+	if got := req.headers.Get("Authorization"); got != "Bearer token" {
+		t.Errorf("Authorization = %q, want the configured header", got)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(req.body, &got); err != nil {
+		t.Fatalf("payload: %s", err)
+	}
+
+	want := map[string]interface{}{
+		"event":       "success",
+		"master":      "production",
+		"slave":       "local",
+		"user":        "ci@runner",
+		"started_at":  "2024-05-02T02:00:00Z",
+		"duration_ms": float64(73214),
+		"refresh":     []interface{}{"orders", "users"},
+		"skipped":     float64(12),
+		"bytes":       float64(1325467),
+		"rows":        float64(52110),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("payload = %v, want %v", got, want)
+	}
+}
+
+func TestSlackSend(t *testing.T) {
+	srv, reqs := newServer(t, http.StatusOK)
+
+	sl := &Slack{URL: srv.URL}
+	if err := sl.Send(context.Background(), testSummary()); err != nil {
+		t.Fatalf("Send: %s", err)
+	}
+
+	var got map[string]string
+	if err := json.Unmarshal((<-reqs).body, &got); err != nil {
+		t.Fatalf("payload: %s", err)
+	}
+
+	want := ":white_check_mark: dbsync synced production to local in 1m13s\n" +
+		"Refresh tables: orders, users\n" +
+		"Skipped 12 tables unchanged since the last sync\n" +
+		"Synced 52110 rows, 1.3 MiB"
+	if len(got) != 1 || got["text"] != want {
+		t.Errorf("payload = %q, want text %q", got, want)
+	}
+}
+
+func TestSendNon2xx(t *testing.T) {
+	for _, status := range []int{http.StatusMovedPermanently, http.StatusBadRequest, http.StatusInternalServerError} {
+		srv, _ := newServer(t, status)
+
+		for _, s := range []Sender{&Webhook{URL: srv.URL}, &Slack{URL: srv.URL}} {
+			err := s.Send(context.Background(), testSummary())
+			if err == nil {
+				t.Errorf("%T with status %d: no error", s, status)
+				continue
+			}
+
+			want := fmt.Sprintf("Webhook responded %d %s: nope", status, http.StatusText(status))
+			if err.Error() != want {
+				t.Errorf("%T with status %d: error %q, want %q", s, status, err, want)
+			}
+		}
+	}
+}
+
+func TestSummaryText(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary Summary
+		want    string
+	}{
+		{
+			name:    "nothing to sync",
+			summary: Summary{Event: EventSuccess, Master: "m", Slave: "s", DurationMs: 1400},
+			want:    ":white_check_mark: dbsync synced m to s in 1s, nothing to sync",
+		},
+		{
+			name:    "failure",
+			summary: Summary{Event: EventFailure, Master: "m", Slave: "s", DurationMs: 2600, Error: "slave: timeout"},
+			want:    ":x: dbsync failed to sync m to s after 3s: slave: timeout",
+		},
+		{
+			name:    "diff",
+			summary: Summary{Event: EventDiff, Master: "m", Slave: "s", Create: []string{"a"}, Delete: []string{"b", "c"}},
+			want:    ":mag: dbsync detected changes on m, to be synced to s\nCreate tables: a\nDelete tables: b, c",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.summary.Text(); got != tt.want {
+				t.Errorf("Text() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNotifySubscribedOnly(t *testing.T) {
+	srv, reqs := newServer(t, http.StatusOK)
+
+	failures, err := New("failures", KindWebhook, srv.URL, nil, []string{string(EventFailure)})
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+
+	all, err := New("all", KindSlack, srv.URL, nil, nil)
+	if err != nil {
+		t.Fatalf("New: %s", err)
+	}
+
+	Notify(context.Background(), []*Notifier{failures, all}, testSummary())
+	close(reqs)
+
+	var n int
+	for req := range reqs {
+		n++
+		if !strings.Contains(string(req.body), `"text"`) {
+			t.Errorf("unsubscribed notifier sent %s", req.body)
+		}
+	}
+
+	if n != 1 {
+		t.Errorf("sent %d notifications, want 1", n)
+	}
+}
+
+func TestNewErrors(t *testing.T) {
+	tests := []struct {
+		name   string
+		kind   string
+		url    string
+		events []string
+		want   string
+	}{
+		{"no url", KindWebhook, "", nil, "Notification n: url is required"},
+		{"unknown kind", "email", "http://x", nil, "Notification n: unknown type email, expected webhook or slack"},
+		{"unknown event", KindSlack, "http://x", []string{"start"}, "Notification n: unknown event start, expected success, failure or diff"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := New("n", tt.kind, tt.url, nil, tt.events)
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("New() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
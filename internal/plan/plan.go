@@ -0,0 +1,158 @@
+package plan
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vcraescu/dbsync/internal/compress"
+)
+
+const (
+	// Format - format name written in every plan
+	Format = "dbsync-plan"
+	// Version - version of the plan format
+	Version = 1
+)
+
+// Server - server the plan was generated for
+type Server struct {
+	Host   string `json:"host"`
+	Schema string `json:"schema"`
+}
+
+// Plan - sync plan generated from a diff and applied later on slave
+type Plan struct {
+	Format        string    `json:"format"`
+	Version       int       `json:"version"`
+	CreatedAt     time.Time `json:"created_at"`
+	Master        Server    `json:"master"`
+	Slave         Server    `json:"slave"`
+	Create        []string  `json:"create,omitempty"`
+	Refresh       []string  `json:"refresh,omitempty"`
+	Delete        []string  `json:"delete,omitempty"`
+	CreateObjects []string  `json:"create_objects,omitempty"`
+	DropObjects   []string  `json:"drop_objects,omitempty"`
+	// MasterChecksums - content checksums of the synced master tables at generation time
+	MasterChecksums map[string]string `json:"master_checksums"`
+	// SlaveChecksums - content checksums of every slave table at generation time
+	SlaveChecksums map[string]string `json:"slave_checksums"`
+	// Filters - where conditions of the filtered tables, the slave checksums being computed
+	// from their matching rows only
+	Filters map[string]string `json:"filters,omitempty"`
+	SQL     string            `json:"sql"`
+}
+
+// New - creates an empty plan
+func New() *Plan {
+	return &Plan{
+		Format:    Format,
+		Version:   Version,
+		CreatedAt: time.Now().UTC(),
+	}
+}
+
+// Write - writes the plan as json compressed with the given algorithm
+func (p *Plan) Write(w io.Writer, a compress.Algorithm) error {
+	zw, err := compress.NewWriter(w, a)
+	if err != nil {
+		return err
+	}
+
+	if err := p.encode(zw); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+func (p *Plan) encode(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(p)
+}
+
+// WriteFile - writes the plan to file
+func (p *Plan) WriteFile(file string, a compress.Algorithm) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+
+	if err := p.Write(f, a); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+// Read - reads a plan, detecting its compression
+func Read(r io.Reader) (*Plan, error) {
+	zr, err := compress.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("read plan: %s", err)
+	}
+	defer zr.Close()
+
+	p := &Plan{}
+	if err := json.NewDecoder(zr).Decode(p); err != nil {
+		return nil, fmt.Errorf("read plan: %s", err)
+	}
+
+	if p.Format != Format {
+		return nil, fmt.Errorf("read plan: not a %s file", Format)
+	}
+
+	if p.Version != Version {
+		return nil, fmt.Errorf("read plan: unsupported version %d", p.Version)
+	}
+
+	return p, nil
+}
+
+// ReadFile - reads a plan from file
+func ReadFile(file string) (*Plan, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return Read(f)
+}
+
+// Verify - checks that the slave checksums still match the ones recorded at generation time
+func (p *Plan) Verify(slaveChecksums map[string]string) error {
+	var mismatches []string
+	for table, expected := range p.SlaveChecksums {
+		actual, ok := slaveChecksums[table]
+		if !ok {
+			mismatches = append(mismatches, fmt.Sprintf("%s (missing)", table))
+			continue
+		}
+
+		if actual != expected {
+			mismatches = append(mismatches, fmt.Sprintf("%s (changed)", table))
+		}
+	}
+
+	for table := range slaveChecksums {
+		if _, ok := p.SlaveChecksums[table]; !ok {
+			mismatches = append(mismatches, fmt.Sprintf("%s (new)", table))
+		}
+	}
+
+	if len(mismatches) == 0 {
+		return nil
+	}
+
+	sort.Strings(mismatches)
+
+	return fmt.Errorf("slave changed since the plan was generated: %s", strings.Join(mismatches, ", "))
+}
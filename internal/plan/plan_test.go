@@ -0,0 +1,122 @@
+package plan
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vcraescu/dbsync/internal/compress"
+)
+
+func TestVerify(t *testing.T) {
+	p := New()
+	p.SlaveChecksums = map[string]string{"users": "a1", "orders": "b2"}
+
+	tests := []struct {
+		name  string
+		slave map[string]string
+		want  string
+	}{
+		{
+			name:  "unchanged",
+			slave: map[string]string{"users": "a1", "orders": "b2"},
+		},
+		{
+			name:  "changed",
+			slave: map[string]string{"users": "a1", "orders": "c3"},
+			want:  "slave changed since the plan was generated: orders (changed)",
+		},
+		{
+			name:  "missing",
+			slave: map[string]string{"users": "a1"},
+			want:  "slave changed since the plan was generated: orders (missing)",
+		},
+		{
+			name:  "new",
+			slave: map[string]string{"users": "a1", "orders": "b2", "logs": "d4"},
+			want:  "slave changed since the plan was generated: logs (new)",
+		},
+		{
+			name:  "every mismatch in name order",
+			slave: map[string]string{"users": "x", "logs": "d4"},
+			want:  "slave changed since the plan was generated: logs (new), orders (missing), users (changed)",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := p.Verify(tt.slave)
+			if tt.want == "" {
+				if err != nil {
+					t.Errorf("Verify() error = %s", err)
+				}
+
+				return
+			}
+
+			if err == nil || err.Error() != tt.want {
+				t.Errorf("Verify() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestVerifyEmptySlave(t *testing.T) {
+	p := New()
+	if err := p.Verify(map[string]string{}); err != nil {
+		t.Errorf("Verify() of an empty slave against an empty plan: %s", err)
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	p := New()
+	p.Master = Server{Host: "db1", Schema: "app"}
+	p.Slave = Server{Host: "localhost", Schema: "app"}
+	p.Refresh = []string{"users"}
+	p.MasterChecksums = map[string]string{"users": "a1"}
+	p.SlaveChecksums = map[string]string{"users": "b2"}
+	p.SQL = "DROP TABLE IF EXISTS `users`;\n"
+
+	for _, a := range []compress.Algorithm{compress.None, compress.Gzip, compress.Zstd} {
+		var buf bytes.Buffer
+		if err := p.Write(&buf, a); err != nil {
+			t.Fatalf("Write(%s) error = %s", a, err)
+		}
+
+		got, err := Read(&buf)
+		if err != nil {
+			t.Fatalf("Read(%s) error = %s", a, err)
+		}
+
+		if !got.CreatedAt.Equal(p.CreatedAt) {
+			t.Errorf("Read(%s) created at %s, want %s", a, got.CreatedAt, p.CreatedAt)
+		}
+
+		got.CreatedAt = p.CreatedAt
+		if !reflect.DeepEqual(got, p) {
+			t.Errorf("Read(%s) = %+v, want %+v", a, got, p)
+		}
+	}
+}
+
+func TestReadErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		data string
+		want string
+	}{
+		{"not json", "SQL", "read plan: "},
+		{"other format", `{"format": "other", "version": 1}`, "read plan: not a dbsync-plan file"},
+		{"other version", `{"format": "dbsync-plan", "version": 2}`, "read plan: unsupported version 2"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Read(strings.NewReader(tt.data))
+			if err == nil || !strings.HasPrefix(err.Error(), tt.want) {
+				t.Errorf("Read() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,307 @@
+package progress
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vcraescu/dbsync/pkg/dbsync"
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+const (
+	// ttyInterval, plainInterval - time between redraws on a terminal and between lines otherwise
+	ttyInterval   = 200 * time.Millisecond
+	plainInterval = 10 * time.Second
+
+	defaultWidth = 80
+	barWidth     = 20
+	// maxTableLines - number of running tables drawn on a terminal
+	maxTableLines = 8
+)
+
+// Display - draws the progress of a diff or sync: bars redrawn in place on a terminal,
+// or a line every few seconds otherwise, e.g. when the output is redirected to a file
+type Display struct {
+	w        io.Writer
+	progress *dbsync.Progress
+	tty      bool
+	width    int
+	interval time.Duration
+
+	mu    sync.Mutex
+	lines int
+	stop  chan struct{}
+	done  chan struct{}
+}
+
+// New - draws the progress on f, with bars when f is a terminal
+func New(f *os.File, p *dbsync.Progress) *Display {
+	d := &Display{
+		w:        f,
+		progress: p,
+		tty:      terminal.IsTerminal(int(f.Fd())),
+		width:    defaultWidth,
+		interval: plainInterval,
+	}
+
+	if d.tty {
+		d.interval = ttyInterval
+		if width, _, err := terminal.GetSize(int(f.Fd())); err == nil && width > 0 {
+			d.width = width
+		}
+	}
+
+	return d
+}
+
+// NewPlain - writes a progress line every few seconds on w, e.g. a logger writer
+func NewPlain(w io.Writer, p *dbsync.Progress) *Display {
+	return &Display{
+		w:        w,
+		progress: p,
+		width:    defaultWidth,
+		interval: plainInterval,
+	}
+}
+
+// Start - starts drawing until stopped. Nothing else may be written to the terminal meanwhile,
+// as the bars are redrawn over the previous ones. A nil display draws nothing.
+func (d *Display) Start() {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stop != nil {
+		return
+	}
+
+	d.stop = make(chan struct{})
+	d.done = make(chan struct{})
+	go d.run(d.stop, d.done)
+}
+
+// Stop - stops drawing, leaving the final progress drawn
+func (d *Display) Stop() {
+	if d == nil {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.stop == nil {
+		return
+	}
+
+	close(d.stop)
+	<-d.done
+	d.stop = nil
+
+	d.draw(time.Now())
+	d.lines = 0
+}
+
+func (d *Display) run(stop, done chan struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(d.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case now := <-ticker.C:
+			d.mu.Lock()
+			d.draw(now)
+			d.mu.Unlock()
+		}
+	}
+}
+
+// draw - draws the bars over the previous ones on a terminal, or writes a line
+func (d *Display) draw(now time.Time) {
+	s := d.progress.Snapshot()
+	if !d.tty {
+		if line := plainLine(s, now); line != "" {
+			fmt.Fprintln(d.w, line)
+		}
+
+		return
+	}
+
+	lines := d.barLines(s, now)
+	var b strings.Builder
+	if d.lines > 0 {
+		// moves to the first line drawn and clears the previous bars
+		fmt.Fprintf(&b, "\x1b[%dA\r\x1b[J", d.lines)
+	}
+
+	for _, line := range lines {
+		b.WriteString(truncate(line, d.width-1))
+		b.WriteString("\n")
+	}
+
+	io.WriteString(d.w, b.String())
+	d.lines = len(lines)
+}
+
+// barLines - returns the checksum bar before the sync, or the total bar and the bars of the running tables
+func (d *Display) barLines(s dbsync.ProgressSnapshot, now time.Time) []string {
+	if s.SyncStart.IsZero() {
+		if s.ChecksumTotal == 0 {
+			return nil
+		}
+
+		return []string{fmt.Sprintf(
+			"Checksums %s %d/%d tables %s",
+			bar(int64(s.ChecksumDone), int64(s.ChecksumTotal)),
+			s.ChecksumDone,
+			s.ChecksumTotal,
+			s.ChecksumLast,
+		)}
+	}
+
+	rows, doneRows, bytes := s.Totals()
+	total := "Total     " + bar(doneRows, rows)
+	if eta, ok := s.ETA(now); ok {
+		total += " ETA " + eta.Round(time.Second).String()
+	}
+
+	if rows >= 0 {
+		total += fmt.Sprintf(" %d/%d rows", doneRows, rows)
+	}
+
+	total += fmt.Sprintf(" %s %d/%d tables", rates(s, bytes, now), finished(s), len(s.Tables))
+
+	lines := []string{total}
+	for _, t := range s.Tables {
+		if t.Started.IsZero() || !t.Finished.IsZero() {
+			continue
+		}
+
+		if len(lines) > maxTableLines {
+			lines = append(lines, "  ...")
+			break
+		}
+
+		lines = append(lines, fmt.Sprintf(
+			"  %s %s %s %s %d statements",
+			t.Table,
+			bar(t.DumpedRows, t.Rows),
+			FormatBytes(t.Bytes),
+			tableRows(t),
+			t.Statements,
+		))
+	}
+
+	return lines
+}
+
+// plainLine - returns the checksum progress before the sync, or the totals and running tables
+func plainLine(s dbsync.ProgressSnapshot, now time.Time) string {
+	if s.SyncStart.IsZero() {
+		if s.ChecksumTotal == 0 {
+			return ""
+		}
+
+		return fmt.Sprintf("Checksums: %d/%d tables", s.ChecksumDone, s.ChecksumTotal)
+	}
+
+	rows, doneRows, bytes := s.Totals()
+	line := fmt.Sprintf("Sync: %d/%d tables, %s", finished(s), len(s.Tables), rates(s, bytes, now))
+	if rows >= 0 {
+		line += fmt.Sprintf(", %d/%d rows", doneRows, rows)
+	}
+
+	if eta, ok := s.ETA(now); ok {
+		line += ", ETA " + eta.Round(time.Second).String()
+	}
+
+	var running []string
+	for _, t := range s.Tables {
+		if !t.Started.IsZero() && t.Finished.IsZero() {
+			running = append(running, fmt.Sprintf("%s %s %d statements", t.Table, tableRows(t), t.Statements))
+		}
+	}
+
+	if len(running) > 0 {
+		line += "; " + strings.Join(running, ", ")
+	}
+
+	return line
+}
+
+func finished(s dbsync.ProgressSnapshot) int {
+	var n int
+	for _, t := range s.Tables {
+		if !t.Finished.IsZero() {
+			n++
+		}
+	}
+
+	return n
+}
+
+// rates - returns the bytes synced and the throughput
+func rates(s dbsync.ProgressSnapshot, bytes int64, now time.Time) string {
+	elapsed := now.Sub(s.SyncStart).Seconds()
+	if elapsed <= 0 {
+		return FormatBytes(bytes)
+	}
+
+	return fmt.Sprintf("%s %s/s", FormatBytes(bytes), FormatBytes(int64(float64(bytes)/elapsed)))
+}
+
+func tableRows(t dbsync.TableProgress) string {
+	if t.Rows < 0 {
+		return fmt.Sprintf("%d rows", t.DumpedRows)
+	}
+
+	return fmt.Sprintf("%d/%d rows", t.DumpedRows, t.Rows)
+}
+
+// bar - returns a bar filled by done out of total, empty when total is unknown
+func bar(done, total int64) string {
+	filled := 0
+	if total > 0 {
+		filled = int(done * barWidth / total)
+	}
+
+	if filled > barWidth {
+		filled = barWidth
+	}
+
+	return "[" + strings.Repeat("#", filled) + strings.Repeat("-", barWidth-filled) + "]"
+}
+
+func truncate(s string, width int) string {
+	if width <= 0 || len(s) <= width {
+		return s
+	}
+
+	return s[:width]
+}
+
+// FormatBytes - formats a size with a binary unit, e.g. 1.5 MiB
+func FormatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+
+	return fmt.Sprintf("%.1f %ciB", float64(n)/float64(div), "KMGTPE"[exp])
+}
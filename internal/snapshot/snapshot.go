@@ -0,0 +1,445 @@
+package snapshot
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/vcraescu/dbsync/internal/compress"
+	"github.com/vcraescu/dbsync/internal/database"
+	"github.com/vcraescu/dbsync/internal/database/mysql"
+)
+
+const (
+	// Format - format name written in every manifest
+	Format = "dbsync-snapshot"
+	// Version - version of the snapshot format
+	Version = 1
+
+	manifestFile = "manifest.json"
+	tablesDir    = "tables"
+
+	// maxDumpAttempts - number of times a table changing while being dumped is dumped
+	maxDumpAttempts = 3
+)
+
+// Table - table entry of the manifest
+type Table struct {
+	Checksum string `json:"checksum"`
+	// Schema - file holding the create statement, relative to the snapshot dir
+	Schema string `json:"schema"`
+	// Data - file holding the insert statements, relative to the snapshot dir
+	Data   string `json:"data"`
+	Filter string `json:"filter,omitempty"`
+}
+
+// Object - view, trigger, stored routine or event entry of the manifest
+type Object struct {
+	Type       string `json:"type"`
+	Name       string `json:"name"`
+	Table      string `json:"table,omitempty"`
+	Definition string `json:"definition"`
+}
+
+// Manifest - describes the content of a snapshot
+type Manifest struct {
+	Format       string              `json:"format"`
+	Version      int                 `json:"version"`
+	CreatedAt    time.Time           `json:"created_at"`
+	Driver       string              `json:"driver,omitempty"`
+	Host         string              `json:"host"`
+	Schema       string              `json:"schema"`
+	Tables       map[string]Table    `json:"tables"`
+	Dependencies map[string][]string `json:"dependencies"`
+	Objects      []Object            `json:"objects"`
+}
+
+// Database - database a snapshot is taken of
+type Database interface {
+	database.Source
+	Host() string
+	Schema() string
+}
+
+// TableDumper - streams the create statement and the insert statements of a table separately
+type TableDumper interface {
+	DumpTableSchemaTo(ctx context.Context, w io.Writer, table string) error
+	DumpTableDataTo(ctx context.Context, w io.Writer, table string) error
+}
+
+// Snapshot - database schema and data stored in a directory, usable as sync source
+type Snapshot struct {
+	dir      string
+	manifest Manifest
+}
+
+// Create - writes a snapshot of the connection database to dir, table files compressed with the given algorithm.
+// Stops at the first error, or once ctx is done, leaving the written files in place.
+func Create(ctx context.Context, dir string, conn Database, dumper TableDumper, a compress.Algorithm) (*Snapshot, error) {
+	if err := conn.Open(ctx); err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, tablesDir), 0755); err != nil {
+		return nil, err
+	}
+
+	names, err := conn.TableNames(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %s", err)
+	}
+
+	deps, err := conn.TableDependencies(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %s", err)
+	}
+
+	objects, err := conn.Objects(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %s", err)
+	}
+
+	s := &Snapshot{
+		dir: dir,
+		manifest: Manifest{
+			Format:       Format,
+			Version:      Version,
+			CreatedAt:    time.Now().UTC(),
+			Driver:       conn.Driver(),
+			Host:         conn.Host(),
+			Schema:       conn.Schema(),
+			Tables:       make(map[string]Table, len(names)),
+			Dependencies: deps,
+		},
+	}
+
+	for _, o := range objects {
+		s.manifest.Objects = append(s.manifest.Objects, Object{
+			Type:       string(o.Type),
+			Name:       o.Name,
+			Table:      o.Table,
+			Definition: o.Definition,
+		})
+	}
+
+	// the tables are checksummed before and after being dumped, the tables changed in between being
+	// dumped again, so the recorded checksums are the ones of the dumped rows
+	pending := names
+	for attempt := 1; len(pending) > 0; attempt++ {
+		if attempt > maxDumpAttempts {
+			return nil, fmt.Errorf(
+				"snapshot: tables %s changed while being dumped %d times",
+				strings.Join(pending, ", "),
+				maxDumpAttempts,
+			)
+		}
+
+		if pending, err = s.dumpTables(ctx, conn, dumper, a, pending); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := s.writeFile(manifestFile, compress.None, s.writeManifest); err != nil {
+		return nil, fmt.Errorf("snapshot manifest: %s", err)
+	}
+
+	return s, nil
+}
+
+// dumpTables - dumps the given tables and returns the ones whose checksum changed while being dumped
+func (s *Snapshot) dumpTables(ctx context.Context, conn Database, dumper TableDumper, a compress.Algorithm, names []string) ([]string, error) {
+	before, err := conn.ChecksumTables(ctx, names...)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %s", err)
+	}
+
+	for _, name := range names {
+		if err := ctx.Err(); err != nil {
+			return nil, fmt.Errorf("snapshot: %s", err)
+		}
+
+		table := Table{
+			Checksum: before[name],
+			Schema:   tableFile(name, "schema", a),
+			Data:     tableFile(name, "data", a),
+		}
+		table.Filter, _ = conn.Filter(name)
+
+		if err := s.writeFile(table.Schema, a, func(w io.Writer) error { return dumper.DumpTableSchemaTo(ctx, w, name) }); err != nil {
+			return nil, fmt.Errorf("snapshot %s schema: %s", name, err)
+		}
+
+		if err := s.writeFile(table.Data, a, func(w io.Writer) error { return dumper.DumpTableDataTo(ctx, w, name) }); err != nil {
+			return nil, fmt.Errorf("snapshot %s data: %s", name, err)
+		}
+
+		s.manifest.Tables[name] = table
+	}
+
+	after, err := conn.ChecksumTables(ctx, names...)
+	if err != nil {
+		return nil, fmt.Errorf("snapshot: %s", err)
+	}
+
+	var changed []string
+	for _, name := range names {
+		if after[name] != before[name] {
+			changed = append(changed, name)
+		}
+	}
+
+	return changed, nil
+}
+
+// tableFile - returns the file of a table holding the given part, relative to the snapshot dir. The
+// table name is escaped, as names may hold path separators.
+func tableFile(table, part string, a compress.Algorithm) string {
+	return filepath.Join(tablesDir, url.PathEscape(table)+"."+part+".sql"+a.Extension())
+}
+
+func (s *Snapshot) writeFile(name string, a compress.Algorithm, write func(w io.Writer) error) error {
+	f, err := os.Create(filepath.Join(s.dir, name))
+	if err != nil {
+		return err
+	}
+
+	zw, err := compress.NewWriter(f, a)
+	if err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := write(zw); err != nil {
+		f.Close()
+		return err
+	}
+
+	if err := zw.Close(); err != nil {
+		f.Close()
+		return err
+	}
+
+	return f.Close()
+}
+
+func (s *Snapshot) writeManifest(w io.Writer) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(s.manifest)
+}
+
+// IsSnapshot - returns true when dir holds a snapshot manifest
+func IsSnapshot(dir string) bool {
+	info, err := os.Stat(filepath.Join(dir, manifestFile))
+
+	return err == nil && !info.IsDir()
+}
+
+// Open - opens the snapshot stored in dir
+func Open(dir string) (*Snapshot, error) {
+	f, err := os.Open(filepath.Join(dir, manifestFile))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	s := &Snapshot{
+		dir: dir,
+	}
+	if err := json.NewDecoder(f).Decode(&s.manifest); err != nil {
+		return nil, fmt.Errorf("snapshot manifest: %s", err)
+	}
+
+	if s.manifest.Format != Format {
+		return nil, fmt.Errorf("%s: not a %s", dir, Format)
+	}
+
+	if s.manifest.Version != Version {
+		return nil, fmt.Errorf("%s: unsupported snapshot version %d", dir, s.manifest.Version)
+	}
+
+	for name, t := range s.manifest.Tables {
+		for _, file := range []string{t.Schema, t.Data} {
+			if !inDir(file) {
+				return nil, fmt.Errorf("%s: file %s of table %s outside of the snapshot", dir, file, name)
+			}
+		}
+	}
+
+	return s, nil
+}
+
+// inDir - returns true when the relative path does not leave its directory
+func inDir(path string) bool {
+	if filepath.IsAbs(path) || filepath.VolumeName(path) != "" {
+		return false
+	}
+
+	path = filepath.Clean(path)
+
+	return path != ".." && !strings.HasPrefix(path, ".."+string(filepath.Separator))
+}
+
+// Manifest - returns the snapshot manifest
+func (s *Snapshot) Manifest() Manifest {
+	return s.manifest
+}
+
+// Open - nothing to open, snapshots are read on demand
+func (s *Snapshot) Open(_ context.Context) error {
+	return nil
+}
+
+// Driver - returns the driver of the database the snapshot was taken from
+func (s *Snapshot) Driver() string {
+	if s.manifest.Driver == "" {
+		return database.DefaultDriver
+	}
+
+	return s.manifest.Driver
+}
+
+// TableNames - returns the table names
+func (s *Snapshot) TableNames(_ context.Context) ([]string, error) {
+	names := make([]string, 0, len(s.manifest.Tables))
+	for name := range s.manifest.Tables {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// ChecksumTables - returns the checksums of the given tables recorded in the manifest
+func (s *Snapshot) ChecksumTables(ctx context.Context, tables ...string) (map[string]string, error) {
+	chks := make(map[string]string, len(tables))
+	for _, name := range tables {
+		table, ok := s.manifest.Tables[name]
+		if !ok {
+			return nil, fmt.Errorf("snapshot: table %s not found", name)
+		}
+
+		chks[name] = table.Checksum
+		database.TableChecksummed(ctx, name)
+	}
+
+	return chks, nil
+}
+
+// Fingerprints - returns the checksums of the given tables, snapshots never changing
+func (s *Snapshot) Fingerprints(ctx context.Context, tables ...string) (map[string]string, error) {
+	return s.ChecksumTables(ctx, tables...)
+}
+
+// TableDependencies - returns the tables referenced through foreign keys by every table
+func (s *Snapshot) TableDependencies(_ context.Context) (map[string][]string, error) {
+	return s.manifest.Dependencies, nil
+}
+
+// Objects - returns views, triggers, stored routines and events
+func (s *Snapshot) Objects(_ context.Context) ([]database.Object, error) {
+	objects := make([]database.Object, 0, len(s.manifest.Objects))
+	for _, o := range s.manifest.Objects {
+		objects = append(objects, database.Object{
+			Type:       database.ObjectType(o.Type),
+			Name:       o.Name,
+			Table:      o.Table,
+			Definition: o.Definition,
+		})
+	}
+
+	return objects, nil
+}
+
+// Filter - returns the where condition the table was filtered with
+func (s *Snapshot) Filter(table string) (string, bool) {
+	t := s.manifest.Tables[table]
+
+	return t.Filter, t.Filter != ""
+}
+
+// DumpTableTo - streams the schema and data of a table to w
+func (s *Snapshot) DumpTableTo(ctx context.Context, w io.Writer, table string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t, ok := s.manifest.Tables[table]
+	if !ok {
+		return fmt.Errorf("snapshot: table %s not found", table)
+	}
+
+	if err := s.copyFile(w, t.Schema); err != nil {
+		return err
+	}
+
+	return s.copyFile(w, t.Data)
+}
+
+// DumpTableSchemaTo - streams the schema of a table to w
+func (s *Snapshot) DumpTableSchemaTo(ctx context.Context, w io.Writer, table string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t, ok := s.manifest.Tables[table]
+	if !ok {
+		return fmt.Errorf("snapshot: table %s not found", table)
+	}
+
+	return s.copyFile(w, t.Schema)
+}
+
+// RefreshTableTo - streams the sql deleting the filtered rows of a table and inserting them again,
+// replacing the rows whose key is still used by a slave row outside of the filter
+func (s *Snapshot) RefreshTableTo(ctx context.Context, w io.Writer, table string) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	t, ok := s.manifest.Tables[table]
+	if !ok {
+		return fmt.Errorf("snapshot: table %s not found", table)
+	}
+
+	if t.Filter == "" {
+		return fmt.Errorf("refresh %s: table has no filter", table)
+	}
+
+	if _, err := io.WriteString(w, mysql.GenerateDeleteStatement(table, t.Filter)); err != nil {
+		return err
+	}
+
+	rw := mysql.NewReplaceWriter(w)
+	if err := s.copyFile(rw, t.Data); err != nil {
+		return err
+	}
+
+	return rw.Close()
+}
+
+func (s *Snapshot) copyFile(w io.Writer, name string) error {
+	f, err := os.Open(filepath.Join(s.dir, name))
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	zr, err := compress.NewReader(f)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	_, err = io.Copy(w, zr)
+
+	return err
+}
@@ -0,0 +1,324 @@
+package snapshot
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vcraescu/dbsync/internal/compress"
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+// fakeDatabase - database whose tables change while being dumped, changes times each
+type fakeDatabase struct {
+	tables  []string
+	filters map[string]string
+
+	mu       sync.Mutex
+	changes  map[string]int
+	versions map[string]int
+	// dumps - number of times each table was dumped
+	dumps map[string]int
+}
+
+func newFakeDatabase(changes map[string]int, tables ...string) *fakeDatabase {
+	return &fakeDatabase{
+		tables:   tables,
+		changes:  changes,
+		versions: make(map[string]int),
+		dumps:    make(map[string]int),
+	}
+}
+
+func (db *fakeDatabase) Open(ctx context.Context) error {
+	return nil
+}
+
+func (db *fakeDatabase) Driver() string {
+	return "mysql"
+}
+
+func (db *fakeDatabase) TableNames(ctx context.Context) ([]string, error) {
+	return db.tables, nil
+}
+
+func (db *fakeDatabase) ChecksumTables(ctx context.Context, tables ...string) (map[string]string, error) {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	chks := make(map[string]string, len(tables))
+	for _, table := range tables {
+		chks[table] = fmt.Sprintf("%s-v%d", table, db.versions[table])
+	}
+
+	return chks, nil
+}
+
+func (db *fakeDatabase) TableDependencies(ctx context.Context) (map[string][]string, error) {
+	return map[string][]string{"orders": {"users"}}, nil
+}
+
+func (db *fakeDatabase) Objects(ctx context.Context) ([]database.Object, error) {
+	return []database.Object{
+		{Type: database.ObjectTrigger, Name: "audit", Table: "orders", Definition: "CREATE TRIGGER `audit`"},
+	}, nil
+}
+
+func (db *fakeDatabase) Filter(table string) (string, bool) {
+	filter, ok := db.filters[table]
+
+	return filter, ok
+}
+
+func (db *fakeDatabase) Host() string {
+	return "db.local"
+}
+
+func (db *fakeDatabase) Schema() string {
+	return "shop"
+}
+
+func (db *fakeDatabase) DumpTableSchemaTo(ctx context.Context, w io.Writer, table string) error {
+	_, err := fmt.Fprintf(w, "CREATE TABLE `%s`;\n", table)
+
+	return err
+}
+
+// DumpTableDataTo - dumps the current version of the table, which then changes if it still has to
+func (db *fakeDatabase) DumpTableDataTo(ctx context.Context, w io.Writer, table string) error {
+	db.mu.Lock()
+	defer db.mu.Unlock()
+
+	db.dumps[table]++
+	if _, err := fmt.Fprintf(w, "INSERT INTO `%s` VALUES (%d);\n", table, db.versions[table]); err != nil {
+		return err
+	}
+
+	if db.changes[table] > 0 {
+		db.changes[table]--
+		db.versions[table]++
+	}
+
+	return nil
+}
+
+func TestCreateOpen(t *testing.T) {
+	for _, a := range []compress.Algorithm{compress.None, compress.Gzip} {
+		t.Run(string(a), func(t *testing.T) {
+			dir := t.TempDir()
+			db := newFakeDatabase(nil, "users", "orders", "odd/../name")
+			db.filters = map[string]string{"orders": "total > 0"}
+
+			created, err := Create(context.Background(), dir, db, db, a)
+			if err != nil {
+				t.Fatalf("Create() error = %s", err)
+			}
+
+			s, err := Open(dir)
+			if err != nil {
+				t.Fatalf("Open() error = %s", err)
+			}
+
+			if !reflect.DeepEqual(s.Manifest(), created.Manifest()) {
+				t.Errorf("Open() manifest = %+v, want %+v", s.Manifest(), created.Manifest())
+			}
+
+			m := s.Manifest()
+			if m.Format != Format || m.Version != Version || m.Host != "db.local" || m.Schema != "shop" {
+				t.Errorf("manifest = %+v", m)
+			}
+
+			// the table files are all in the tables dir, whatever the table names
+			files, err := ioutil.ReadDir(filepath.Join(dir, tablesDir))
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if len(files) != 6 {
+				t.Errorf("%d table files, want 6", len(files))
+			}
+
+			names, _ := s.TableNames(context.Background())
+			if want := []string{"odd/../name", "orders", "users"}; !reflect.DeepEqual(names, want) {
+				t.Errorf("TableNames() = %q, want %q", names, want)
+			}
+
+			if filter, ok := s.Filter("orders"); filter != "total > 0" || !ok {
+				t.Errorf("Filter(orders) = %q, %v", filter, ok)
+			}
+
+			var out bytes.Buffer
+			if err := s.DumpTableTo(context.Background(), &out, "odd/../name"); err != nil {
+				t.Fatalf("DumpTableTo() error = %s", err)
+			}
+
+			if want := "CREATE TABLE `odd/../name`;\nINSERT INTO `odd/../name` VALUES (0);\n"; out.String() != want {
+				t.Errorf("DumpTableTo() = %q, want %q", out.String(), want)
+			}
+
+			objects, _ := s.Objects(context.Background())
+			if len(objects) != 1 || objects[0].Type != database.ObjectTrigger || objects[0].Table != "orders" {
+				t.Errorf("Objects() = %+v", objects)
+			}
+		})
+	}
+}
+
+func TestCreateTablesChangedWhileDumped(t *testing.T) {
+	dir := t.TempDir()
+	db := newFakeDatabase(map[string]int{"orders": maxDumpAttempts - 1}, "users", "orders")
+
+	s, err := Create(context.Background(), dir, db, db, compress.None)
+	if err != nil {
+		t.Fatalf("Create() error = %s", err)
+	}
+
+	if want := map[string]int{"users": 1, "orders": maxDumpAttempts}; !reflect.DeepEqual(db.dumps, want) {
+		t.Errorf("dumps = %v, want %v", db.dumps, want)
+	}
+
+	// the recorded checksum is the one of the dumped rows
+	version := maxDumpAttempts - 1
+	if got, want := s.Manifest().Tables["orders"].Checksum, fmt.Sprintf("orders-v%d", version); got != want {
+		t.Errorf("orders checksum = %q, want %q", got, want)
+	}
+
+	var out bytes.Buffer
+	if err := s.DumpTableTo(context.Background(), &out, "orders"); err != nil {
+		t.Fatalf("DumpTableTo() error = %s", err)
+	}
+
+	if want := fmt.Sprintf("VALUES (%d);", version); !strings.Contains(out.String(), want) {
+		t.Errorf("DumpTableTo() = %q, want the rows of version %d", out.String(), version)
+	}
+}
+
+func TestCreateTablesChangingOnEveryDump(t *testing.T) {
+	dir := t.TempDir()
+	db := newFakeDatabase(map[string]int{"orders": maxDumpAttempts, "users": maxDumpAttempts}, "users", "orders", "logs")
+
+	_, err := Create(context.Background(), dir, db, db, compress.None)
+	want := fmt.Sprintf("snapshot: tables users, orders changed while being dumped %d times", maxDumpAttempts)
+	if err == nil || err.Error() != want {
+		t.Errorf("Create() error = %v, want %q", err, want)
+	}
+
+	if IsSnapshot(dir) {
+		t.Errorf("manifest written for an inconsistent snapshot")
+	}
+}
+
+func TestOpenErrors(t *testing.T) {
+	table := func(schema, data string) map[string]Table {
+		return map[string]Table{"users": {Schema: schema, Data: data}}
+	}
+
+	tests := []struct {
+		name     string
+		manifest interface{}
+		want     string
+	}{
+		{"format", Manifest{Format: "other", Version: Version}, "not a dbsync-snapshot"},
+		{"version", Manifest{Format: Format, Version: Version + 1}, fmt.Sprintf("unsupported snapshot version %d", Version+1)},
+		{"invalid json", "{", "snapshot manifest: "},
+		{
+			"file outside the snapshot",
+			Manifest{Format: Format, Version: Version, Tables: table("tables/users.schema.sql", "../users.data.sql")},
+			"file ../users.data.sql of table users outside of the snapshot",
+		},
+		{
+			"absolute file",
+			Manifest{Format: Format, Version: Version, Tables: table("/etc/passwd", "tables/users.data.sql")},
+			"file /etc/passwd of table users outside of the snapshot",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			data, err := json.Marshal(tt.manifest)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			if s, ok := tt.manifest.(string); ok {
+				data = []byte(s)
+			}
+
+			if err := ioutil.WriteFile(filepath.Join(dir, manifestFile), data, 0644); err != nil {
+				t.Fatal(err)
+			}
+
+			if _, err := Open(dir); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Open() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+
+	if _, err := Open(t.TempDir()); !os.IsNotExist(err) {
+		t.Errorf("Open() of a dir without manifest error = %v, want not exist", err)
+	}
+}
+
+func TestRefreshTableTo(t *testing.T) {
+	dir := t.TempDir()
+	db := newFakeDatabase(nil, "users", "orders")
+	db.filters = map[string]string{"orders": "total > 0"}
+
+	s, err := Create(context.Background(), dir, db, db, compress.None)
+	if err != nil {
+		t.Fatalf("Create() error = %s", err)
+	}
+
+	var out bytes.Buffer
+	err = s.RefreshTableTo(context.Background(), &out, "users")
+	if want := "refresh users: table has no filter"; err == nil || err.Error() != want {
+		t.Errorf("RefreshTableTo(users) error = %v, want %q", err, want)
+	}
+
+	if err := s.RefreshTableTo(context.Background(), &out, "missing"); err == nil {
+		t.Errorf("RefreshTableTo(missing) succeeded")
+	}
+
+	out.Reset()
+	if err := s.RefreshTableTo(context.Background(), &out, "orders"); err != nil {
+		t.Fatalf("RefreshTableTo(orders) error = %s", err)
+	}
+
+	if !strings.Contains(out.String(), "delete from `orders` where (total > 0);\nset foreign_key_checks = @dbsync_foreign_key_checks;\n") ||
+		!strings.Contains(out.String(), "REPLACE INTO `orders` VALUES (0);") {
+		t.Errorf("RefreshTableTo(orders) = %q", out.String())
+	}
+}
+
+func TestTableFile(t *testing.T) {
+	names := []string{"users", "odd/name", `back\slash`, "..", "a%2Fb"}
+	files := make(map[string]bool)
+	for _, name := range names {
+		file := tableFile(name, "data", compress.Gzip)
+		if filepath.Dir(file) != tablesDir {
+			t.Errorf("tableFile(%q) = %q, outside of %s", name, file, tablesDir)
+		}
+
+		files[file] = true
+	}
+
+	if len(files) != len(names) {
+		var got []string
+		for file := range files {
+			got = append(got, file)
+		}
+		sort.Strings(got)
+		t.Errorf("tableFile() of different tables are equal: %q", got)
+	}
+}
@@ -0,0 +1,253 @@
+package state
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mitchellh/go-homedir"
+	"github.com/vcraescu/dbsync/internal/bisync"
+)
+
+// maxRuns - number of runs kept per pair, older ones are dropped
+const maxRuns = 100
+
+// Table - state of a table after the last sync which compared or synced it
+type Table struct {
+	// MasterFingerprint, SlaveFingerprint - cheap values changing whenever the table changes,
+	// taken before comparing on master and after syncing on slave
+	MasterFingerprint string `json:"master_fingerprint"`
+	SlaveFingerprint  string `json:"slave_fingerprint"`
+	// MasterChecksum, SlaveChecksum - content checksums, when they were computed
+	MasterChecksum string `json:"master_checksum,omitempty"`
+	SlaveChecksum  string `json:"slave_checksum,omitempty"`
+	// Options - hash of the filter, masks and types of the table the sync used
+	Options string `json:"options"`
+	// SyncedAt - last time the table was copied to slave
+	SyncedAt time.Time `json:"synced_at,omitempty"`
+	// CheckedAt - last time the table was known to be in sync
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Run - outcome of a sync
+type Run struct {
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	// Tables - tables created, refreshed or deleted on slave
+	Tables []string `json:"tables,omitempty"`
+	Bytes  int64    `json:"bytes"`
+	Error  string   `json:"error,omitempty"`
+	// User - who ran the sync, as user@host
+	User string `json:"user,omitempty"`
+}
+
+// Succeeded - returns true if the sync did not fail
+func (r Run) Succeeded() bool {
+	return r.Error == ""
+}
+
+// CurrentUser - returns the current user as user@host, for recording who ran a sync
+func CurrentUser() string {
+	name := os.Getenv("USER")
+	if u, err := user.Current(); err == nil {
+		name = u.Username
+	}
+
+	host, err := os.Hostname()
+	if err != nil || host == "" {
+		return name
+	}
+
+	return name + "@" + host
+}
+
+// Pair - what is known of a master and slave pair from their previous syncs
+type Pair struct {
+	Master string `json:"master"`
+	Slave  string `json:"slave"`
+	// Tables - tables in sync after the last sync, by name
+	Tables map[string]Table `json:"tables"`
+	// Runs - previous syncs, oldest first
+	Runs []Run `json:"runs,omitempty"`
+	// Base - checksums of both sides after the last two-way sync
+	Base *bisync.Base `json:"base,omitempty"`
+}
+
+// AddRun - records a run, dropping the oldest ones beyond the kept number
+func (p *Pair) AddRun(r Run) {
+	p.Runs = append(p.Runs, r)
+	if len(p.Runs) > maxRuns {
+		p.Runs = p.Runs[len(p.Runs)-maxRuns:]
+	}
+}
+
+// LastRun - returns the last run, nil if the pair was never synced
+func (p *Pair) LastRun() *Run {
+	if len(p.Runs) == 0 {
+		return nil
+	}
+
+	return &p.Runs[len(p.Runs)-1]
+}
+
+// LastSuccess - returns the last run which did not fail, nil if none
+func (p *Pair) LastSuccess() *Run {
+	for i := len(p.Runs) - 1; i >= 0; i-- {
+		if p.Runs[i].Succeeded() {
+			return &p.Runs[i]
+		}
+	}
+
+	return nil
+}
+
+// LastSynced - returns the start of the last run which synced a table, the zero time if none
+func (p *Pair) LastSynced(table string) time.Time {
+	for i := len(p.Runs) - 1; i >= 0; i-- {
+		for _, t := range p.Runs[i].Tables {
+			if t == table {
+				return p.Runs[i].StartedAt
+			}
+		}
+	}
+
+	return time.Time{}
+}
+
+// Store - pair states stored as json files in a directory
+type Store struct {
+	dir string
+}
+
+// DefaultDir - returns ~/.dbsync/state
+func DefaultDir() (string, error) {
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(home, ".dbsync", "state"), nil
+}
+
+// NewStore - store constructor, the directory is created on first save
+func NewStore(dir string) *Store {
+	return &Store{
+		dir: dir,
+	}
+}
+
+var unsafeChars = regexp.MustCompile(`[^A-Za-z0-9._-]+`)
+
+// file - returns the file of a pair, named after the servers and a hash telling apart names
+// differing only by unsafe characters
+func (s *Store) file(master, slave string) string {
+	sum := sha1.Sum([]byte(master + "\x00" + slave))
+	name := fmt.Sprintf(
+		"%s_%s_%s.json",
+		unsafeChars.ReplaceAllString(master, "-"),
+		unsafeChars.ReplaceAllString(slave, "-"),
+		hex.EncodeToString(sum[:4]),
+	)
+
+	return filepath.Join(s.dir, name)
+}
+
+// Load - returns the state of a pair, empty when the pair was never synced
+func (s *Store) Load(master, slave string) (*Pair, error) {
+	p, err := readPair(s.file(master, slave))
+	if os.IsNotExist(err) {
+		return &Pair{Master: master, Slave: slave, Tables: make(map[string]Table)}, nil
+	}
+
+	return p, err
+}
+
+// Save - writes the state of a pair, replacing the previous one atomically
+func (s *Store) Save(p *Pair) error {
+	if err := os.MkdirAll(s.dir, 0700); err != nil {
+		return fmt.Errorf("state: %s", err)
+	}
+
+	data, err := json.MarshalIndent(p, "", "  ")
+	if err != nil {
+		return fmt.Errorf("state: %s", err)
+	}
+
+	f, err := ioutil.TempFile(s.dir, ".state-*")
+	if err != nil {
+		return fmt.Errorf("state: %s", err)
+	}
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		f.Close()
+		os.Remove(f.Name())
+		return fmt.Errorf("state: %s", err)
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("state: %s", err)
+	}
+
+	if err := os.Rename(f.Name(), s.file(p.Master, p.Slave)); err != nil {
+		os.Remove(f.Name())
+		return fmt.Errorf("state: %s", err)
+	}
+
+	return nil
+}
+
+// Pairs - returns the states of every pair, ordered by master and slave
+func (s *Store) Pairs() ([]*Pair, error) {
+	files, err := filepath.Glob(filepath.Join(s.dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make([]*Pair, 0, len(files))
+	for _, file := range files {
+		p, err := readPair(file)
+		if err != nil {
+			return nil, err
+		}
+
+		pairs = append(pairs, p)
+	}
+
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Master != pairs[j].Master {
+			return pairs[i].Master < pairs[j].Master
+		}
+
+		return pairs[i].Slave < pairs[j].Slave
+	})
+
+	return pairs, nil
+}
+
+func readPair(file string) (*Pair, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var p Pair
+	if err := json.Unmarshal(data, &p); err != nil {
+		return nil, fmt.Errorf("state %s: %s", strings.TrimSuffix(filepath.Base(file), ".json"), err)
+	}
+
+	if p.Tables == nil {
+		p.Tables = make(map[string]Table)
+	}
+
+	return &p, nil
+}
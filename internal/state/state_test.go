@@ -0,0 +1,167 @@
+package state
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestStoreLoadNeverSynced(t *testing.T) {
+	s := NewStore(filepath.Join(t.TempDir(), "state"))
+
+	p, err := s.Load("prod", "local")
+	if err != nil {
+		t.Fatalf("Load() error = %s", err)
+	}
+
+	if p.Master != "prod" || p.Slave != "local" || p.Tables == nil || len(p.Runs) != 0 {
+		t.Errorf("Load() = %+v, want an empty prod to local pair", p)
+	}
+}
+
+func TestStoreSaveLoad(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "state")
+	s := NewStore(dir)
+	at := time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	pairs := []*Pair{
+		{
+			Master: "prod",
+			Slave:  "local",
+			Tables: map[string]Table{
+				"users": {MasterFingerprint: "m1", SlaveFingerprint: "s1", MasterChecksum: "c1", Options: "o", SyncedAt: at, CheckedAt: at},
+			},
+			Runs: []Run{{StartedAt: at, DurationMs: 10, Tables: []string{"users"}, Bytes: 100, User: "u@h"}},
+		},
+		// differs from the first pair by unsafe characters only
+		{Master: "prod", Slave: "local/", Tables: map[string]Table{}},
+	}
+
+	for _, p := range pairs {
+		if err := s.Save(p); err != nil {
+			t.Fatalf("Save() error = %s", err)
+		}
+	}
+
+	// saving again replaces the state
+	pairs[0].AddRun(Run{StartedAt: at.Add(time.Hour), Error: "failed"})
+	if err := s.Save(pairs[0]); err != nil {
+		t.Fatalf("Save() error = %s", err)
+	}
+
+	for _, want := range pairs {
+		got, err := s.Load(want.Master, want.Slave)
+		if err != nil {
+			t.Fatalf("Load() error = %s", err)
+		}
+
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Load(%q, %q) = %+v, want %+v", want.Master, want.Slave, got, want)
+		}
+	}
+
+	// the temporary files are renamed over the state files
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var names []string
+	for _, f := range files {
+		names = append(names, f.Name())
+	}
+
+	if len(names) != 2 || filepath.Ext(names[0]) != ".json" || filepath.Ext(names[1]) != ".json" {
+		t.Errorf("state files = %q, want 2 json files", names)
+	}
+
+	all, err := s.Pairs()
+	if err != nil {
+		t.Fatalf("Pairs() error = %s", err)
+	}
+
+	if len(all) != 2 || all[0].Slave != "local" || all[1].Slave != "local/" {
+		t.Errorf("Pairs() = %+v, want the pairs ordered by slave", all)
+	}
+}
+
+func TestStoreLoadInvalid(t *testing.T) {
+	s := NewStore(t.TempDir())
+	if err := ioutil.WriteFile(s.file("prod", "local"), []byte("{"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := s.Load("prod", "local"); err == nil {
+		t.Errorf("Load() of an invalid state succeeded")
+	}
+}
+
+func TestPairAddRun(t *testing.T) {
+	start := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name  string
+		runs  int
+		want  int
+		first time.Time
+	}{
+		{"below the cap", 3, 3, start},
+		{"at the cap", maxRuns, maxRuns, start},
+		{"beyond the cap", maxRuns + 5, maxRuns, start.Add(5 * time.Minute)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var p Pair
+			for i := 0; i < tt.runs; i++ {
+				p.AddRun(Run{StartedAt: start.Add(time.Duration(i) * time.Minute)})
+			}
+
+			if len(p.Runs) != tt.want {
+				t.Fatalf("%d runs kept, want %d", len(p.Runs), tt.want)
+			}
+
+			if !p.Runs[0].StartedAt.Equal(tt.first) {
+				t.Errorf("oldest run started at %s, want %s", p.Runs[0].StartedAt, tt.first)
+			}
+
+			if last := p.LastRun(); !last.StartedAt.Equal(start.Add(time.Duration(tt.runs-1) * time.Minute)) {
+				t.Errorf("LastRun() started at %s, want the last added run", last.StartedAt)
+			}
+		})
+	}
+}
+
+func TestPairLastSynced(t *testing.T) {
+	at := func(hour int) time.Time {
+		return time.Date(2020, 1, 1, hour, 0, 0, 0, time.UTC)
+	}
+
+	p := &Pair{
+		Runs: []Run{
+			{StartedAt: at(1), Tables: []string{"users", "orders"}},
+			{StartedAt: at(2), Tables: []string{"orders"}},
+			{StartedAt: at(3), Error: "failed"},
+		},
+	}
+
+	tests := []struct {
+		table string
+		want  time.Time
+	}{
+		{"users", at(1)},
+		{"orders", at(2)},
+		{"logs", time.Time{}},
+	}
+
+	for _, tt := range tests {
+		if got := p.LastSynced(tt.table); !got.Equal(tt.want) {
+			t.Errorf("LastSynced(%q) = %s, want %s", tt.table, got, tt.want)
+		}
+	}
+
+	if got := p.LastSuccess(); got == nil || !got.StartedAt.Equal(at(2)) {
+		t.Errorf("LastSuccess() = %+v, want the run of %s", got, at(2))
+	}
+}
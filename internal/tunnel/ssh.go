@@ -1,12 +1,14 @@
 package tunnel
 
 import (
+	"context"
 	"fmt"
 	"net"
 	"io"
 	"errors"
-	"log"
+	"sync"
 
+	"github.com/vcraescu/dbsync/internal/logger"
 	"golang.org/x/crypto/ssh"
 )
 
@@ -35,16 +37,26 @@ func (tunnel *SSHTunnel) LocalHost() string {
 	return tunnel.local.Host
 }
 
-func (tunnel *SSHTunnel) Start() error {
+// Start - forwards the local connections to remote until ctx is done
+func (tunnel *SSHTunnel) Start(ctx context.Context) error {
 	listener, err := net.Listen("tcp", tunnel.local.String())
 	if err != nil {
 		return err
 	}
 	defer listener.Close()
 
+	go func() {
+		<-ctx.Done()
+		listener.Close()
+	}()
+
 	for {
 		conn, err := listener.Accept()
 		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+
 			return err
 		}
 
@@ -52,7 +64,7 @@ func (tunnel *SSHTunnel) Start() error {
 			err := tunnel.forward(conn)
 			if err != nil {
 				conn.Close()
-				log.Panic(fmt.Sprintf("SSH Tunnel: %s", err))
+				logger.Error("SSH tunnel forward failed", "server", tunnel.server.String(), "error", err)
 			}
 		}()
 	}
@@ -66,33 +78,46 @@ func (tunnel *SSHTunnel) forward(localConn net.Conn) error {
 
 	remoteConn, err := serverConn.Dial("tcp", tunnel.remote.String())
 	if err != nil {
+		serverConn.Close()
 		return errors.New(fmt.Sprintf("Remote dial error: %s", err))
 	}
 
-	copyConn := func(writer, reader net.Conn) error {
-		_, err := io.Copy(writer, reader)
-		if err != nil {
-			return errors.New(fmt.Sprintf("io.Copy error: %s", err))
-		}
+	go pipe(localConn, remoteConn, serverConn)
 
-		return nil
+	return nil
+}
+
+// pipe - copies between the local and remote connections until either side is done, then closes
+// both of them, unblocking the other copy, and the given closers
+func pipe(localConn, remoteConn net.Conn, closers ...io.Closer) {
+	var once sync.Once
+	closeAll := func() {
+		once.Do(func() {
+			localConn.Close()
+			remoteConn.Close()
+			for _, c := range closers {
+				c.Close()
+			}
+		})
 	}
 
-	go func() {
-		err := copyConn(localConn, remoteConn)
-		if err != nil {
-			log.Println(fmt.Sprintf("SSH Tunnel: %s", err))
+	copyConn := func(writer, reader net.Conn) {
+		defer closeAll()
+
+		// the copy of the other direction ends with the connections closed
+		if _, err := io.Copy(writer, reader); err != nil && !errors.Is(err, net.ErrClosed) {
+			logger.Warn("SSH tunnel copy failed", "error", err)
 		}
-	}()
+	}
 
+	done := make(chan struct{})
 	go func() {
-		err := copyConn(remoteConn, localConn)
-		if err != nil {
-			log.Println(fmt.Sprintf("SSH Tunnel: %s", err))
-		}
+		copyConn(localConn, remoteConn)
+		close(done)
 	}()
 
-	return nil
+	copyConn(remoteConn, localConn)
+	<-done
 }
 
 func getFreePort() (int, error) {
@@ -136,15 +161,46 @@ func CreateSSHTunnel(localEndpoint, serverEndpoint, remoteEndpoint Endpoint, aut
 	}, nil
 }
 
-func StartSSHTunnel(localEndpoint, serverEndpoint, remoteEndpoint Endpoint, authMethod *ssh.AuthMethod) (*SSHTunnel, error) {
+// StartSSHTunnel - creates and starts a tunnel, which is closed once ctx is done
+func StartSSHTunnel(ctx context.Context, localEndpoint, serverEndpoint, remoteEndpoint Endpoint, authMethod *ssh.AuthMethod) (*SSHTunnel, error) {
 	tunn, err := CreateSSHTunnel(localEndpoint, serverEndpoint, remoteEndpoint, authMethod)
 	if err != nil {
 		return nil, err
 	}
 
 	go func() {
-		log.Panicf("SSH Tunnel: %s", tunn.Start())
+		if err := tunn.Start(ctx); err != nil && ctx.Err() == nil {
+			logger.Fatal("SSH tunnel failed", "server", tunn.server.String(), "error", err)
+		}
 	}()
 
 	return tunn, nil
 }
+
+// Check - connects to remote through the ssh server, returning an error when either is unreachable
+func (tunnel *SSHTunnel) Check(ctx context.Context) error {
+	done := make(chan error, 1)
+	go func() {
+		serverConn, err := ssh.Dial("tcp", tunnel.server.String(), tunnel.cfg)
+		if err != nil {
+			done <- fmt.Errorf("Server dial error: %s", err)
+			return
+		}
+		defer serverConn.Close()
+
+		remoteConn, err := serverConn.Dial("tcp", tunnel.remote.String())
+		if err != nil {
+			done <- fmt.Errorf("Remote dial error: %s", err)
+			return
+		}
+
+		done <- remoteConn.Close()
+	}()
+
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case err := <-done:
+		return err
+	}
+}
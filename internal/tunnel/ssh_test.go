@@ -0,0 +1,77 @@
+package tunnel
+
+import (
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+type closer struct {
+	closed chan struct{}
+}
+
+func (c *closer) Close() error {
+	close(c.closed)
+	return nil
+}
+
+func TestPipe(t *testing.T) {
+	tests := []struct {
+		name string
+		// closeSide - returns the end closing the tunnel, of the client or of the remote
+		closeSide func(client, remote net.Conn) net.Conn
+	}{
+		{"client closes", func(client, _ net.Conn) net.Conn { return client }},
+		{"remote closes", func(_, remote net.Conn) net.Conn { return remote }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, localConn := net.Pipe()
+			remoteConn, remote := net.Pipe()
+			server := &closer{closed: make(chan struct{})}
+
+			done := make(chan struct{})
+			go func() {
+				pipe(localConn, remoteConn, server)
+				close(done)
+			}()
+
+			go client.Write([]byte("ping"))
+			buf := make([]byte, 4)
+			if _, err := io.ReadFull(remote, buf); err != nil || string(buf) != "ping" {
+				t.Fatalf("remote read %q, %v, want ping", buf, err)
+			}
+
+			go remote.Write([]byte("pong"))
+			if _, err := io.ReadFull(client, buf); err != nil || string(buf) != "pong" {
+				t.Fatalf("client read %q, %v, want pong", buf, err)
+			}
+
+			closing := tt.closeSide(client, remote)
+			other := client
+			if closing == client {
+				other = remote
+			}
+			closing.Close()
+
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatal("pipe still copying after a side closed")
+			}
+
+			select {
+			case <-server.closed:
+			default:
+				t.Error("server connection not closed")
+			}
+
+			// the other side sees its tunnel connection closed
+			if _, err := other.Read(buf); err == nil {
+				t.Error("other side still open")
+			}
+		})
+	}
+}
@@ -1,15 +1,16 @@
 package watcher
 
 import (
+	"context"
 	"time"
-	"log"
 
-	"github.com/vcraescu/dbsync/internal/database/mysql"
+	"github.com/vcraescu/dbsync/internal/database"
+	"github.com/vcraescu/dbsync/internal/logger"
 )
 
 // Watcher - watch for db changes
 type Watcher struct {
-	conn   *mysql.Connection
+	conn   database.Connection
 	poll   time.Duration
 	DiffCh chan Diff
 	ErrCh  chan error
@@ -23,7 +24,7 @@ type Diff struct {
 }
 
 // New - creates new watcher
-func New(conn *mysql.Connection, poll time.Duration) *Watcher {
+func New(conn database.Connection, poll time.Duration) *Watcher {
 	w := &Watcher{
 		conn:   conn,
 		poll:   poll,
@@ -35,11 +36,11 @@ func New(conn *mysql.Connection, poll time.Duration) *Watcher {
 }
 
 func (w *Watcher) Hostname() string {
-	return w.conn.Host
+	return w.conn.Host()
 }
 
 func (w *Watcher) DBName() string {
-	return w.conn.DBName
+	return w.conn.Schema()
 }
 
 func newDiff(o map[string]string, n map[string]string) *Diff {
@@ -76,32 +77,48 @@ func (d *Diff) Empty() bool {
 	return len(d.Updated) == 0 && len(d.Created) == 0 && len(d.Deleted) == 0
 }
 
-// Start - starts watching for changes
-func (w *Watcher) Start() error {
-	err := w.conn.Open()
+// Start - starts watching for changes until ctx is done. The checksums taken when starting are the
+// reference of the first check, so the tables existing already are not reported as created.
+func (w *Watcher) Start(ctx context.Context) error {
+	err := w.conn.Open(ctx)
 	if err != nil {
 		return err
 	}
 	defer w.conn.Close()
 
-	var lastChks map[string]string
+	lastChks, err := database.TableChecksums(ctx, w.conn)
+	seeded := err == nil
+	if err != nil {
+		w.ErrCh <- err
+	}
 
 	for {
-		time.Sleep(w.poll)
-		log.Println("Checking for changes...")
-		chks, err := w.conn.TableChecksums()
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(w.poll):
+		}
+
+		logger.Debug("Checking for changes...")
+		chks, err := database.TableChecksums(ctx, w.conn)
 		if err != nil {
 			w.ErrCh <- err
 			continue
 		}
 
+		// the checksums could not be taken when starting
+		if !seeded {
+			lastChks = chks
+			seeded = true
+			continue
+		}
+
 		diff := newDiff(lastChks, chks)
+		lastChks = chks
 		if diff.Empty() {
 			continue
 		}
 
 		w.DiffCh <- *diff
 	}
-
-	return nil
 }
@@ -0,0 +1,284 @@
+package dbsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/vcraescu/dbsync/internal/bisync"
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+// ConflictPolicy - how tables changed on both sides of a two-way sync are resolved
+type ConflictPolicy string
+
+const (
+	// ConflictFail - nothing is synced when a table conflicts
+	ConflictFail ConflictPolicy = ConflictPolicy(bisync.Fail)
+	// ConflictLeftWins - conflicting tables are copied from left to right
+	ConflictLeftWins ConflictPolicy = ConflictPolicy(bisync.LeftWins)
+	// ConflictRightWins - conflicting tables are copied from right to left
+	ConflictRightWins ConflictPolicy = ConflictPolicy(bisync.RightWins)
+)
+
+// ParseConflictPolicy - returns the conflict policy of the given name, fail when empty
+func ParseConflictPolicy(name string) (ConflictPolicy, error) {
+	policy, err := bisync.ParsePolicy(name)
+
+	return ConflictPolicy(policy), err
+}
+
+// BisyncChange - how a table changed since the last two-way sync
+type BisyncChange string
+
+const (
+	// BisyncUnchanged - same content on both sides, or changed the same way on both
+	BisyncUnchanged BisyncChange = BisyncChange(bisync.Unchanged)
+	// BisyncChangedLeft - created, updated or deleted on the left side only
+	BisyncChangedLeft BisyncChange = BisyncChange(bisync.ChangedLeft)
+	// BisyncChangedRight - created, updated or deleted on the right side only
+	BisyncChangedRight BisyncChange = BisyncChange(bisync.ChangedRight)
+	// BisyncConflict - changed differently on both sides
+	BisyncConflict BisyncChange = BisyncChange(bisync.Conflict)
+)
+
+// Base - content checksums of the tables of both sides after the last two-way sync.
+// A table missing from a side did not exist there.
+type Base struct {
+	SyncedAt time.Time         `json:"synced_at"`
+	Left     map[string]string `json:"left"`
+	Right    map[string]string `json:"right"`
+}
+
+// ReadBase - reads the base from file, nil when the file does not exist
+func ReadBase(file string) (*Base, error) {
+	base, err := bisync.ReadBase(file)
+
+	return (*Base)(base), err
+}
+
+// WriteFile - writes the base to file, creating its directory
+func (b *Base) WriteFile(file string) error {
+	return (*bisync.Base)(b).WriteFile(file)
+}
+
+// BisyncTable - change of a table since the last two-way sync
+type BisyncTable struct {
+	Name   string       `json:"name"`
+	Change BisyncChange `json:"change"`
+	// InLeft, InRight - true when the table currently exists on the side
+	InLeft  bool `json:"in_left"`
+	InRight bool `json:"in_right"`
+}
+
+// BisyncResult - outcome of a two-way sync
+type BisyncResult struct {
+	// Tables - change of every table of either side
+	Tables []BisyncTable
+	// ToRight, ToLeft - tables copied, or deleted, from left to right and from right to left
+	ToRight []string
+	ToLeft  []string
+	// Base - checksums of both sides after the sync, to pass to the next one
+	Base     *Base
+	Duration time.Duration
+}
+
+// Bisync - syncs two servers of the same driver both ways, at table level. Tables created, updated
+// or deleted on one side since base are copied to, or deleted from, the other side; tables changed
+// on both sides are resolved with the policy. Without a base, tables existing on a single side are
+// copied to the other one and tables differing on both sides conflict.
+// Masks are not applied, since masked data would be copied back. On failure the base must not
+// be replaced, the next sync detects the tables already copied as changed the same way on both sides.
+func Bisync(ctx context.Context, left, right Server, base *Base, policy ConflictPolicy, opts Options) (*BisyncResult, error) {
+	start := time.Now()
+	opts.Masks = nil
+
+	if left.Snapshot != "" || right.Snapshot != "" || len(opts.Subset) > 0 {
+		return nil, errors.New("two-way sync does not support snapshots or subsets")
+	}
+
+	leftCfg, err := left.connectionConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	rightCfg, err := right.connectionConfig(opts)
+	if err != nil {
+		return nil, err
+	}
+
+	leftConn, err := createConnection(leftCfg)
+	if err != nil {
+		return nil, err
+	}
+	defer leftConn.Close()
+
+	rightConn, err := createConnection(rightCfg)
+	if err != nil {
+		return nil, err
+	}
+	defer rightConn.Close()
+
+	if leftConn.Driver() != rightConn.Driver() {
+		return nil, fmt.Errorf("two-way sync between %s and %s is not supported", leftConn.Driver(), rightConn.Driver())
+	}
+
+	leftChks, rightChks, err := bisyncChecksums(ctx, leftConn, rightConn, opts.Timeouts.Checksum)
+	if err != nil {
+		return nil, err
+	}
+
+	tables := bisync.Classify((*bisync.Base)(base), leftChks, rightChks)
+	res := &BisyncResult{
+		Tables: make([]BisyncTable, len(tables)),
+	}
+	for i, t := range tables {
+		res.Tables[i] = BisyncTable{
+			Name:    t.Name,
+			Change:  BisyncChange(t.Change),
+			InLeft:  t.InLeft,
+			InRight: t.InRight,
+		}
+	}
+
+	toRight, toLeft, err := bisync.Resolve(tables, bisync.Policy(policy))
+	if err != nil {
+		return res, err
+	}
+
+	copied, deleted := splitTables(toRight, func(t bisync.Table) bool { return t.InLeft })
+	if err := bisyncTables(ctx, leftConn, rightConn, leftCfg, rightCfg, copied, deleted, opts); err != nil {
+		return res, fmt.Errorf("left to right: %s", err)
+	}
+
+	res.ToRight = tableNames(toRight)
+
+	copied, deleted = splitTables(toLeft, func(t bisync.Table) bool { return t.InRight })
+	if err := bisyncTables(ctx, rightConn, leftConn, rightCfg, leftCfg, copied, deleted, opts); err != nil {
+		return res, fmt.Errorf("right to left: %s", err)
+	}
+
+	res.ToLeft = tableNames(toLeft)
+
+	// copied tables have the checksum of their source, deleted ones are gone from both sides
+	for _, t := range toRight {
+		copyChecksum(leftChks, rightChks, t.Name, t.InLeft)
+	}
+
+	for _, t := range toLeft {
+		copyChecksum(rightChks, leftChks, t.Name, t.InRight)
+	}
+
+	res.Base = &Base{
+		SyncedAt: time.Now().UTC(),
+		Left:     leftChks,
+		Right:    rightChks,
+	}
+	res.Duration = time.Since(start)
+
+	return res, nil
+}
+
+// bisyncChecksums - returns the content checksums of every table of both sides
+func bisyncChecksums(
+	ctx context.Context,
+	leftConn, rightConn database.Connection,
+	timeout time.Duration,
+) (map[string]string, map[string]string, error) {
+	ctx, cancel := database.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := leftConn.Open(ctx); err != nil {
+		return nil, nil, fmt.Errorf("left: %s", err)
+	}
+
+	if err := rightConn.Open(ctx); err != nil {
+		return nil, nil, fmt.Errorf("right: %s", err)
+	}
+
+	leftChks, err := database.TableChecksums(ctx, leftConn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("left table checksums: %s", err)
+	}
+
+	rightChks, err := database.TableChecksums(ctx, rightConn)
+	if err != nil {
+		return nil, nil, fmt.Errorf("right table checksums: %s", err)
+	}
+
+	return leftChks, rightChks, nil
+}
+
+// bisyncTables - copies the given tables from source to target and deletes the deleted ones from target
+func bisyncTables(
+	ctx context.Context,
+	sourceConn, targetConn database.Connection,
+	sourceCfg, targetCfg database.ConnectionConfig,
+	copied, deleted []string,
+	opts Options,
+) error {
+	if len(copied) == 0 && len(deleted) == 0 {
+		return nil
+	}
+
+	diffCtx, cancel := database.WithTimeout(ctx, opts.Timeouts.Checksum)
+	defer cancel()
+
+	diff, err := database.GenerateTablesDiff(diffCtx, sourceConn, targetConn, copied, deleted)
+	if err != nil {
+		return err
+	}
+
+	dumper, err := createDumper(ctx, sourceCfg, opts)
+	if err != nil {
+		return err
+	}
+
+	imp, err := createImporter(targetCfg)
+	if err != nil {
+		return err
+	}
+
+	syncer := database.NewSyncer(dumper, imp, opts.Workers)
+	syncer.SetTimeouts(database.Timeouts(opts.Timeouts))
+
+	return syncError(syncer.Sync(ctx, diff))
+}
+
+// splitTables - returns the names of the tables existing on the source side, to copy, and of
+// the other ones, to delete
+func splitTables(tables []bisync.Table, inSource func(bisync.Table) bool) ([]string, []string) {
+	var copied, deleted []string
+	for _, t := range tables {
+		if inSource(t) {
+			copied = append(copied, t.Name)
+			continue
+		}
+
+		deleted = append(deleted, t.Name)
+	}
+
+	return copied, deleted
+}
+
+// copyChecksum - sets the target checksum of a copied table to the source one, removing the
+// table from both when it was deleted
+func copyChecksum(source, target map[string]string, table string, inSource bool) {
+	if !inSource {
+		delete(source, table)
+		delete(target, table)
+		return
+	}
+
+	target[table] = source[table]
+}
+
+func tableNames(tables []bisync.Table) []string {
+	names := make([]string, 0, len(tables))
+	for _, t := range tables {
+		names = append(names, t.Name)
+	}
+
+	return names
+}
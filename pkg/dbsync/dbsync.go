@@ -0,0 +1,191 @@
+// Package dbsync - syncs a slave database with a master database, or a snapshot of one, by recreating
+// the changed tables. It is the library behind the dbsync command.
+package dbsync
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/vcraescu/dbsync/internal/compress"
+	"github.com/vcraescu/dbsync/internal/database"
+	"github.com/vcraescu/dbsync/internal/masking"
+	"github.com/vcraescu/dbsync/internal/snapshot"
+)
+
+// Server - database server synced from or to. SSH tunnels are up to the caller, Host and Port
+// pointing to the local end of the tunnel.
+type Server struct {
+	// Driver - mysql (default), postgres or sqlite
+	Driver   string
+	Username string
+	Password string
+	Host     string
+	Port     int
+	// Schema - database name, or the database file for sqlite
+	Schema   string
+	Timezone string
+	// MaxConnections - maximum number of simultaneous connections to the server
+	MaxConnections int
+	// Compression - gzip or zstd compression of the mysql client traffic
+	Compression string
+	// Snapshot - directory of a snapshot used as master instead of a server
+	Snapshot string
+}
+
+// Mask - masking rule of a column
+type Mask struct {
+	// Type - fixed, fake, hash, null or preserve-format
+	Type string
+	// Value - value used by fixed rules
+	Value string
+	// Generator - generator used by fake rules, e.g. name or email
+	Generator string
+}
+
+// Options - what is compared and synced and how
+type Options struct {
+	// Checksum - change detection strategy: content (default), native or metadata
+	Checksum string
+	// Workers - number of tables dumped and imported in parallel, 4 when not set
+	Workers int
+	// Filters - where conditions by table; only the matching rows are compared and synced
+	Filters map[string]string
+	// Masks - masking rules by table and column, mysql only
+	Masks map[string]map[string]Mask
+	// MaskSeed - seed making the masked values differ between installations
+	MaskSeed string
+	// Subset - where conditions of the tables the referentially intact subset of master is
+	// computed from; slave is replaced with the subset. mysql only.
+	Subset map[string]string
+	// Types - slave column types by table and column, overriding the mapped types when the
+	// master and slave drivers differ
+	Types map[string]map[string]string
+	// CountRows - reports exact row counts, counted by reading every table, instead of the
+	// estimates of the server statistics
+	CountRows bool
+	// Timeouts - maximum durations of the checksum, dump and import phases, unlimited when zero
+	Timeouts Timeouts
+	// State - state of the pair from the previous syncs; tables unchanged on both sides since the
+	// last sync are not compared, and the sync records the run and the tables left in sync.
+	// With a subset only the run is recorded.
+	State *State
+}
+
+// Timeouts - maximum durations of the sync phases; a phase running longer is cancelled and its
+// database clients are killed
+type Timeouts struct {
+	// Checksum - listing and checksumming the master and slave tables
+	Checksum time.Duration
+	// Dump - dumping the synced tables from master
+	Dump time.Duration
+	// Import - importing the dumps into slave
+	Import time.Duration
+}
+
+// Ping - connects to the server, or checks the snapshot, returning an error when unreachable
+func (s Server) Ping(ctx context.Context) error {
+	if s.Snapshot != "" {
+		if !snapshot.IsSnapshot(s.Snapshot) {
+			return errors.New("snapshot not found")
+		}
+
+		return nil
+	}
+
+	cfg, err := s.connectionConfig(Options{})
+	if err != nil {
+		return err
+	}
+
+	conn, err := createConnection(cfg)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Open(ctx)
+}
+
+// connectionConfig - returns the connection config of the server
+func (s Server) connectionConfig(opts Options) (database.ConnectionConfig, error) {
+	strategy, err := database.ParseChecksumStrategy(opts.Checksum)
+	if err != nil {
+		return database.ConnectionConfig{}, err
+	}
+
+	a, err := compress.ParseAlgorithm(s.Compression)
+	if err != nil {
+		return database.ConnectionConfig{}, err
+	}
+
+	return database.ConnectionConfig{
+		Driver:         s.Driver,
+		Username:       s.Username,
+		Password:       s.Password,
+		Host:           s.Host,
+		Port:           s.Port,
+		Schema:         s.Schema,
+		Timezone:       s.Timezone,
+		MaxConnections: s.MaxConnections,
+		Checksum:       strategy,
+		Filters:        opts.Filters,
+		Compression:    a,
+	}, nil
+}
+
+// tableMasks - returns the masking rules of a table. Tables are matched case-insensitively when
+// there is no exact match, as the config file keys are lowercased.
+func (opts Options) tableMasks(table string) map[string]Mask {
+	if masks, ok := opts.Masks[table]; ok {
+		return masks
+	}
+
+	for name, masks := range opts.Masks {
+		if strings.EqualFold(name, table) {
+			return masks
+		}
+	}
+
+	return nil
+}
+
+// tableTypes - returns the column types of a table, matched like the masking rules
+func (opts Options) tableTypes(table string) map[string]string {
+	if types, ok := opts.Types[table]; ok {
+		return types
+	}
+
+	for name, types := range opts.Types {
+		if strings.EqualFold(name, table) {
+			return types
+		}
+	}
+
+	return nil
+}
+
+// masker - returns the masker of the masking rules, nil when nothing is masked
+func (opts Options) masker() (*masking.Masker, error) {
+	rules := make(map[string]map[string]masking.Rule)
+	for table, masks := range opts.Masks {
+		for column, mask := range masks {
+			if rules[table] == nil {
+				rules[table] = make(map[string]masking.Rule)
+			}
+
+			rules[table][column] = masking.Rule{
+				Type:      mask.Type,
+				Value:     mask.Value,
+				Generator: mask.Generator,
+			}
+		}
+	}
+
+	if len(rules) == 0 {
+		return nil, nil
+	}
+
+	return masking.New(opts.MaskSeed, rules)
+}
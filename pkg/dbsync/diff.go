@@ -0,0 +1,412 @@
+package dbsync
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/vcraescu/dbsync/internal/database"
+	"github.com/vcraescu/dbsync/internal/database/mysql"
+	"github.com/vcraescu/dbsync/internal/snapshot"
+)
+
+// Diff - tables and objects differing between master and slave
+type Diff struct {
+	// Create - tables to create, ordered so that referenced tables come first
+	Create []string
+	// Refresh - filtered tables whose matching rows are deleted and inserted again
+	Refresh []string
+	// Delete - tables to delete
+	Delete []string
+	// Updated - created or refreshed tables which already exist on slave
+	Updated []string
+	// Unchanged - tables with the same content on master and slave
+	Unchanged []string
+	// Unknown - updated tables whose content can't be compared, master and slave being of
+	// different engines; they are recreated on every sync
+	Unknown []string
+	// Skipped - unchanged tables which were not compared, the state telling they did not change
+	// on either side since the last sync
+	Skipped []string
+	// MasterChecksums, SlaveChecksums - checksums of the tables existing on both master and slave
+	MasterChecksums map[string]string
+	SlaveChecksums  map[string]string
+	// CreateObjects - views, triggers, routines and events to create, e.g. "view active_users"
+	CreateObjects []string
+	// DropObjects - views, triggers, routines and events to drop
+	DropObjects []string
+
+	master    Server
+	slave     Server
+	opts      Options
+	masterCfg database.ConnectionConfig
+	slaveCfg  database.ConnectionConfig
+	source    database.Source
+	slaveConn database.Connection
+	diff      *database.Diff
+
+	masterFingerprints map[string]string
+	slaveFingerprints  map[string]string
+}
+
+// Result - outcome of a sync
+type Result struct {
+	// Diff - differences between master and slave, all of them synced unless the sync failed
+	Diff *Diff
+	// Tables - outcome of every synced table, in completion order
+	Tables   []TableResult
+	Duration time.Duration
+}
+
+// TableResult - outcome of the sync of a single table: the bytes of sql streamed from master to
+// slave, the duration and the error, if any
+type TableResult struct {
+	Table string
+	// Refresh - true when the matching rows of a filtered table were replaced
+	Refresh bool
+	// Deleted - true when the table was dropped from slave
+	Deleted bool
+	// Bytes - size of the sql streamed from master to slave
+	Bytes    int64
+	Duration time.Duration
+	Err      error
+}
+
+// sync phases
+const (
+	PhaseDrop          = database.PhaseDrop
+	PhaseDump          = database.PhaseDump
+	PhaseImport        = database.PhaseImport
+	PhaseCreateObjects = database.PhaseCreateObjects
+)
+
+// PhaseError - error of a sync phase: drop, dump, import or create objects, of a single table
+// when Table is set
+type PhaseError struct {
+	Phase string
+	Table string
+	Err   error
+}
+
+func (e *PhaseError) Error() string {
+	return (*database.PhaseError)(e).Error()
+}
+
+// Unwrap - returns the error of the phase
+func (e *PhaseError) Unwrap() error {
+	return e.Err
+}
+
+// syncError - returns the sync errors as PhaseError
+func syncError(err error) error {
+	if pe, ok := err.(*database.PhaseError); ok {
+		return (*PhaseError)(pe)
+	}
+
+	return err
+}
+
+// GenerateDiff - compares master and slave. The diff holds the connections to both until closed.
+// Listing and checksumming the tables is limited to the checksum timeout.
+func GenerateDiff(ctx context.Context, master, slave Server, opts Options) (*Diff, error) {
+	d := &Diff{
+		master: master,
+		slave:  slave,
+		opts:   opts,
+	}
+
+	var err error
+	if d.slaveCfg, err = slave.connectionConfig(opts); err != nil {
+		return nil, err
+	}
+
+	if d.slaveConn, err = createConnection(d.slaveCfg); err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := database.WithTimeout(ctx, opts.Timeouts.Checksum)
+	defer cancel()
+
+	if err := d.openSource(ctx); err != nil {
+		return nil, err
+	}
+
+	var unchanged map[string]database.ChecksumPair
+	if opts.State != nil && len(opts.Subset) == 0 {
+		if err := d.fingerprint(ctx); err != nil {
+			d.Close()
+			return nil, err
+		}
+
+		unchanged = d.knownUnchanged()
+	}
+
+	if len(opts.Subset) > 0 {
+		d.diff, err = database.GenerateSubsetDiff(ctx, d.source, d.slaveConn)
+	} else {
+		d.diff, err = database.GenerateIncrementalDiff(ctx, d.source, d.slaveConn, unchanged)
+	}
+	if err != nil {
+		d.Close()
+		return nil, err
+	}
+
+	d.Create = d.diff.Create
+	d.Refresh = d.diff.Refresh
+	d.Delete = d.diff.Delete
+	d.Updated = d.diff.Updated
+	d.Unchanged = d.diff.Unchanged
+	d.Unknown = d.diff.Unknown
+	d.MasterChecksums = d.diff.MasterChecksums
+	d.SlaveChecksums = d.diff.SlaveChecksums
+	for _, table := range d.Unchanged {
+		if _, ok := unchanged[table]; ok {
+			d.Skipped = append(d.Skipped, table)
+		}
+	}
+
+	for _, o := range d.diff.CreateObjects {
+		d.CreateObjects = append(d.CreateObjects, o.String())
+	}
+
+	for _, o := range d.diff.DropObjects {
+		d.DropObjects = append(d.DropObjects, o.String())
+	}
+
+	return d, nil
+}
+
+// openSource - opens the master snapshot or creates the master connection, computing the
+// subset filters when a subset is configured
+func (d *Diff) openSource(ctx context.Context) error {
+	if d.master.Snapshot != "" {
+		if len(d.opts.Subset) > 0 {
+			return errors.New("subset cannot be used with snapshots")
+		}
+
+		snap, err := snapshot.Open(d.master.Snapshot)
+		if err != nil {
+			return err
+		}
+
+		d.source = snap
+
+		return nil
+	}
+
+	var err error
+	if d.masterCfg, err = d.master.connectionConfig(d.opts); err != nil {
+		return err
+	}
+
+	conn, err := createConnection(d.masterCfg)
+	if err != nil {
+		return err
+	}
+
+	d.source = conn
+	if len(d.opts.Subset) == 0 {
+		return nil
+	}
+
+	mysqlConn, ok := conn.(*mysql.Connection)
+	if !ok {
+		return errors.New("subset is only supported by the mysql driver")
+	}
+
+	batches, err := mysql.GenerateSubset(ctx, mysqlConn, d.opts.Subset)
+	if err != nil {
+		return err
+	}
+
+	d.masterCfg.Filters = make(map[string]string, len(batches))
+	for table, conditions := range batches {
+		d.masterCfg.Filters[table] = "(" + strings.Join(conditions, ") or (") + ")"
+	}
+	d.masterCfg.FilterBatches = batches
+
+	return nil
+}
+
+// Empty - returns true if there is nothing to sync
+func (d *Diff) Empty() bool {
+	return d.diff.Empty()
+}
+
+// Close - closes the master and slave connections
+func (d *Diff) Close() error {
+	var err error
+	if conn, ok := d.source.(database.Connection); ok {
+		err = conn.Close()
+	}
+
+	if d.slaveConn != nil {
+		if slaveErr := d.slaveConn.Close(); slaveErr != nil {
+			err = slaveErr
+		}
+	}
+
+	return err
+}
+
+// dumper - returns the dumper of master translating to the slave engine
+func (d *Diff) dumper(ctx context.Context) (database.Dumper, error) {
+	var dumper database.Dumper
+	if snap, ok := d.source.(*snapshot.Snapshot); ok {
+		dumper = snap
+	} else {
+		var err error
+		if dumper, err = createDumper(ctx, d.masterCfg, d.opts); err != nil {
+			return nil, err
+		}
+	}
+
+	return targetDumper(dumper, d.source.Driver(), d.slaveConn.Driver(), d.opts)
+}
+
+// Sync - syncs the differences to slave. Once ctx is done, or the dump or import timeout
+// expires, the running dumps and imports are killed and no further table is synced.
+// On failure the result holds the tables synced or failed so far.
+// With a state in the options, the run and the tables left in sync are recorded in it.
+func (d *Diff) Sync(ctx context.Context) (*Result, error) {
+	start := time.Now()
+	res, err := d.sync(ctx, start)
+	// failing to fingerprint the synced tables only leaves them out of the state, to be compared
+	// again by the next sync
+	_ = d.record(ctx, start, res, err)
+
+	return res, err
+}
+
+// Record - records in the state of the options a run which synced nothing and the tables in sync,
+// for empty diffs which are not synced
+func (d *Diff) Record(ctx context.Context) error {
+	if d.opts.State == nil {
+		return errors.New("Record: no state in the options")
+	}
+
+	if !d.Empty() {
+		return errors.New("Record: the diff is not empty")
+	}
+
+	return d.record(ctx, time.Now(), &Result{Diff: d}, nil)
+}
+
+func (d *Diff) sync(ctx context.Context, start time.Time) (*Result, error) {
+	res := &Result{
+		Diff: d,
+	}
+	if d.Empty() {
+		return res, nil
+	}
+
+	dumper, err := d.dumper(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	imp, err := createImporter(d.slaveCfg)
+	if err != nil {
+		return nil, err
+	}
+
+	d.startProgress(ctx)
+
+	var mu sync.Mutex
+	syncer := database.NewSyncer(dumper, imp, d.opts.Workers)
+	syncer.SetTimeouts(database.Timeouts(d.opts.Timeouts))
+	syncer.SetTableCallback(func(r database.TableResult) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		res.Tables = append(res.Tables, TableResult{
+			Table:    r.Table,
+			Refresh:  r.Refresh,
+			Deleted:  r.Deleted,
+			Bytes:    r.Bytes,
+			Duration: r.Duration,
+			Err:      syncError(r.Err),
+		})
+	})
+
+	err = syncer.Sync(ctx, d.diff)
+	res.Duration = time.Since(start)
+
+	return res, syncError(err)
+}
+
+// Plan - generates the plan of the differences, to be applied later with Apply. The plan records
+// the current checksums of the synced master tables and of every slave table.
+func (d *Diff) Plan(ctx context.Context) (*Plan, error) {
+	if d.Empty() {
+		return nil, errors.New("diff empty")
+	}
+
+	dumper, err := d.dumper(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &Plan{CreatedAt: time.Now().UTC()}
+	p.Master, p.Slave = d.servers()
+	p.Create = d.Create
+	p.Refresh = d.Refresh
+	p.Delete = d.Delete
+	p.CreateObjects = d.CreateObjects
+	p.DropObjects = d.DropObjects
+	p.Filters = d.slaveCfg.Filters
+
+	if err := d.planChecksums(ctx, p); err != nil {
+		return nil, err
+	}
+
+	dumpCtx, cancel := database.WithTimeout(ctx, d.opts.Timeouts.Dump)
+	defer cancel()
+
+	p.SQL, err = d.diff.GenerateSQL(dumpCtx, dumper)
+	if err != nil {
+		return nil, err
+	}
+
+	return p, nil
+}
+
+// servers - returns the master and slave hosts and schemas, the snapshot directory being
+// the master schema when syncing from a snapshot
+func (d *Diff) servers() (ServerInfo, ServerInfo) {
+	master := ServerInfo{Host: d.master.Host, Schema: d.master.Schema}
+	if d.master.Snapshot != "" {
+		master = ServerInfo{Schema: d.master.Snapshot}
+	}
+
+	return master, ServerInfo{Host: d.slave.Host, Schema: d.slave.Schema}
+}
+
+// planChecksums - records the checksums of the synced master tables and of every slave table
+func (d *Diff) planChecksums(ctx context.Context, p *Plan) error {
+	ctx, cancel := database.WithTimeout(ctx, d.opts.Timeouts.Checksum)
+	defer cancel()
+
+	var err error
+	p.MasterChecksums, err = d.source.ChecksumTables(ctx, append(append([]string{}, d.Create...), d.Refresh...)...)
+	if err != nil {
+		return err
+	}
+
+	p.SlaveChecksums, err = database.TableChecksums(ctx, d.slaveConn)
+
+	return err
+}
+
+// Sync - syncs slave with master
+func Sync(ctx context.Context, master, slave Server, opts Options) (*Result, error) {
+	d, err := GenerateDiff(ctx, master, slave, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer d.Close()
+
+	return d.Sync(ctx)
+}
@@ -0,0 +1,89 @@
+package dbsync
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+func TestDiffKnownUnchanged(t *testing.T) {
+	opts := Options{Filters: map[string]string{"orders": "total > 0"}}
+	source, err := createConnection(database.ConnectionConfig{Driver: "sqlite", Filters: opts.Filters})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Diff{opts: opts, source: source}
+	table := func(masterFp, slaveFp string) StateTable {
+		return StateTable{
+			MasterFingerprint: masterFp,
+			SlaveFingerprint:  slaveFp,
+			MasterChecksum:    "m-" + masterFp,
+			SlaveChecksum:     "s-" + slaveFp,
+		}
+	}
+
+	d.opts.State = &State{
+		Tables: map[string]StateTable{
+			"users":         table("m1", "s1"),
+			"orders":        table("m1", "s1"),
+			"master_update": table("m1", "s1"),
+			"slave_update":  table("m1", "s1"),
+			"unknown":       table("m1", "s1"),
+			"other_options": table("m1", "s1"),
+		},
+	}
+	for name, t := range d.opts.State.Tables {
+		t.Options = d.tableOptions(name)
+		d.opts.State.Tables[name] = t
+	}
+
+	// synced with another filter than the current one
+	t2 := d.opts.State.Tables["other_options"]
+	t2.Options = "other"
+	d.opts.State.Tables["other_options"] = t2
+
+	d.masterFingerprints = map[string]string{
+		"users": "m1", "orders": "m1", "master_update": "m2", "slave_update": "m1", "other_options": "m1", "new": "m1",
+	}
+	d.slaveFingerprints = map[string]string{
+		"users": "s1", "orders": "s1", "master_update": "s1", "slave_update": "s2", "unknown": "s1", "other_options": "s1", "new": "s1",
+	}
+
+	want := map[string]database.ChecksumPair{
+		"users":  {Master: "m-m1", Slave: "s-s1"},
+		"orders": {Master: "m-m1", Slave: "s-s1"},
+	}
+	if got := d.knownUnchanged(); !reflect.DeepEqual(got, want) {
+		t.Errorf("knownUnchanged() = %v, want %v", got, want)
+	}
+}
+
+func TestDiffTableOptions(t *testing.T) {
+	opts := Options{
+		Filters:  map[string]string{"orders": "total > 0"},
+		Masks:    map[string]map[string]Mask{"users": {"email": {Type: "hash"}}},
+		MaskSeed: "seed",
+	}
+	source, err := createConnection(database.ConnectionConfig{Driver: "sqlite", Filters: opts.Filters})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	d := &Diff{opts: opts, source: source}
+	reseeded := &Diff{opts: opts, source: source}
+	reseeded.opts.MaskSeed = "other seed"
+
+	if d.tableOptions("logs") == d.tableOptions("orders") {
+		t.Errorf("filtered and unfiltered tables have the same options")
+	}
+
+	if d.tableOptions("users") == reseeded.tableOptions("users") {
+		t.Errorf("masked tables keep their options when the mask seed changes")
+	}
+
+	if d.tableOptions("logs") != reseeded.tableOptions("logs") {
+		t.Errorf("unmasked tables change options when the mask seed changes")
+	}
+}
@@ -0,0 +1,99 @@
+package dbsync
+
+import (
+	"context"
+	"errors"
+	"io"
+
+	"github.com/vcraescu/dbsync/internal/compress"
+	"github.com/vcraescu/dbsync/internal/database"
+	"github.com/vcraescu/dbsync/internal/database/mysql"
+	"github.com/vcraescu/dbsync/internal/snapshot"
+)
+
+// Dump - writes the sql recreating the given tables of master to w, every table when none is given.
+// Filtered tables only include their matching rows and masked columns are masked.
+func Dump(ctx context.Context, master Server, w io.Writer, opts Options, tables ...string) error {
+	var (
+		source database.Source
+		dumper database.Dumper
+	)
+	if master.Snapshot != "" {
+		snap, err := snapshot.Open(master.Snapshot)
+		if err != nil {
+			return err
+		}
+
+		source, dumper = snap, snap
+	} else {
+		cfg, err := master.connectionConfig(opts)
+		if err != nil {
+			return err
+		}
+
+		conn, err := createConnection(cfg)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if dumper, err = createDumper(ctx, cfg, opts); err != nil {
+			return err
+		}
+
+		source = conn
+	}
+
+	if len(tables) == 0 {
+		if err := source.Open(ctx); err != nil {
+			return err
+		}
+
+		var err error
+		if tables, err = source.TableNames(ctx); err != nil {
+			return err
+		}
+	}
+
+	for _, table := range tables {
+		if err := dumper.DumpTableTo(ctx, w, table); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Snapshot - writes schema, data and checksums of master to dir, which can be used as master
+// afterwards. The table files are compressed with gzip or zstd, unless compression is empty.
+// Returns the number of written tables.
+func Snapshot(ctx context.Context, master Server, dir, compression string, opts Options) (int, error) {
+	a, err := compress.ParseAlgorithm(compression)
+	if err != nil {
+		return 0, err
+	}
+
+	cfg, err := master.connectionConfig(opts)
+	if err != nil {
+		return 0, err
+	}
+
+	if cfg.Driver != "" && cfg.Driver != mysql.DriverName {
+		return 0, errors.New("snapshots are only supported by the mysql driver")
+	}
+
+	dumper, err := createDumper(ctx, cfg, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	conn := mysql.New(cfg)
+	defer conn.Close()
+
+	snap, err := snapshot.Create(ctx, dir, conn, dumper.(*mysql.Dumper), a)
+	if err != nil {
+		return 0, err
+	}
+
+	return len(snap.Manifest().Tables), nil
+}
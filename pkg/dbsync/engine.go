@@ -0,0 +1,140 @@
+package dbsync
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/vcraescu/dbsync/internal/database"
+	"github.com/vcraescu/dbsync/internal/database/mysql"
+	"github.com/vcraescu/dbsync/internal/database/postgres"
+	"github.com/vcraescu/dbsync/internal/database/sqlite"
+	"github.com/vcraescu/dbsync/internal/database/typemap"
+	"github.com/vcraescu/dbsync/internal/masking"
+)
+
+// createConnection - creates a connection of the configured driver
+func createConnection(cfg database.ConnectionConfig) (database.Connection, error) {
+	engine, err := database.Lookup(cfg.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	return engine.Connection(cfg), nil
+}
+
+// createImporter - creates an importer of the configured driver
+func createImporter(cfg database.ConnectionConfig) (database.Importer, error) {
+	engine, err := database.Lookup(cfg.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	return engine.Importer(cfg), nil
+}
+
+// createDumper - creates master dumper masking the configured columns, which must exist on master
+func createDumper(ctx context.Context, cfg database.ConnectionConfig, opts Options) (database.Dumper, error) {
+	engine, err := database.Lookup(cfg.Driver)
+	if err != nil {
+		return nil, err
+	}
+
+	dumper := engine.Dumper(cfg)
+	masker, err := opts.masker()
+	if err != nil {
+		return nil, err
+	}
+
+	if masker == nil {
+		return dumper, nil
+	}
+
+	d, ok := dumper.(*mysql.Dumper)
+	if !ok {
+		return nil, errors.New("masking is only supported by the mysql driver")
+	}
+
+	if err := checkMaskedColumns(ctx, cfg, masker); err != nil {
+		return nil, err
+	}
+
+	d.SetMasker(masker)
+
+	return d, nil
+}
+
+// checkMaskedColumns - returns an error if a masking rule names a table or a column missing from
+// master, as its values would be synced unmasked
+func checkMaskedColumns(ctx context.Context, cfg database.ConnectionConfig, masker *masking.Masker) error {
+	conn := mysql.New(cfg)
+	defer conn.Close()
+
+	if err := conn.Open(ctx); err != nil {
+		return err
+	}
+
+	columns, err := conn.Columns(ctx)
+	if err != nil {
+		return err
+	}
+
+	existing := make(map[string]map[string]bool, len(columns))
+	for table, names := range columns {
+		t := strings.ToLower(table)
+		existing[t] = make(map[string]bool, len(names))
+		for _, name := range names {
+			existing[t][strings.ToLower(name)] = true
+		}
+	}
+
+	masked := masker.Columns()
+	tables := make([]string, 0, len(masked))
+	for table := range masked {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+
+	for _, table := range tables {
+		if existing[table] == nil {
+			return fmt.Errorf("masking rules of table %s: table not found on master", table)
+		}
+
+		sort.Strings(masked[table])
+		for _, column := range masked[table] {
+			if !existing[table][column] {
+				return fmt.Errorf("masking rule %s.%s: column not found on master", table, column)
+			}
+		}
+	}
+
+	return nil
+}
+
+// targetDumper - wraps the master dumper so that its sql runs on the slave engine, column types
+// being mapped to the slave ones
+func targetDumper(dumper database.Dumper, masterDriver, slaveDriver string, opts Options) (database.Dumper, error) {
+	if masterDriver == slaveDriver {
+		return dumper, nil
+	}
+
+	if masterDriver != mysql.DriverName {
+		return nil, fmt.Errorf("syncing a %s master to a %s slave is not supported", masterDriver, slaveDriver)
+	}
+
+	types, err := typemap.New(masterDriver, slaveDriver, opts.Types)
+	if err != nil {
+		return nil, err
+	}
+
+	switch slaveDriver {
+	case sqlite.DriverName:
+		return sqlite.FromMySQL(dumper, types), nil
+	case postgres.DriverName:
+		return postgres.FromMySQL(dumper, types), nil
+	}
+
+	return nil, fmt.Errorf("syncing a %s master to a %s slave is not supported", masterDriver, slaveDriver)
+}
@@ -0,0 +1,52 @@
+package dbsync_test
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/vcraescu/dbsync/pkg/dbsync"
+)
+
+func ExamplePlan_Verify() {
+	p := &dbsync.Plan{
+		Refresh:        []string{"users"},
+		SlaveChecksums: map[string]string{"users": "a1", "orders": "b2"},
+	}
+
+	fmt.Println(p.Verify(map[string]string{"users": "a1", "orders": "b2"}))
+	fmt.Println(p.Verify(map[string]string{"users": "a1", "orders": "c3", "logs": "d4"}))
+	// Output:
+	// <nil>
+	// slave changed since the plan was generated: logs (new), orders (changed)
+}
+
+func ExampleState_LastSuccess() {
+	st := &dbsync.State{Master: "production", Slave: "local"}
+	start := time.Date(2020, 1, 2, 10, 0, 0, 0, time.UTC)
+
+	st.AddRun(dbsync.Run{StartedAt: start, Tables: []string{"users"}})
+	st.AddRun(dbsync.Run{StartedAt: start.Add(time.Hour), Error: "slave: connection refused"})
+
+	fmt.Println(st.LastRun().Succeeded())
+	fmt.Println(st.LastSuccess().StartedAt.Format(time.RFC3339))
+	fmt.Println(st.LastSynced("users").Format(time.RFC3339))
+	// Output:
+	// false
+	// 2020-01-02T10:00:00Z
+	// 2020-01-02T10:00:00Z
+}
+
+func ExampleProgressSnapshot_ETA() {
+	start := time.Date(2020, 1, 2, 10, 0, 0, 0, time.UTC)
+	snap := dbsync.ProgressSnapshot{
+		SyncStart: start,
+		Tables: []dbsync.TableProgress{
+			{Table: "users", Rows: 1000, DumpedRows: 250},
+		},
+	}
+
+	eta, ok := snap.ETA(start.Add(time.Minute))
+	fmt.Println(eta, ok)
+	// Output:
+	// 3m0s true
+}
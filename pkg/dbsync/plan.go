@@ -0,0 +1,150 @@
+package dbsync
+
+import (
+	"context"
+	"time"
+
+	"github.com/vcraescu/dbsync/internal/compress"
+	"github.com/vcraescu/dbsync/internal/database"
+	"github.com/vcraescu/dbsync/internal/plan"
+)
+
+// ServerInfo - host and schema of the master or slave of a plan or report, the schema being the
+// directory of snapshots
+type ServerInfo struct {
+	Host   string `json:"host"`
+	Schema string `json:"schema"`
+}
+
+// Plan - sync plan generated from a diff and applied later on slave
+type Plan struct {
+	CreatedAt     time.Time
+	Master        ServerInfo
+	Slave         ServerInfo
+	Create        []string
+	Refresh       []string
+	Delete        []string
+	CreateObjects []string
+	DropObjects   []string
+	// MasterChecksums - content checksums of the synced master tables at generation time
+	MasterChecksums map[string]string
+	// SlaveChecksums - content checksums of every slave table at generation time
+	SlaveChecksums map[string]string
+	// Filters - where conditions of the filtered tables, the slave checksums being computed
+	// from their matching rows only
+	Filters map[string]string
+	SQL     string
+}
+
+// ReadPlan - reads a plan file, compressed or not
+func ReadPlan(file string) (*Plan, error) {
+	p, err := plan.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Plan{
+		CreatedAt:       p.CreatedAt,
+		Master:          ServerInfo(p.Master),
+		Slave:           ServerInfo(p.Slave),
+		Create:          p.Create,
+		Refresh:         p.Refresh,
+		Delete:          p.Delete,
+		CreateObjects:   p.CreateObjects,
+		DropObjects:     p.DropObjects,
+		MasterChecksums: p.MasterChecksums,
+		SlaveChecksums:  p.SlaveChecksums,
+		Filters:         p.Filters,
+		SQL:             p.SQL,
+	}, nil
+}
+
+// WritePlan - writes the plan to file compressed with gzip or zstd, uncompressed when compression is empty
+func WritePlan(p *Plan, file, compression string) error {
+	a, err := compress.ParseAlgorithm(compression)
+	if err != nil {
+		return err
+	}
+
+	return p.internal().WriteFile(file, a)
+}
+
+// Verify - checks that the slave checksums still match the ones recorded at generation time
+func (p *Plan) Verify(slaveChecksums map[string]string) error {
+	return p.internal().Verify(slaveChecksums)
+}
+
+// internal - returns the plan in the format of plan files
+func (p *Plan) internal() *plan.Plan {
+	ip := plan.New()
+	ip.CreatedAt = p.CreatedAt
+	ip.Master = plan.Server(p.Master)
+	ip.Slave = plan.Server(p.Slave)
+	ip.Create = p.Create
+	ip.Refresh = p.Refresh
+	ip.Delete = p.Delete
+	ip.CreateObjects = p.CreateObjects
+	ip.DropObjects = p.DropObjects
+	ip.MasterChecksums = p.MasterChecksums
+	ip.SlaveChecksums = p.SlaveChecksums
+	ip.Filters = p.Filters
+	ip.SQL = p.SQL
+
+	return ip
+}
+
+// ApplyOptions - how a plan is applied
+type ApplyOptions struct {
+	// Force - applies the plan even if slave changed since it was generated
+	Force bool
+	// Timeouts - maximum durations of the checksum and import phases, unlimited when zero
+	Timeouts Timeouts
+}
+
+// Apply - applies the plan on slave, unless slave changed since the plan was generated. The slave
+// tables are checksummed with the filters of the plan, as they were at generation time.
+func Apply(ctx context.Context, p *Plan, slave Server, opts ApplyOptions) error {
+	cfg, err := slave.connectionConfig(Options{Filters: p.Filters})
+	if err != nil {
+		return err
+	}
+
+	if !opts.Force {
+		conn, err := createConnection(cfg)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+
+		if err := verify(ctx, p, conn, opts.Timeouts.Checksum); err != nil {
+			return err
+		}
+	}
+
+	imp, err := createImporter(cfg)
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := database.WithTimeout(ctx, opts.Timeouts.Import)
+	defer cancel()
+
+	return imp.Import(ctx, p.SQL)
+}
+
+// verify - returns an error if the slave checksums differ from the ones recorded in the plan
+func verify(ctx context.Context, p *Plan, conn database.Connection, timeout time.Duration) error {
+	ctx, cancel := database.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if err := conn.Open(ctx); err != nil {
+		return err
+	}
+
+	checksums, err := database.TableChecksums(ctx, conn)
+	if err != nil {
+		return err
+	}
+
+	return p.Verify(checksums)
+}
@@ -0,0 +1,91 @@
+package dbsync_test
+
+import (
+	"context"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/vcraescu/dbsync/pkg/dbsync"
+)
+
+// sqliteServer - returns a sqlite server whose database file is created by running sql
+func sqliteServer(t *testing.T, name, sql string) dbsync.Server {
+	t.Helper()
+
+	file := filepath.Join(t.TempDir(), name+".db")
+	cmd := exec.Command("sqlite3", "-batch", "-bail", file)
+	cmd.Stdin = strings.NewReader(sql)
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("sqlite3 %s: %s: %s", name, err, out)
+	}
+
+	return dbsync.Server{Driver: "sqlite", Schema: file}
+}
+
+// sqliteQuery - returns the output of a query on the database file of the server
+func sqliteQuery(t *testing.T, s dbsync.Server, sql string) string {
+	t.Helper()
+
+	out, err := exec.Command("sqlite3", "-batch", s.Schema, sql).CombinedOutput()
+	if err != nil {
+		t.Fatalf("sqlite3 %s: %s: %s", sql, err, out)
+	}
+
+	return strings.TrimSpace(string(out))
+}
+
+func TestApplyFilteredPlan(t *testing.T) {
+	if _, err := exec.LookPath("sqlite3"); err != nil {
+		t.Skip("sqlite3 not installed")
+	}
+
+	const schema = "create table items (id integer primary key, flag int, t text);\n"
+	master := sqliteServer(t, "master", schema+
+		"insert into items values (1, 1, 'a'), (2, 1, 'b'), (3, 0, 'master only');\n")
+	// the rows outside the filter differ from master, which the filtered checksums ignore
+	slave := sqliteServer(t, "slave", schema+
+		"insert into items values (1, 1, 'a'), (4, 0, 'slave only');\n")
+
+	ctx := context.Background()
+	opts := dbsync.Options{Filters: map[string]string{"items": "flag = 1"}}
+	d, err := dbsync.GenerateDiff(ctx, master, slave, opts)
+	if err != nil {
+		t.Fatalf("GenerateDiff() error = %s", err)
+	}
+	defer d.Close()
+
+	p, err := d.Plan(ctx)
+	if err != nil {
+		t.Fatalf("Plan() error = %s", err)
+	}
+
+	if got := strings.Join(p.Refresh, ","); got != "items" {
+		t.Fatalf("plan refreshes %q, want items", got)
+	}
+
+	file := filepath.Join(t.TempDir(), "plan.dbsync")
+	if err := dbsync.WritePlan(p, file, ""); err != nil {
+		t.Fatalf("WritePlan() error = %s", err)
+	}
+
+	read, err := dbsync.ReadPlan(file)
+	if err != nil {
+		t.Fatalf("ReadPlan() error = %s", err)
+	}
+
+	unfiltered := *read
+	unfiltered.Filters = nil
+	if err := dbsync.Apply(ctx, &unfiltered, slave, dbsync.ApplyOptions{}); err == nil {
+		t.Fatalf("Apply() verified the filtered checksums against the whole slave tables")
+	}
+
+	if err := dbsync.Apply(ctx, read, slave, dbsync.ApplyOptions{}); err != nil {
+		t.Fatalf("Apply() error = %s", err)
+	}
+
+	if got, want := sqliteQuery(t, slave, "select id from items order by id"), "1\n2\n4"; got != want {
+		t.Errorf("slave rows = %q, want %q", got, want)
+	}
+}
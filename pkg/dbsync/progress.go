@@ -0,0 +1,120 @@
+package dbsync
+
+import (
+	"context"
+	"time"
+
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+// Progress - live counters of the table checksums of a diff and of the bytes, rows and
+// statements of a sync, for progress displays. Safe for concurrent use.
+type Progress struct {
+	p *database.Progress
+}
+
+// ProgressSnapshot - copy of the progress counters at a point in time
+type ProgressSnapshot struct {
+	// ChecksumDone, ChecksumTotal - table checksums computed on master and slave, and to compute
+	ChecksumDone  int
+	ChecksumTotal int
+	ChecksumStart time.Time
+	// ChecksumLast - table whose checksum was computed last
+	ChecksumLast string
+	// Tables - synced tables, in sync order
+	Tables    []TableProgress
+	SyncStart time.Time
+}
+
+// TableProgress - progress counters of a synced table
+type TableProgress struct {
+	Table string
+	// Rows - rows of the master table, -1 when unknown
+	Rows int64
+	// Bytes - size of the sql dumped from master and imported into slave
+	Bytes int64
+	// DumpedRows - rows dumped so far, counted from the inserted values, so approximate
+	DumpedRows int64
+	// Statements - statements read by the import client so far
+	Statements int64
+	Started    time.Time
+	Finished   time.Time
+	Err        error
+}
+
+// NewProgress - constructor
+func NewProgress() *Progress {
+	return &Progress{p: database.NewProgress()}
+}
+
+// WithProgress - returns ctx reporting the progress of the diffs and syncs using it to p
+func WithProgress(ctx context.Context, p *Progress) context.Context {
+	return database.WithProgress(ctx, p.p)
+}
+
+// Snapshot - returns a copy of the counters
+func (p *Progress) Snapshot() ProgressSnapshot {
+	s := p.p.Snapshot()
+	snap := ProgressSnapshot{
+		ChecksumDone:  s.ChecksumDone,
+		ChecksumTotal: s.ChecksumTotal,
+		ChecksumStart: s.ChecksumStart,
+		ChecksumLast:  s.ChecksumLast,
+		Tables:        make([]TableProgress, len(s.Tables)),
+		SyncStart:     s.SyncStart,
+	}
+	for i, t := range s.Tables {
+		snap.Tables[i] = TableProgress(t)
+	}
+
+	return snap
+}
+
+// Totals - returns the rows of the synced tables, -1 when any of them is unknown, and the rows
+// and bytes synced so far
+func (s ProgressSnapshot) Totals() (rows, doneRows, bytes int64) {
+	return s.internal().Totals()
+}
+
+// ETA - returns the estimated time left to sync the remaining rows at the average rate so far,
+// false when unknown
+func (s ProgressSnapshot) ETA(now time.Time) (time.Duration, bool) {
+	return s.internal().ETA(now)
+}
+
+func (s ProgressSnapshot) internal() database.ProgressSnapshot {
+	snap := database.ProgressSnapshot{
+		ChecksumDone:  s.ChecksumDone,
+		ChecksumTotal: s.ChecksumTotal,
+		ChecksumStart: s.ChecksumStart,
+		ChecksumLast:  s.ChecksumLast,
+		Tables:        make([]database.TableProgress, len(s.Tables)),
+		SyncStart:     s.SyncStart,
+	}
+	for i, t := range s.Tables {
+		snap.Tables[i] = database.TableProgress(t)
+	}
+
+	return snap
+}
+
+// startProgress - starts counting the sync progress of the created and refreshed tables. The
+// master rows are counted for estimating the time left, which is unknown when counting fails.
+func (d *Diff) startProgress(ctx context.Context) {
+	p := database.ProgressFrom(ctx)
+	if p == nil {
+		return
+	}
+
+	tables := append(append([]string{}, d.Create...), d.Refresh...)
+
+	ctx, cancel := database.WithTimeout(ctx, d.opts.Timeouts.Checksum)
+	defer cancel()
+
+	rows, err := estimateRows(ctx, d.source, tables)
+	if err != nil {
+		rows = nil
+	}
+
+	p.StartSync(tables, rows)
+}
@@ -0,0 +1,222 @@
+package dbsync
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+// TableStatus - state of a slave table compared to master
+type TableStatus string
+
+const (
+	// TableCreated - table missing on slave
+	TableCreated TableStatus = "created"
+	// TableUpdated - table whose content differs between master and slave
+	TableUpdated TableStatus = "updated"
+	// TableDeleted - table missing on master
+	TableDeleted TableStatus = "deleted"
+	// TableUnchanged - table with the same content on master and slave
+	TableUnchanged TableStatus = "unchanged"
+	// TableUnknown - table whose content can't be compared, master and slave being of different
+	// engines
+	TableUnknown TableStatus = "unknown"
+)
+
+// TableReport - comparison and sync outcome of a table
+type TableReport struct {
+	Name   string      `json:"name"`
+	Status TableStatus `json:"status"`
+	// MasterChecksum, SlaveChecksum - checksums the table was compared by, empty when the table
+	// only exists on one side
+	MasterChecksum string `json:"master_checksum,omitempty"`
+	SlaveChecksum  string `json:"slave_checksum,omitempty"`
+	// MasterRows, SlaveRows - row counts at report time, estimated unless the diff counts rows.
+	// nil when the table does not exist, has no estimate, or the source cannot count rows, like
+	// snapshots.
+	MasterRows *int64 `json:"master_rows,omitempty"`
+	SlaveRows  *int64 `json:"slave_rows,omitempty"`
+	// Synced - true when the table was created, refreshed or deleted on slave
+	Synced bool `json:"synced,omitempty"`
+	// Bytes - size of the sql streamed from master to slave
+	Bytes      int64  `json:"bytes,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Report - machine readable outcome of a diff or a sync
+type Report struct {
+	Master        ServerInfo    `json:"master"`
+	Slave         ServerInfo    `json:"slave"`
+	Tables        []TableReport `json:"tables"`
+	CreateObjects []string      `json:"create_objects,omitempty"`
+	DropObjects   []string      `json:"drop_objects,omitempty"`
+	// RowsEstimated - true when the row counts of the tables are estimates
+	RowsEstimated bool `json:"rows_estimated,omitempty"`
+	// Plan - file the plan was written to
+	Plan string `json:"plan,omitempty"`
+	// Synced - true when every difference was synced
+	Synced     bool   `json:"synced"`
+	Bytes      int64  `json:"bytes,omitempty"`
+	DurationMs int64  `json:"duration_ms,omitempty"`
+	Error      string `json:"error,omitempty"`
+}
+
+// Report - returns the status, checksums and row counts of every master and slave table. The row
+// counts are estimated from the server statistics, unless the options count rows: counting reads
+// every table, so it is limited to the checksum timeout.
+func (d *Diff) Report(ctx context.Context) (*Report, error) {
+	ctx, cancel := database.WithTimeout(ctx, d.opts.Timeouts.Checksum)
+	defer cancel()
+
+	r := &Report{
+		Tables:        []TableReport{},
+		CreateObjects: d.CreateObjects,
+		DropObjects:   d.DropObjects,
+		RowsEstimated: !d.opts.CountRows,
+		Synced:        d.Empty(),
+	}
+	r.Master, r.Slave = d.servers()
+
+	var masterTables, slaveTables []string
+	add := func(table string, status TableStatus) {
+		r.Tables = append(r.Tables, TableReport{
+			Name:           table,
+			Status:         status,
+			MasterChecksum: d.MasterChecksums[table],
+			SlaveChecksum:  d.SlaveChecksums[table],
+		})
+
+		if status != TableDeleted {
+			masterTables = append(masterTables, table)
+		}
+
+		if status != TableCreated {
+			slaveTables = append(slaveTables, table)
+		}
+	}
+
+	for _, table := range append(append([]string{}, d.Create...), d.Refresh...) {
+		add(table, d.Status(table))
+	}
+
+	for _, table := range d.Delete {
+		add(table, TableDeleted)
+	}
+
+	for _, table := range d.Unchanged {
+		add(table, TableUnchanged)
+	}
+
+	sort.Slice(r.Tables, func(i, j int) bool {
+		return r.Tables[i].Name < r.Tables[j].Name
+	})
+
+	rowCounts := estimateRows
+	if d.opts.CountRows {
+		rowCounts = countRows
+	}
+
+	masterRows, err := rowCounts(ctx, d.source, masterTables)
+	if err != nil {
+		return nil, fmt.Errorf("master row counts: %s", err)
+	}
+
+	slaveRows, err := rowCounts(ctx, d.slaveConn, slaveTables)
+	if err != nil {
+		return nil, fmt.Errorf("slave row counts: %s", err)
+	}
+
+	for i := range r.Tables {
+		t := &r.Tables[i]
+		if n, ok := masterRows[t.Name]; ok {
+			t.MasterRows = &n
+		}
+
+		if n, ok := slaveRows[t.Name]; ok {
+			t.SlaveRows = &n
+		}
+	}
+
+	return r, nil
+}
+
+// Status - returns the status of a created or refreshed table
+func (d *Diff) Status(table string) TableStatus {
+	for _, t := range d.Unknown {
+		if t == table {
+			return TableUnknown
+		}
+	}
+
+	for _, t := range d.Updated {
+		if t == table {
+			return TableUpdated
+		}
+	}
+
+	return TableCreated
+}
+
+// countRows - returns the row counts of the tables, nil when the source cannot count rows
+func countRows(ctx context.Context, src database.Source, tables []string) (map[string]int64, error) {
+	counter, ok := src.(database.RowCounter)
+	if !ok || len(tables) == 0 {
+		return nil, nil
+	}
+
+	return counter.CountRows(ctx, tables...)
+}
+
+// estimateRows - returns the estimated row counts of the tables, nil when the source cannot
+// estimate rows
+func estimateRows(ctx context.Context, src database.Source, tables []string) (map[string]int64, error) {
+	estimator, ok := src.(database.RowEstimator)
+	if !ok || len(tables) == 0 {
+		return nil, nil
+	}
+
+	return estimator.EstimateRows(ctx, tables...)
+}
+
+// AddResult - adds the outcome of the sync of the reported diff, err being the sync error
+func (r *Report) AddResult(res *Result, err error) {
+	r.Synced = err == nil
+	if err != nil {
+		r.Error = err.Error()
+	}
+
+	if res == nil {
+		return
+	}
+
+	r.DurationMs = durationMs(res.Duration)
+
+	byName := make(map[string]*TableReport, len(r.Tables))
+	for i := range r.Tables {
+		byName[r.Tables[i].Name] = &r.Tables[i]
+	}
+
+	for _, tr := range res.Tables {
+		r.Bytes += tr.Bytes
+
+		t, ok := byName[tr.Table]
+		if !ok {
+			continue
+		}
+
+		t.Synced = tr.Err == nil
+		t.Bytes = tr.Bytes
+		t.DurationMs = durationMs(tr.Duration)
+		if tr.Err != nil {
+			t.Error = tr.Err.Error()
+		}
+	}
+}
+
+func durationMs(d time.Duration) int64 {
+	return int64(d / time.Millisecond)
+}
@@ -0,0 +1,177 @@
+package dbsync
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/vcraescu/dbsync/internal/database"
+)
+
+// rowsConn - connection only counting and estimating rows, whose estimates differ from the counts
+type rowsConn struct {
+	database.Connection
+	counts    map[string]int64
+	estimates map[string]int64
+}
+
+func (c *rowsConn) CountRows(ctx context.Context, tables ...string) (map[string]int64, error) {
+	return pickRows(c.counts, tables), nil
+}
+
+func (c *rowsConn) EstimateRows(ctx context.Context, tables ...string) (map[string]int64, error) {
+	return pickRows(c.estimates, tables), nil
+}
+
+func pickRows(rows map[string]int64, tables []string) map[string]int64 {
+	picked := make(map[string]int64)
+	for _, table := range tables {
+		if n, ok := rows[table]; ok {
+			picked[table] = n
+		}
+	}
+
+	return picked
+}
+
+// reportDiff - diff of a table of every status
+func reportDiff(countRows bool) *Diff {
+	rows := func(n int64) map[string]int64 {
+		return map[string]int64{"new": n, "old": n, "orders": n, "other": n, "users": n}
+	}
+
+	return &Diff{
+		Create:          []string{"new", "other"},
+		Refresh:         []string{"orders"},
+		Delete:          []string{"old"},
+		Updated:         []string{"orders", "other"},
+		Unchanged:       []string{"users"},
+		Unknown:         []string{"other"},
+		MasterChecksums: map[string]string{"orders": "m1", "users": "u1"},
+		SlaveChecksums:  map[string]string{"orders": "s1", "users": "u1"},
+		CreateObjects:   []string{"view active_users"},
+		master:          Server{Host: "db.prod", Schema: "shop"},
+		slave:           Server{Host: "localhost", Schema: "shop_dev"},
+		diff:            &database.Diff{Create: []string{"new", "other"}, Refresh: []string{"orders"}, Delete: []string{"old"}},
+		opts:            Options{CountRows: countRows},
+		source:          &rowsConn{counts: rows(10), estimates: rows(12)},
+		slaveConn:       &rowsConn{counts: rows(20), estimates: rows(24)},
+	}
+}
+
+func TestReportStatuses(t *testing.T) {
+	r, err := reportDiff(false).Report(context.Background())
+	if err != nil {
+		t.Fatalf("Report() error = %s", err)
+	}
+
+	want := map[string]TableStatus{
+		"new":    TableCreated,
+		"old":    TableDeleted,
+		"orders": TableUpdated,
+		"other":  TableUnknown,
+		"users":  TableUnchanged,
+	}
+
+	var names []string
+	got := make(map[string]TableStatus)
+	for _, t := range r.Tables {
+		names = append(names, t.Name)
+		got[t.Name] = t.Status
+	}
+
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("statuses = %v, want %v", got, want)
+	}
+
+	if want := []string{"new", "old", "orders", "other", "users"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("tables = %q, want them sorted as %q", names, want)
+	}
+
+	if r.Synced {
+		t.Errorf("report of differences synced")
+	}
+
+	d := &Diff{Unchanged: []string{"users"}, diff: &database.Diff{}, source: &rowsConn{}, slaveConn: &rowsConn{}}
+	empty, err := d.Report(context.Background())
+	if err != nil || !empty.Synced {
+		t.Errorf("Report() of an empty diff = %+v, %v, want it synced", empty, err)
+	}
+}
+
+func TestReportRows(t *testing.T) {
+	tests := []struct {
+		name          string
+		countRows     bool
+		master, slave int64
+	}{
+		{"estimated", false, 12, 24},
+		{"counted", true, 10, 20},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r, err := reportDiff(tt.countRows).Report(context.Background())
+			if err != nil {
+				t.Fatalf("Report() error = %s", err)
+			}
+
+			if r.RowsEstimated == tt.countRows {
+				t.Errorf("RowsEstimated = %v with CountRows %v", r.RowsEstimated, tt.countRows)
+			}
+
+			for _, table := range r.Tables {
+				// created tables have no slave rows and deleted tables no master rows
+				wantMaster, wantSlave := &tt.master, &tt.slave
+				switch table.Status {
+				case TableCreated:
+					wantSlave = nil
+				case TableDeleted:
+					wantMaster = nil
+				}
+
+				if !reflect.DeepEqual(table.MasterRows, wantMaster) || !reflect.DeepEqual(table.SlaveRows, wantSlave) {
+					t.Errorf("%s rows = %v, %v, want %v, %v", table.Name, table.MasterRows, table.SlaveRows, wantMaster, wantSlave)
+				}
+			}
+		})
+	}
+}
+
+func TestReportJSON(t *testing.T) {
+	d := reportDiff(true)
+	d.Create, d.Unchanged = nil, nil
+	d.diff.Create = nil
+
+	r, err := d.Report(context.Background())
+	if err != nil {
+		t.Fatalf("Report() error = %s", err)
+	}
+
+	res := &Result{
+		Duration: 1500 * time.Millisecond,
+		Tables: []TableResult{
+			{Table: "orders", Refresh: true, Bytes: 100, Duration: 20 * time.Millisecond},
+			{Table: "old", Deleted: true, Err: errors.New("drop denied")},
+		},
+	}
+	r.AddResult(res, errors.New("sync failed"))
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := `{"master":{"host":"db.prod","schema":"shop"},"slave":{"host":"localhost","schema":"shop_dev"},` +
+		`"tables":[` +
+		`{"name":"old","status":"deleted","slave_rows":20,"error":"drop denied"},` +
+		`{"name":"orders","status":"updated","master_checksum":"m1","slave_checksum":"s1","master_rows":10,"slave_rows":20,` +
+		`"synced":true,"bytes":100,"duration_ms":20}` +
+		`],"create_objects":["view active_users"],"synced":false,"bytes":100,"duration_ms":1500,"error":"sync failed"}`
+	if string(data) != want {
+		t.Errorf("json =\n%s\nwant\n%s", data, want)
+	}
+}
@@ -0,0 +1,377 @@
+package dbsync
+
+import (
+	"context"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/vcraescu/dbsync/internal/bisync"
+	"github.com/vcraescu/dbsync/internal/database"
+	"github.com/vcraescu/dbsync/internal/state"
+)
+
+// StateTable - state of a table after the last sync which compared or synced it
+type StateTable struct {
+	// MasterFingerprint, SlaveFingerprint - cheap values changing whenever the table changes,
+	// taken before comparing on master and after syncing on slave
+	MasterFingerprint string `json:"master_fingerprint"`
+	SlaveFingerprint  string `json:"slave_fingerprint"`
+	// MasterChecksum, SlaveChecksum - content checksums, when they were computed
+	MasterChecksum string `json:"master_checksum,omitempty"`
+	SlaveChecksum  string `json:"slave_checksum,omitempty"`
+	// Options - hash of the filter, masks and types of the table the sync used
+	Options string `json:"options"`
+	// SyncedAt - last time the table was copied to slave
+	SyncedAt time.Time `json:"synced_at,omitempty"`
+	// CheckedAt - last time the table was known to be in sync
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Run - outcome of a sync recorded in the state
+type Run struct {
+	StartedAt  time.Time `json:"started_at"`
+	DurationMs int64     `json:"duration_ms"`
+	// Tables - tables created, refreshed or deleted on slave
+	Tables []string `json:"tables,omitempty"`
+	Bytes  int64    `json:"bytes"`
+	Error  string   `json:"error,omitempty"`
+	// User - who ran the sync, as user@host
+	User string `json:"user,omitempty"`
+}
+
+// Succeeded - returns true if the sync did not fail
+func (r Run) Succeeded() bool {
+	return r.Error == ""
+}
+
+// State - what is known of a master and slave pair from their previous syncs: the tables left
+// in sync, with the fingerprints telling whether they changed since, and the previous runs
+type State struct {
+	Master string `json:"master"`
+	Slave  string `json:"slave"`
+	// Tables - tables in sync after the last sync, by name
+	Tables map[string]StateTable `json:"tables"`
+	// Runs - previous syncs, oldest first
+	Runs []Run `json:"runs,omitempty"`
+	// Base - checksums of both sides after the last two-way sync
+	Base *Base `json:"base,omitempty"`
+}
+
+// AddRun - records a run, dropping the oldest ones beyond the kept number
+func (s *State) AddRun(r Run) {
+	p := state.Pair{Runs: make([]state.Run, len(s.Runs))}
+	for i, run := range s.Runs {
+		p.Runs[i] = state.Run(run)
+	}
+	p.AddRun(state.Run(r))
+
+	s.Runs = make([]Run, len(p.Runs))
+	for i, run := range p.Runs {
+		s.Runs[i] = Run(run)
+	}
+}
+
+// LastRun - returns the last run, nil if the pair was never synced
+func (s *State) LastRun() *Run {
+	if len(s.Runs) == 0 {
+		return nil
+	}
+
+	return &s.Runs[len(s.Runs)-1]
+}
+
+// LastSuccess - returns the last run which did not fail, nil if none
+func (s *State) LastSuccess() *Run {
+	for i := len(s.Runs) - 1; i >= 0; i-- {
+		if s.Runs[i].Succeeded() {
+			return &s.Runs[i]
+		}
+	}
+
+	return nil
+}
+
+// LastSynced - returns the start of the last run which synced a table, the zero time if none
+func (s *State) LastSynced(table string) time.Time {
+	for i := len(s.Runs) - 1; i >= 0; i-- {
+		for _, t := range s.Runs[i].Tables {
+			if t == table {
+				return s.Runs[i].StartedAt
+			}
+		}
+	}
+
+	return time.Time{}
+}
+
+func newState(p *state.Pair) *State {
+	if p == nil {
+		return nil
+	}
+
+	s := &State{
+		Master: p.Master,
+		Slave:  p.Slave,
+		Tables: make(map[string]StateTable, len(p.Tables)),
+		Base:   (*Base)(p.Base),
+	}
+	for name, t := range p.Tables {
+		s.Tables[name] = StateTable(t)
+	}
+
+	for _, r := range p.Runs {
+		s.Runs = append(s.Runs, Run(r))
+	}
+
+	return s
+}
+
+func (s *State) internal() *state.Pair {
+	p := &state.Pair{
+		Master: s.Master,
+		Slave:  s.Slave,
+		Tables: make(map[string]state.Table, len(s.Tables)),
+		Base:   (*bisync.Base)(s.Base),
+	}
+	for name, t := range s.Tables {
+		p.Tables[name] = state.Table(t)
+	}
+
+	for _, r := range s.Runs {
+		p.Runs = append(p.Runs, state.Run(r))
+	}
+
+	return p
+}
+
+// StateStore - states of master and slave pairs stored as json files in a directory
+type StateStore struct {
+	store *state.Store
+}
+
+// DefaultStateDir - returns ~/.dbsync/state
+func DefaultStateDir() (string, error) {
+	return state.DefaultDir()
+}
+
+// NewStateStore - constructor, the directory is created on first save
+func NewStateStore(dir string) *StateStore {
+	return &StateStore{
+		store: state.NewStore(dir),
+	}
+}
+
+// Load - returns the state of a pair, empty when the pair was never synced
+func (s *StateStore) Load(master, slave string) (*State, error) {
+	p, err := s.store.Load(master, slave)
+	if err != nil {
+		return nil, err
+	}
+
+	return newState(p), nil
+}
+
+// Save - writes the state of a pair, replacing the previous one atomically
+func (s *StateStore) Save(st *State) error {
+	return s.store.Save(st.internal())
+}
+
+// States - returns the states of every pair, ordered by master and slave
+func (s *StateStore) States() ([]*State, error) {
+	pairs, err := s.store.Pairs()
+	if err != nil {
+		return nil, err
+	}
+
+	states := make([]*State, len(pairs))
+	for i, p := range pairs {
+		states[i] = newState(p)
+	}
+
+	return states, nil
+}
+
+// fingerprint - takes the fingerprints of the master and slave tables, when both drivers support them
+func (d *Diff) fingerprint(ctx context.Context) error {
+	if err := d.source.Open(ctx); err != nil {
+		return fmt.Errorf("master: %s", err)
+	}
+
+	if err := d.slaveConn.Open(ctx); err != nil {
+		return fmt.Errorf("slave: %s", err)
+	}
+
+	var err error
+	if d.masterFingerprints, err = fingerprints(ctx, d.source); err != nil {
+		return fmt.Errorf("master fingerprints: %s", err)
+	}
+
+	if d.slaveFingerprints, err = fingerprints(ctx, d.slaveConn); err != nil {
+		return fmt.Errorf("slave fingerprints: %s", err)
+	}
+
+	return nil
+}
+
+// fingerprints - returns the fingerprints of every table of a source, nil when not supported
+func fingerprints(ctx context.Context, src database.Source) (map[string]string, error) {
+	f, ok := src.(database.Fingerprinter)
+	if !ok {
+		return nil, nil
+	}
+
+	tables, err := src.TableNames(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return f.Fingerprints(ctx, tables...)
+}
+
+// knownUnchanged - returns the checksums of the tables left in sync by the last sync and unchanged
+// on both sides since, synced with the same options
+func (d *Diff) knownUnchanged() map[string]database.ChecksumPair {
+	unchanged := make(map[string]database.ChecksumPair)
+	for table, t := range d.opts.State.Tables {
+		mfp, ok := d.masterFingerprints[table]
+		if !ok || mfp != t.MasterFingerprint {
+			continue
+		}
+
+		sfp, ok := d.slaveFingerprints[table]
+		if !ok || sfp != t.SlaveFingerprint || t.Options != d.tableOptions(table) {
+			continue
+		}
+
+		unchanged[table] = database.ChecksumPair{Master: t.MasterChecksum, Slave: t.SlaveChecksum}
+	}
+
+	return unchanged
+}
+
+// tableOptions - returns the hash of the filter, masks and types used to sync a table, a table
+// synced with other options being compared again
+func (d *Diff) tableOptions(table string) string {
+	filter, _ := d.source.Filter(table)
+	opts := struct {
+		Filter   string            `json:"filter,omitempty"`
+		Masks    map[string]Mask   `json:"masks,omitempty"`
+		MaskSeed string            `json:"mask_seed,omitempty"`
+		Types    map[string]string `json:"types,omitempty"`
+	}{
+		Filter: filter,
+		Masks:  d.opts.tableMasks(table),
+		Types:  d.opts.tableTypes(table),
+	}
+	if len(opts.Masks) > 0 {
+		opts.MaskSeed = d.opts.MaskSeed
+	}
+
+	data, err := json.Marshal(opts)
+	if err != nil {
+		return ""
+	}
+
+	sum := sha1.Sum(data)
+
+	return hex.EncodeToString(sum[:8])
+}
+
+// record - records the run and the tables left in sync in the state. Unchanged tables keep the
+// slave fingerprints taken before comparing, synced tables get the ones taken after the sync.
+// Tables whose fingerprints are unknown are left out and compared again by the next sync, an
+// error being returned when the slave fingerprints could not be taken after the sync.
+func (d *Diff) record(ctx context.Context, start time.Time, res *Result, err error) error {
+	st := d.opts.State
+	if st == nil {
+		return nil
+	}
+
+	run := Run{
+		StartedAt:  start.UTC(),
+		DurationMs: durationMs(time.Since(start)),
+		User:       state.CurrentUser(),
+	}
+	if err != nil {
+		run.Error = err.Error()
+	}
+
+	synced := make(map[string]bool)
+	if res != nil {
+		for _, r := range res.Tables {
+			if r.Err != nil {
+				continue
+			}
+
+			run.Tables = append(run.Tables, r.Table)
+			run.Bytes += r.Bytes
+			if !r.Deleted {
+				synced[r.Table] = true
+			}
+		}
+	}
+	sort.Strings(run.Tables)
+	st.AddRun(run)
+
+	tables := make(map[string]StateTable)
+	defer func() { st.Tables = tables }()
+
+	if d.masterFingerprints == nil || d.slaveFingerprints == nil {
+		return nil
+	}
+
+	slaveFps := d.slaveFingerprints
+	if len(synced) > 0 {
+		ctx, cancel := database.WithTimeout(ctx, d.opts.Timeouts.Checksum)
+		defer cancel()
+
+		var fpErr error
+		if slaveFps, fpErr = fingerprints(ctx, d.slaveConn); fpErr != nil {
+			return fmt.Errorf("slave fingerprints: %s", fpErr)
+		}
+	}
+
+	now := time.Now().UTC()
+	for _, table := range d.Unchanged {
+		d.recordTable(tables, table, d.slaveFingerprints[table], now, false)
+	}
+
+	for table := range synced {
+		d.recordTable(tables, table, slaveFps[table], now, true)
+	}
+
+	return nil
+}
+
+// recordTable - records a table in sync, unless one of its fingerprints is unknown
+func (d *Diff) recordTable(tables map[string]StateTable, table, slaveFp string, now time.Time, synced bool) {
+	masterFp, ok := d.masterFingerprints[table]
+	if !ok || slaveFp == "" {
+		return
+	}
+
+	t := StateTable{
+		MasterFingerprint: masterFp,
+		SlaveFingerprint:  slaveFp,
+		MasterChecksum:    d.MasterChecksums[table],
+		Options:           d.tableOptions(table),
+		SyncedAt:          d.opts.State.Tables[table].SyncedAt,
+		CheckedAt:         now,
+	}
+	if synced {
+		t.SyncedAt = now
+	} else {
+		t.SlaveChecksum = d.SlaveChecksums[table]
+	}
+
+	// tables synced while their fingerprint was unknown
+	if t.SyncedAt.IsZero() {
+		t.SyncedAt = d.opts.State.LastSynced(table)
+	}
+
+	tables[table] = t
+}